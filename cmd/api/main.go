@@ -5,10 +5,14 @@ import (
 	"net/http"
 
 	"github.com/dylan0804/image-processing-tool/internal/api"
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
 	"github.com/dylan0804/image-processing-tool/internal/api/handlers"
 	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
 	"github.com/dylan0804/image-processing-tool/internal/api/logger"
 	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
 	"github.com/dylan0804/image-processing-tool/internal/api/storage"
 )
 
@@ -20,6 +24,9 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// set up config
+	cfg := config.Load()
+
 	// set up redis
 	sessionStore, err := storage.NewRedisSessionStore()
 	if err != nil {
@@ -30,12 +37,44 @@ func main() {
 	response := response.NewResponse()
 
 	// set up imaging
-	imaging := imaging.NewImaging()
+	imaging := imaging.NewImaging(cfg.ImageLimits())
+
+	// set up url signing
+	var signer *signing.Signer
+	if keys := cfg.SigningKeys(); len(keys) > 0 {
+		signer, err = signing.NewSigner(keys, cfg.URLSigningActiveKey)
+		if err != nil {
+			log.Fatalf("Failed to set up URL signing: %v", err)
+		}
+		if cfg.URLSigningToken == "" {
+			log.Fatalf("URL_SIGNING_TOKEN must be set when URL_SIGNING_KEYS is, or anyone could mint signed URLs")
+		}
+	} else {
+		log.Printf("URL_SIGNING_KEYS not set, download and transformation URLs are not signed")
+	}
+
+	// set up result cache
+	resultCache, err := cache.New(cfg.CacheOptions())
+	if err != nil {
+		log.Fatalf("Failed to set up result cache: %v", err)
+	}
+
+	// set up asset storage
+	assetStore, err := assets.New(cfg.AssetsDir)
+	if err != nil {
+		log.Fatalf("Failed to set up asset storage: %v", err)
+	}
+	if cfg.AssetsToken == "" {
+		log.Printf("ASSETS_TOKEN not set, assets cannot be uploaded or deleted")
+	}
 
 	// set up handlers
-	imageHandler := handlers.NewImageHandler(response, sessionStore, imaging)
+	imageHandler := handlers.NewImageHandler(response, sessionStore, imaging, cfg, signer, resultCache, assetStore)
+
+	// metrics are served on their own, internal listener
+	go api.ServeDebug(cfg.DebugAddr, logger)
 
-	routes := api.NewRoutes(mux, imageHandler, logger)
+	routes := api.NewRoutes(mux, imageHandler, signer, cfg, logger)
 
 	routes.InitRoutes()
 }
\ No newline at end of file
@@ -0,0 +1,217 @@
+// Package assets keeps named files that operations refer to, such as colour
+// grading LUTs, on local disk. Each kind of asset has its own namespace.
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("assets: not found")
+	ErrInvalidName = errors.New("assets: names must be 1-64 lowercase letters, digits, dashes or underscores")
+)
+
+// names are used as file names and inside operation chains, so they are kept
+// free of separators
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type Info struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modifiedAt"`
+}
+
+type Store struct {
+	dir string
+
+	// versions remembers the version of each file read, so Version only
+	// needs to stat a file that has not changed since
+	mu       sync.Mutex
+	versions map[string]fileVersion
+}
+
+type fileVersion struct {
+	info    os.FileInfo
+	version string
+}
+
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("assets: %w", err)
+	}
+
+	return &Store{dir: dir, versions: make(map[string]fileVersion)}, nil
+}
+
+// ValidName reports whether name can be used for an asset.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+func (s *Store) path(kind, name string) (string, error) {
+	if !ValidName(kind) || !ValidName(name) {
+		return "", ErrInvalidName
+	}
+
+	return filepath.Join(s.dir, kind, name), nil
+}
+
+// Put stores data under name, replacing any asset of the same kind and name.
+func (s *Store) Put(kind, name string, data []byte) (Info, error) {
+	path, err := s.path(kind, name)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return Info{}, fmt.Errorf("assets: %w", err)
+	}
+
+	// write to a temp file first so readers never see a partial asset
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return Info{}, fmt.Errorf("assets: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return Info{}, fmt.Errorf("assets: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return Info{}, fmt.Errorf("assets: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return Info{}, fmt.Errorf("assets: %w", err)
+	}
+
+	return Info{Name: name, Size: int64(len(data)), ModTime: time.Now()}, nil
+}
+
+// Load returns the content of an asset and a version that changes whenever
+// the content does.
+func (s *Store) Load(kind, name string) ([]byte, string, error) {
+	path, err := s.path(kind, name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", fmt.Errorf("%w: %s %q", ErrNotFound, kind, name)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("assets: %w", err)
+	}
+	defer file.Close()
+
+	// stat the open file so the info matches the content even when the
+	// asset is replaced meanwhile
+	info, err := file.Stat()
+	if err != nil {
+		return nil, "", fmt.Errorf("assets: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("assets: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+
+	s.mu.Lock()
+	s.versions[path] = fileVersion{info: info, version: version}
+	s.mu.Unlock()
+
+	return data, version, nil
+}
+
+// Version returns the version Load would return for an asset. Files that
+// have not changed since they were last read are not read again.
+func (s *Store) Version(kind, name string) (string, error) {
+	path, err := s.path(kind, name)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s %q", ErrNotFound, kind, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("assets: %w", err)
+	}
+
+	s.mu.Lock()
+	known, ok := s.versions[path]
+	s.mu.Unlock()
+
+	// assets are replaced by renaming a new file over them, so an unchanged
+	// one is still the same file
+	if ok && os.SameFile(known.info, info) && known.info.ModTime().Equal(info.ModTime()) && known.info.Size() == info.Size() {
+		return known.version, nil
+	}
+
+	_, version, err := s.Load(kind, name)
+	return version, err
+}
+
+// List returns the assets of a kind sorted by name.
+func (s *Store) List(kind string) ([]Info, error) {
+	if !ValidName(kind) {
+		return nil, ErrInvalidName
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, kind))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("assets: %w", err)
+	}
+
+	infos := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !ValidName(entry.Name()) {
+			continue
+		}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info{Name: entry.Name(), Size: fileInfo.Size(), ModTime: fileInfo.ModTime()})
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].Name < infos[b].Name })
+
+	return infos, nil
+}
+
+func (s *Store) Delete(kind, name string) error {
+	path, err := s.path(kind, name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.versions, path)
+	s.mu.Unlock()
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s %q", ErrNotFound, kind, name)
+	}
+	if err != nil {
+		return fmt.Errorf("assets: %w", err)
+	}
+
+	return nil
+}
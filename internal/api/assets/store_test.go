@@ -0,0 +1,57 @@
+package assets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	store, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = store.Put("luts", "warm", []byte("v1"))
+	require.NoError(t, err)
+
+	data, version, err := store.Load("luts", "warm")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+	current, err := store.Version("luts", "warm")
+	require.NoError(t, err)
+	assert.Equal(t, version, current)
+
+	// replacing the content changes the version
+	_, err = store.Put("luts", "warm", []byte("v2"))
+	require.NoError(t, err)
+	current, err = store.Version("luts", "warm")
+	require.NoError(t, err)
+	assert.NotEqual(t, version, current)
+	_, newVersion, err := store.Load("luts", "warm")
+	require.NoError(t, err)
+	assert.Equal(t, current, newVersion)
+
+	infos, err := store.List("luts")
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "warm", infos[0].Name)
+
+	// kinds are separate namespaces
+	_, _, err = store.Load("fonts", "warm")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete("luts", "warm"))
+	assert.ErrorIs(t, store.Delete("luts", "warm"), ErrNotFound)
+	_, err = store.Version("luts", "warm")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreRejectsNames(t *testing.T) {
+	store, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	for _, name := range []string{"", "../escape", "a/b", "Upper", "with,comma", "-leading"} {
+		_, err := store.Put("luts", name, []byte("x"))
+		assert.ErrorIs(t, err, ErrInvalidName, "name %q", name)
+	}
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Cache stores encoded results of image operations in two tiers: a small
+// in-memory LRU for hot results and a larger LRU directory on disk. Both tiers
+// are bounded by total byte size. The lock only covers the bookkeeping, files
+// are read and written without holding it so a slow disk does not hold up
+// memory hits.
+type Cache struct {
+	mu     sync.Mutex
+	memory *lru
+	disk   *lru
+	dir    string
+}
+
+type Options struct {
+	MemoryBytes int64
+	// DiskDir is where the disk tier lives; an empty DiskDir or zero
+	// DiskBytes disables it.
+	DiskDir   string
+	DiskBytes int64
+}
+
+// Key derives a cache key from the hash of the source image and a canonical
+// encoding of everything applied to it.
+func Key(sourceHash, operations string) string {
+	sum := sha256.Sum256([]byte(sourceHash + "\n" + operations))
+	return hex.EncodeToString(sum[:])
+}
+
+func New(opts Options) (*Cache, error) {
+	c := &Cache{
+		memory: newLRU(opts.MemoryBytes),
+	}
+
+	if opts.DiskDir != "" && opts.DiskBytes > 0 {
+		if err := os.MkdirAll(opts.DiskDir, 0700); err != nil {
+			return nil, err
+		}
+		c.dir = opts.DiskDir
+		c.disk = newLRU(opts.DiskBytes)
+
+		if err := c.loadDisk(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// loadDisk indexes results left on disk by a previous run, oldest first so
+// they are the first to be evicted.
+func (c *Cache) loadDisk() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type file struct {
+		key     string
+		size    int64
+		modTime int64
+	}
+	var files []file
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || len(entry.Name()) != sha256.Size*2 {
+			continue
+		}
+		files = append(files, file{entry.Name(), info.Size(), info.ModTime().UnixNano()})
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].modTime < files[b].modTime
+	})
+
+	for _, f := range files {
+		c.disk.add(f.key, nil, f.size)
+	}
+
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	if data, ok := c.memory.get(key); ok {
+		c.mu.Unlock()
+		return data, true
+	}
+	onDisk := false
+	if c.disk != nil {
+		_, onDisk = c.disk.get(key)
+	}
+	c.mu.Unlock()
+
+	if !onDisk {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.disk.remove(key)
+		return nil, false
+	}
+
+	// promote so the next hit is served from memory
+	c.memory.add(key, data, int64(len(data)))
+
+	return data, true
+}
+
+func (c *Cache) Put(key string, data []byte) {
+	size := int64(len(data))
+
+	c.mu.Lock()
+	c.memory.add(key, data, size)
+	c.mu.Unlock()
+
+	if c.disk == nil || size > c.disk.maxBytes {
+		return
+	}
+
+	// the file is renamed into place whole, so concurrent readers never
+	// see a partial result
+	tmp, err := os.CreateTemp(c.dir, "put-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	c.mu.Lock()
+	evicted := c.disk.add(key, nil, size)
+	c.mu.Unlock()
+
+	for _, key := range evicted {
+		os.Remove(c.path(key))
+	}
+}
+
+// lru tracks entries by recency and evicts the least recently used ones once
+// the total size exceeds maxBytes. Disk entries are tracked with nil data.
+type lru struct {
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	data []byte
+	size int64
+}
+
+func newLRU(maxBytes int64) *lru {
+	return &lru{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (l *lru) get(key string) ([]byte, bool) {
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+
+	return elem.Value.(*lruEntry).data, true
+}
+
+// add inserts or refreshes an entry and returns the keys it evicted.
+func (l *lru) add(key string, data []byte, size int64) []string {
+	if size > l.maxBytes {
+		return nil
+	}
+
+	if elem, ok := l.entries[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		l.size += size - entry.size
+		entry.data, entry.size = data, size
+		l.order.MoveToFront(elem)
+	} else {
+		l.entries[key] = l.order.PushFront(&lruEntry{key: key, data: data, size: size})
+		l.size += size
+	}
+
+	var evicted []string
+	for l.size > l.maxBytes {
+		oldest := l.order.Back()
+		key := oldest.Value.(*lruEntry).key
+		l.remove(key)
+		evicted = append(evicted, key)
+	}
+
+	return evicted
+}
+
+func (l *lru) remove(key string) {
+	elem, ok := l.entries[key]
+	if !ok {
+		return
+	}
+
+	l.order.Remove(elem)
+	delete(l.entries, key)
+	l.size -= elem.Value.(*lruEntry).size
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := New(Options{MemoryBytes: 10, DiskDir: dir, DiskBytes: 20})
+	require.NoError(t, err)
+
+	a, b, d := Key("src", "blur_1"), Key("src", "blur_2"), Key("src", "blur_3")
+	assert.NotEqual(t, a, Key("other", "blur_1"))
+
+	c.Put(a, bytes.Repeat([]byte("a"), 8))
+	c.Put(b, bytes.Repeat([]byte("b"), 8))
+
+	// a was pushed out of memory but is still on disk
+	data, ok := c.Get(a)
+	require.True(t, ok)
+	assert.Equal(t, bytes.Repeat([]byte("a"), 8), data)
+
+	// the disk tier holds 20 bytes, so adding d evicts b, the least recently used
+	c.Put(d, bytes.Repeat([]byte("d"), 8))
+	_, ok = c.Get(b)
+	assert.False(t, ok)
+	assert.NoFileExists(t, filepath.Join(dir, b))
+
+	// results survive a restart through the disk tier
+	reopened, err := New(Options{MemoryBytes: 10, DiskDir: dir, DiskBytes: 20})
+	require.NoError(t, err)
+	data, ok = reopened.Get(d)
+	require.True(t, ok)
+	assert.Equal(t, bytes.Repeat([]byte("d"), 8), data)
+}
+
+func TestCacheMemoryOnly(t *testing.T) {
+	c, err := New(Options{MemoryBytes: 4})
+	require.NoError(t, err)
+
+	key := Key("src", "blur_1")
+	c.Put(key, []byte("too large"))
+	_, ok := c.Get(key)
+	assert.False(t, ok)
+
+	c.Put(key, []byte("ok"))
+	data, ok := c.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("ok"), data)
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/negotiate"
+	"github.com/dylan0804/image-processing-tool/internal/api/remote"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
+)
+
+type Config struct {
+	// MaxUploadBytes caps the size of a request body carrying an image.
+	MaxUploadBytes int64
+	// MaxUploadFiles caps how many images a single upload request may carry.
+	MaxUploadFiles int64
+
+	// TusMaxSize caps the declared length of a resumable upload, and
+	// TusUploadExpiry is how long an unfinished one is kept after its last
+	// chunk. Sessions expire from redis after 30 minutes of inactivity, so
+	// longer expiries have no effect.
+	TusMaxSize int64
+	TusUploadExpiry time.Duration
+
+	// Import* configure fetching images from remote URLs. ImportAllowedHosts
+	// lists hostnames or CIDR ranges exempt from the private address block.
+	ImportTimeout time.Duration
+	ImportMaxBytes int64
+	ImportMaxRedirects int64
+	ImportAllowedHosts []string
+
+	// URLSigningKeys holds "id:secret" pairs; when any are set, download and
+	// transformation URLs must carry a valid signature. New URLs are signed
+	// with URLSigningActiveKey, or the first key if that is empty.
+	// URLSigningToken guards the endpoint that mints signed URLs and, as a
+	// bearer token, the endpoints that edit or analyse a session's image. It
+	// must be set along with the keys. Without signing keys a session ID is
+	// all it takes to edit, analyse or download a session's image, and the
+	// ID of an unfinished resumable upload is always enough to continue or
+	// cancel it.
+	URLSigningKeys []string
+	URLSigningActiveKey string
+	URLSigningToken string
+	URLSigningDefaultTTL time.Duration
+	URLSigningMaxTTL time.Duration
+
+	// Cache* bound the processed-result cache. The disk tier is disabled
+	// when CacheDiskBytes is zero.
+	CacheMemoryBytes int64
+	CacheDiskDir string
+	CacheDiskBytes int64
+
+	// DownloadCacheControl and TransformCacheControl are sent as the
+	// Cache-Control header of the respective image responses.
+	DownloadCacheControl string
+	TransformCacheControl string
+
+	// OutputFormatPreference orders the formats offered to clients through
+	// the Accept header; "source" stands for the format an image is stored in.
+	OutputFormatPreference []string
+
+	// MaxImageWidth, MaxImageHeight and MaxImageMegapixels bound both the
+	// images that are decoded and the images that operations produce.
+	MaxImageWidth int64
+	MaxImageHeight int64
+	MaxImageMegapixels int64
+
+	// AssetsDir holds uploaded assets such as .cube LUTs, and MaxAssetBytes
+	// caps the size of a single one. Every replica reads assets from
+	// AssetsDir, so with more than one it must be a shared persistent volume;
+	// the default under the temp directory only suits a single instance. AssetsToken is the bearer token needed
+	// to upload or delete assets; while it is empty they cannot be changed.
+	AssetsDir string
+	MaxAssetBytes int64
+	AssetsToken string
+
+	// DebugAddr is where the /debug/vars metrics are served, apart from the
+	// public API. It should not be reachable from outside.
+	DebugAddr string
+}
+
+func Default() Config {
+	return Config{
+		MaxUploadBytes: 10 << 20,
+		MaxUploadFiles: 10,
+		TusMaxSize: 200 << 20,
+		TusUploadExpiry: 30 * time.Minute,
+		ImportTimeout: 10 * time.Second,
+		ImportMaxBytes: 10 << 20,
+		ImportMaxRedirects: 3,
+		URLSigningDefaultTTL: time.Hour,
+		URLSigningMaxTTL: 7 * 24 * time.Hour,
+		CacheMemoryBytes: 64 << 20,
+		CacheDiskDir: filepath.Join(os.TempDir(), "image-cache"),
+		CacheDiskBytes: 1 << 30,
+		DownloadCacheControl: "private, no-cache",
+		TransformCacheControl: "public, max-age=300, must-revalidate",
+		MaxImageWidth: 12000,
+		MaxImageHeight: 12000,
+		MaxImageMegapixels: 50,
+		AssetsDir: filepath.Join(os.TempDir(), "image-assets"),
+		MaxAssetBytes: 10 << 20,
+		DebugAddr: "127.0.0.1:6060",
+	}
+}
+
+// Load reads the configuration from environment variables, falling back to
+// Default for anything that is unset or invalid.
+func Load() Config {
+	cfg := Default()
+
+	cfg.MaxUploadBytes = getEnvInt64("MAX_UPLOAD_BYTES", cfg.MaxUploadBytes)
+	cfg.MaxUploadFiles = getEnvInt64("MAX_UPLOAD_FILES", cfg.MaxUploadFiles)
+	cfg.TusMaxSize = getEnvInt64("TUS_MAX_SIZE", cfg.TusMaxSize)
+	cfg.TusUploadExpiry = getEnvDuration("TUS_UPLOAD_EXPIRY", cfg.TusUploadExpiry)
+	cfg.ImportTimeout = getEnvDuration("IMPORT_TIMEOUT", cfg.ImportTimeout)
+	cfg.ImportMaxBytes = getEnvInt64("IMPORT_MAX_BYTES", cfg.ImportMaxBytes)
+	cfg.ImportMaxRedirects = getEnvCount("IMPORT_MAX_REDIRECTS", cfg.ImportMaxRedirects)
+	cfg.ImportAllowedHosts = getEnvList("IMPORT_ALLOWED_HOSTS", cfg.ImportAllowedHosts)
+	cfg.URLSigningKeys = getEnvList("URL_SIGNING_KEYS", cfg.URLSigningKeys)
+	cfg.URLSigningActiveKey = getEnvString("URL_SIGNING_ACTIVE_KEY", cfg.URLSigningActiveKey)
+	cfg.URLSigningToken = getEnvString("URL_SIGNING_TOKEN", cfg.URLSigningToken)
+	cfg.URLSigningDefaultTTL = getEnvDuration("URL_SIGNING_DEFAULT_TTL", cfg.URLSigningDefaultTTL)
+	cfg.URLSigningMaxTTL = getEnvDuration("URL_SIGNING_MAX_TTL", cfg.URLSigningMaxTTL)
+	cfg.CacheMemoryBytes = getEnvInt64("CACHE_MEMORY_BYTES", cfg.CacheMemoryBytes)
+	cfg.CacheDiskDir = getEnvString("CACHE_DISK_DIR", cfg.CacheDiskDir)
+	cfg.CacheDiskBytes = getEnvCount("CACHE_DISK_BYTES", cfg.CacheDiskBytes)
+	cfg.DownloadCacheControl = getEnvString("DOWNLOAD_CACHE_CONTROL", cfg.DownloadCacheControl)
+	cfg.TransformCacheControl = getEnvString("TRANSFORM_CACHE_CONTROL", cfg.TransformCacheControl)
+	cfg.OutputFormatPreference = getEnvList("OUTPUT_FORMAT_PREFERENCE", cfg.OutputFormatPreference)
+	cfg.MaxImageWidth = getEnvInt64("MAX_IMAGE_WIDTH", cfg.MaxImageWidth)
+	cfg.MaxImageHeight = getEnvInt64("MAX_IMAGE_HEIGHT", cfg.MaxImageHeight)
+	cfg.MaxImageMegapixels = getEnvInt64("MAX_IMAGE_MEGAPIXELS", cfg.MaxImageMegapixels)
+	cfg.AssetsDir = getEnvString("ASSETS_DIR", cfg.AssetsDir)
+	cfg.MaxAssetBytes = getEnvInt64("ASSET_MAX_BYTES", cfg.MaxAssetBytes)
+	cfg.AssetsToken = getEnvString("ASSETS_TOKEN", cfg.AssetsToken)
+	cfg.DebugAddr = getEnvString("DEBUG_ADDR", cfg.DebugAddr)
+
+	return cfg
+}
+
+func (c Config) ImageLimits() imaging.Limits {
+	return imaging.Limits{
+		MaxWidth: int(c.MaxImageWidth),
+		MaxHeight: int(c.MaxImageHeight),
+		MaxPixels: c.MaxImageMegapixels * 1000000,
+	}
+}
+
+func (c Config) ImportOptions() remote.Options {
+	return remote.Options{
+		Timeout: c.ImportTimeout,
+		MaxBytes: c.ImportMaxBytes,
+		MaxRedirects: int(c.ImportMaxRedirects),
+		AllowedHosts: c.ImportAllowedHosts,
+	}
+}
+
+func (c Config) CacheOptions() cache.Options {
+	return cache.Options{
+		MemoryBytes: c.CacheMemoryBytes,
+		DiskDir: c.CacheDiskDir,
+		DiskBytes: c.CacheDiskBytes,
+	}
+}
+
+// FormatPolicy returns the content negotiation policy, falling back to the
+// default one if the configured preference is empty or invalid.
+func (c Config) FormatPolicy() negotiate.Policy {
+	if len(c.OutputFormatPreference) == 0 {
+		return negotiate.DefaultPolicy()
+	}
+
+	policy, err := negotiate.NewPolicy(c.OutputFormatPreference)
+	if err != nil {
+		log.Printf("OUTPUT_FORMAT_PREFERENCE is invalid (%v), using the default", err)
+		return negotiate.DefaultPolicy()
+	}
+
+	return policy
+}
+
+func (c Config) SigningKeys() []signing.Key {
+	keys := make([]signing.Key, 0, len(c.URLSigningKeys))
+	for _, entry := range c.URLSigningKeys {
+		id, secret, _ := strings.Cut(entry, ":")
+		keys = append(keys, signing.Key{
+			ID: id,
+			Secret: []byte(secret),
+		})
+	}
+
+	return keys
+}
+
+func getEnvString(key string, fallback string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("%s not set, defaulting to %q", key, fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func getEnvInt64(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("%s not set, defaulting to %d", key, fallback)
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed <= 0 {
+		log.Printf("%s has invalid value %q, defaulting to %d", key, value, fallback)
+		return fallback
+	}
+
+	return parsed
+}
+
+// getEnvCount is like getEnvInt64 but also accepts 0.
+func getEnvCount(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("%s not set, defaulting to %d", key, fallback)
+		return fallback
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		log.Printf("%s has invalid value %q, defaulting to %d", key, value, fallback)
+		return fallback
+	}
+
+	return parsed
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("%s not set, defaulting to %s", key, fallback)
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("%s has invalid value %q, defaulting to %s", key, value, fallback)
+		return fallback
+	}
+
+	return parsed
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Printf("%s not set, defaulting to %v", key, fallback)
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
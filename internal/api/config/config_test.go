@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCounts(t *testing.T) {
+	testcases := []struct{
+		name string
+		value string
+		wantDiskBytes int64
+		wantRedirects int64
+	}{
+		{name: "Unset", value: "", wantDiskBytes: Default().CacheDiskBytes, wantRedirects: Default().ImportMaxRedirects},
+		{name: "Zero disables", value: "0", wantDiskBytes: 0, wantRedirects: 0},
+		{name: "Positive", value: "5", wantDiskBytes: 5, wantRedirects: 5},
+		{name: "Negative falls back", value: "-1", wantDiskBytes: Default().CacheDiskBytes, wantRedirects: Default().ImportMaxRedirects},
+		{name: "Invalid falls back", value: "lots", wantDiskBytes: Default().CacheDiskBytes, wantRedirects: Default().ImportMaxRedirects},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("CACHE_DISK_BYTES", tc.value)
+			t.Setenv("IMPORT_MAX_REDIRECTS", tc.value)
+
+			cfg := Load()
+
+			assert.Equal(t, tc.wantDiskBytes, cfg.CacheDiskBytes)
+			assert.Equal(t, tc.wantRedirects, cfg.ImportMaxRedirects)
+			assert.Equal(t, tc.wantDiskBytes, cfg.CacheOptions().DiskBytes)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+const (
+	histogramChartWidth  = 512
+	histogramChartHeight = 256
+)
+
+// SessionHistogram reports per-channel histograms and statistics for the
+// session's current image, or renders them as a PNG chart with format=png.
+func (i *ImageHandler) SessionHistogram(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+	sessionID := r.PathValue("sessionId")
+
+	query := request.HistogramQuery{
+		Bins: imaging.MaxHistogramBins,
+		Format: r.URL.Query().Get("format"),
+	}
+	if bins := r.URL.Query().Get("bins"); bins != "" {
+		parsed, err := strconv.Atoi(bins)
+		if err != nil {
+			i.response.WriteValidationError(w, validation.Errors{{Field: "bins", Message: "must be an integer"}})
+			return
+		}
+		query.Bins = parsed
+	}
+	if err := validation.Struct(&query); err != nil {
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.loadSession(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("Failed to load session", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	if query.Format == "png" {
+		etag := ""
+		if session.ContentHash != "" {
+			etag = imageETag(cache.Key(session.ContentHash, fmt.Sprintf("histogram,bins_%d,%dx%d", query.Bins, histogramChartWidth, histogramChartHeight)))
+		}
+		setCacheHeaders(w, etag, lastModified(session), i.config.DownloadCacheControl)
+
+		if notModified(r, etag, lastModified(session)) {
+			writeNotModified(w)
+			return
+		}
+	}
+
+	img, err := i.imaging.Open(session.TempPath)
+	if err != nil {
+		logger.Error("Failed to open image", zap.Error(err))
+		i.response.WriteError(w, "Failed to open image", operationErrorStatus(err))
+		return
+	}
+
+	histogram, err := imaging.NewHistogram(img, query.Bins)
+	if err != nil {
+		logger.Error("Failed to compute histogram", zap.Error(err))
+		i.response.WriteError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if query.Format == "png" {
+		var buf bytes.Buffer
+		if err := i.imaging.Encode(&buf, histogram.Render(histogramChartWidth, histogramChartHeight), imaging.PNG); err != nil {
+			logger.Error("Failed to render histogram", zap.Error(err))
+			i.response.WriteError(w, "Failed to render histogram", http.StatusInternalServerError)
+			return
+		}
+
+		serveImage(w, r, bytes.NewReader(buf.Bytes()), imaging.ContentType(imaging.PNG), lastModified(session))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"histogram": histogram,
+		},
+		Err: nil,
+	})
+}
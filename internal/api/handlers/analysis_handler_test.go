@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_SessionHistogram(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+		ContentHash: "abc123",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		sessionID string
+		query string
+		wantStatus int
+		checkResponse func(*httptest.ResponseRecorder)
+	}{
+		{
+			name: "Statistics as JSON",
+			sessionID: "session-imageId",
+			query: "?bins=16",
+			wantStatus: http.StatusCreated,
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				var resp struct {
+					Data struct {
+						Histogram struct {
+							Bins int `json:"bins"`
+							Pixels int `json:"pixels"`
+							Red struct {
+								Histogram []int `json:"histogram"`
+							} `json:"red"`
+						} `json:"histogram"`
+					} `json:"message"`
+				}
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+				assert.Equal(t, 16, resp.Data.Histogram.Bins)
+				assert.Len(t, resp.Data.Histogram.Red.Histogram, 16)
+			},
+		},
+		{
+			name: "Rendered chart",
+			sessionID: "session-imageId",
+			query: "?format=png",
+			wantStatus: http.StatusOK,
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+				assert.NotEmpty(t, rec.Header().Get("ETag"))
+				assert.Equal(t, config.Default().DownloadCacheControl, rec.Header().Get("Cache-Control"))
+
+				chart, err := png.Decode(rec.Body)
+				require.NoError(t, err)
+				assert.Equal(t, histogramChartWidth, chart.Bounds().Dx())
+			},
+		},
+		{
+			name: "Too many bins",
+			sessionID: "session-imageId",
+			query: "?bins=1000",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown format",
+			sessionID: "session-imageId",
+			query: "?format=svg",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown session",
+			sessionID: "missing",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/sessions/"+tc.sessionID+"/histogram"+tc.query, nil)
+			req.SetPathValue("sessionId", tc.sessionID)
+			rec := httptest.NewRecorder()
+
+			handler.SessionHistogram(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.checkResponse != nil {
+				tc.checkResponse(rec)
+			}
+		})
+	}
+}
+
+func TestImageHandler_SessionHistogramNotModified(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+		ContentHash: "abc123",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	chart := func(query, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/sessions/session-imageId/histogram"+query, nil)
+		req.SetPathValue("sessionId", "session-imageId")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		handler.SessionHistogram(rec, req)
+		return rec
+	}
+
+	rec := chart("?format=png", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+
+	rec = chart("?format=png", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	// a chart with other bins is another representation
+	rec = chart("?format=png&bins=16", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
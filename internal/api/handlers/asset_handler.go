@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"go.uber.org/zap"
+)
+
+var errAssetsUnavailable = errors.New("asset storage is not configured")
+
+// assetCheck validates an uploaded asset before it is stored and returns
+// details about it to include in the response.
+type assetCheck func(data []byte) (map[string]interface{}, error)
+
+// uploadAsset stores the asset in the request under the name in the path,
+// replacing any asset of that kind and name.
+func (i *ImageHandler) uploadAsset(w http.ResponseWriter, r *http.Request, kind string, check assetCheck) {
+	logger := logger.LoggerFromContext(r.Context())
+	name := r.PathValue("name")
+
+	if i.assets == nil {
+		i.response.WriteError(w, errAssetsUnavailable.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if !assets.ValidName(name) {
+		i.response.WriteError(w, assets.ErrInvalidName.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := i.readAsset(w, r)
+	if err != nil {
+		logger.Error("Failed to read asset", zap.String("kind", kind), zap.Error(err))
+		i.response.WriteError(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+
+	details, err := check(data)
+	if err != nil {
+		logger.Info("Rejected asset", zap.String("kind", kind), zap.Error(err))
+		i.response.WriteError(w, err.Error(), assetCheckStatus(err))
+		return
+	}
+
+	info, err := i.assets.Put(kind, name, data)
+	if err != nil {
+		logger.Error("Failed to store asset", zap.String("kind", kind), zap.Error(err))
+		i.response.WriteError(w, err.Error(), assetErrorStatus(err))
+		return
+	}
+
+	logger.Info("Stored asset", zap.String("kind", kind), zap.String("name", name))
+
+	result := map[string]interface{}{
+		"name": info.Name,
+		"bytes": info.Size,
+	}
+	for key, value := range details {
+		result[key] = value
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: result,
+		Err: nil,
+	})
+}
+
+func (i *ImageHandler) listAssets(w http.ResponseWriter, r *http.Request, kind string) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	if i.assets == nil {
+		i.response.WriteError(w, errAssetsUnavailable.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	infos, err := i.assets.List(kind)
+	if err != nil {
+		logger.Error("Failed to list assets", zap.String("kind", kind), zap.Error(err))
+		i.response.WriteError(w, "Failed to list "+kind, http.StatusInternalServerError)
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			kind: infos,
+		},
+		Err: nil,
+	})
+}
+
+func (i *ImageHandler) deleteAsset(w http.ResponseWriter, r *http.Request, kind string) {
+	logger := logger.LoggerFromContext(r.Context())
+	name := r.PathValue("name")
+
+	if i.assets == nil {
+		i.response.WriteError(w, errAssetsUnavailable.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := i.assets.Delete(kind, name); err != nil {
+		logger.Error("Failed to delete asset", zap.String("kind", kind), zap.Error(err))
+		i.response.WriteError(w, err.Error(), assetErrorStatus(err))
+		return
+	}
+
+	logger.Info("Deleted asset", zap.String("kind", kind), zap.String("name", name))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readAsset reads an uploaded asset from the raw body, or from the "file"
+// part of a multipart form, capped at the configured asset size.
+func (i *ImageHandler) readAsset(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, i.config.MaxAssetBytes)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return io.ReadAll(r.Body)
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no file part in form")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return io.ReadAll(part)
+		}
+	}
+}
+
+// assetCheckStatus maps errors from validating an uploaded asset to the HTTP
+// status reported to the client.
+func assetCheckStatus(err error) int {
+	if errors.Is(err, imaging.ErrInvalidCube) || errors.Is(err, imaging.ErrInvalidFont) {
+		return http.StatusUnprocessableEntity
+	}
+
+	return uploadErrorStatus(err)
+}
+
+// assetErrorStatus maps errors from the asset store to the HTTP status
+// reported to the client.
+func assetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, assets.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, assets.ErrInvalidName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
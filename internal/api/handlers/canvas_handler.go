@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// PadImage grows the canvas of a session image to a size or aspect ratio
+// without cropping it, e.g. to make product shots square.
+func (i *ImageHandler) PadImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.PadRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	var errs validation.Errors
+	opts := imaging.PadOptions{}
+	if req.Width != nil {
+		opts.Width = *req.Width
+	}
+	if req.Height != nil {
+		opts.Height = *req.Height
+	}
+	if req.AspectRatio != "" {
+		opts.AspectWidth, opts.AspectHeight = aspectRatio(&errs, req.AspectRatio)
+	}
+	if req.Anchor != "" {
+		opts.Anchor, _ = imaging.AnchorFromName(req.Anchor)
+	}
+	fill := canvasFill(&errs, req.CanvasFill)
+
+	if len(errs) == 0 {
+		if err := opts.Validate(); err != nil {
+			errs = append(errs, validation.FieldError{Field: "aspectRatio", Message: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		logger.Info("Rejected padding parameters", zap.Error(errs))
+		i.response.WriteValidationError(w, errs)
+		return
+	}
+
+	i.applyCanvas(w, r, req.SessionID, transform.Pad(opts, fill))
+}
+
+// AddBorder adds a border of the same or a different width on each side of
+// a session image.
+func (i *ImageHandler) AddBorder(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.BorderRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	var errs validation.Errors
+	insets := imaging.Insets{}
+	if req.Width != nil {
+		insets = imaging.UniformInsets(*req.Width)
+	}
+	for _, side := range []struct {
+		value *int
+		inset *int
+	}{{req.Top, &insets.Top}, {req.Right, &insets.Right}, {req.Bottom, &insets.Bottom}, {req.Left, &insets.Left}} {
+		if side.value != nil {
+			*side.inset = *side.value
+		}
+	}
+	if insets == (imaging.Insets{}) {
+		errs = append(errs, validation.FieldError{Field: "width", Message: "must be positive on at least one side"})
+	}
+	fill := canvasFill(&errs, req.CanvasFill)
+
+	if len(errs) > 0 {
+		logger.Info("Rejected border parameters", zap.Error(errs))
+		i.response.WriteValidationError(w, errs)
+		return
+	}
+
+	i.applyCanvas(w, r, req.SessionID, transform.Border(insets, fill))
+}
+
+// applyCanvas applies a canvas operation to the session and reports the
+// image's format, which changes when transparency is added to a format that
+// cannot store it.
+func (i *ImageHandler) applyCanvas(w http.ResponseWriter, r *http.Request, sessionID string, op transform.Operation) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	session, err := i.applyToSession(r.Context(), sessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to extend canvas", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	format := ""
+	if f, err := imaging.FormatFromPath(session.TempPath); err == nil {
+		format = imaging.FormatName(f)
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+			"format": format,
+		},
+		Err: nil,
+	})
+}
+
+// canvasFill converts the fill of a request, recording errors for options
+// of another fill and invalid colours.
+func canvasFill(errs *validation.Errors, req request.CanvasFill) imaging.CanvasFill {
+	fill := imaging.CanvasFill{}
+	if req.Fill != "" {
+		fill.Mode, _ = imaging.FillModeFromName(req.Fill)
+	}
+	fill.Color = colorField(errs, "color", req.Color, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	if req.Sigma != nil {
+		fill.Sigma = req.Sigma.Float64()
+	}
+
+	if req.Color != "" && fill.Mode != imaging.FillColor {
+		*errs = append(*errs, validation.FieldError{Field: "color", Message: "only applies to the color fill"})
+	}
+	if req.Sigma != nil && fill.Mode != imaging.FillBlur {
+		*errs = append(*errs, validation.FieldError{Field: "sigma", Message: "only applies to the blur fill"})
+	}
+
+	return fill
+}
+
+// aspectRatio parses a ratio such as "16:9", recording an error when it is
+// not two positive numbers.
+func aspectRatio(errs *validation.Errors, value string) (float64, float64) {
+	w, h, ok := strings.Cut(value, ":")
+	width, werr := strconv.ParseFloat(strings.TrimSpace(w), 64)
+	height, herr := strconv.ParseFloat(strings.TrimSpace(h), 64)
+	if !ok || werr != nil || herr != nil || !(width > 0) || !(height > 0) {
+		*errs = append(*errs, validation.FieldError{Field: "aspectRatio", Message: "must be width:height, such as 1:1 or 16:9"})
+		return 0, 0
+	}
+
+	return width, height
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_Canvas(t *testing.T) {
+	testcases := []struct{
+		name string
+		border bool
+		body string
+		wantStatus int
+		wantOperation string
+		wantFormat string
+	}{
+		{
+			name: "Square with a white fill",
+			body: `{"sessionID": "session-imageId", "aspectRatio": "1:1"}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "pad,ar_1:1",
+			wantFormat: "jpeg",
+		},
+		{
+			name: "Target size with a blurred fill",
+			body: `{"sessionID": "session-imageId", "width": 800, "height": 800, "anchor": "bottom", "fill": "blur", "sigma": 30}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "pad,w_800,h_800,a_bottom,fill_blur,s_30",
+			wantFormat: "jpeg",
+		},
+		{
+			name: "Transparent padding turns a JPEG into a PNG",
+			body: `{"sessionID": "session-imageId", "aspectRatio": "16:9", "fill": "transparent"}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "pad,ar_16:9,fill_transparent",
+			wantFormat: "png",
+		},
+		{
+			name: "Size and aspect ratio",
+			body: `{"sessionID": "session-imageId", "width": 800, "aspectRatio": "1:1"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Invalid aspect ratio",
+			body: `{"sessionID": "session-imageId", "aspectRatio": "square"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Sigma with a colour fill",
+			body: `{"sessionID": "session-imageId", "aspectRatio": "1:1", "sigma": 4}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Per-side border",
+			border: true,
+			body: `{"sessionID": "session-imageId", "width": 10, "bottom": 40, "color": "#000"}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "border,t_10,r_10,b_40,l_10,c_000000",
+			wantFormat: "jpeg",
+		},
+		{
+			name: "Uniform border",
+			border: true,
+			body: `{"sessionID": "session-imageId", "width": 12, "fill": "blur"}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "border_12,fill_blur",
+			wantFormat: "jpeg",
+		},
+		{
+			name: "Empty border",
+			border: true,
+			body: `{"sessionID": "session-imageId", "width": 0}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown session",
+			border: true,
+			body: `{"sessionID": "missing", "width": 4}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := newMockSessionStore()
+			mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+				TempPath: "/path/to/temp.jpg",
+			})
+			handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+			rec := httptest.NewRecorder()
+			if tc.border {
+				handler.AddBorder(rec, httptest.NewRequest("POST", "/api/v1/image/border", bytes.NewBufferString(tc.body)))
+			} else {
+				handler.PadImage(rec, httptest.NewRequest("POST", "/api/v1/image/pad", bytes.NewBufferString(tc.body)))
+			}
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantOperation == "" {
+				return
+			}
+
+			var resp struct{
+				Data map[string]interface{} `json:"message"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tc.wantOperation, resp.Data["operation"])
+			assert.Equal(t, tc.wantFormat, resp.Data["format"])
+
+			session, _, _ := mockStore.Get(context.Background(), "session-imageId")
+			assert.Equal(t, "."+tc.wantFormat, map[string]string{".jpg": ".jpeg", ".png": ".png"}[filepath.Ext(session.TempPath)])
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"image/color"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// DrawShapes draws rectangles, ellipses, lines, arrows and polygons onto a
+// session image in the order they are given.
+func (i *ImageHandler) DrawShapes(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.DrawRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	shapes, err := shapes(req.Shapes)
+	if err != nil {
+		logger.Info("Rejected shapes", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+	op := transform.Draw(shapes...)
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to draw shapes", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+// shapes converts request shapes, reporting invalid colours and geometry as
+// errors on the shape's fields.
+func shapes(reqs []request.Shape) ([]imaging.Shape, error) {
+	var errs validation.Errors
+
+	result := make([]imaging.Shape, 0, len(reqs))
+	for idx, req := range reqs {
+		field := fmt.Sprintf("shapes[%d]", idx)
+
+		kind, _ := imaging.ShapeKindFromName(req.Type)
+		shape := imaging.Shape{
+			Kind: kind,
+			StrokeWidth: imaging.DefaultShapeStroke,
+			StrokeColor: colorField(&errs, field+".strokeColor", req.StrokeColor, color.NRGBA{A: 255}),
+			Fill: colorField(&errs, field+".fill", req.Fill, color.NRGBA{}),
+			AntiAlias: req.AntiAlias == nil || *req.AntiAlias,
+		}
+		for _, p := range req.Points {
+			shape.Points = append(shape.Points, imaging.Point{X: p.X.Float64(), Y: p.Y.Float64()})
+		}
+		if req.StrokeWidth != nil {
+			shape.StrokeWidth = req.StrokeWidth.Float64()
+		}
+		if req.ArrowSize != nil {
+			shape.ArrowSize = req.ArrowSize.Float64()
+		}
+
+		if err := shape.Validate(); err != nil {
+			errs = append(errs, validation.FieldError{Field: field, Message: err.Error()})
+		}
+		result = append(result, shape)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return result, nil
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_DrawShapes(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+		wantOperation string
+	}{
+		{
+			name: "Annotations",
+			body: `{"sessionID": "session-imageId", "shapes": [{"type": "rect", "points": [{"x": 10, "y": 10}, {"x": 200, "y": 80}], "strokeWidth": 4, "strokeColor": "#ff0000", "fill": "#ff000040"}, {"type": "arrow", "points": [{"x": 300, "y": 300}, {"x": 210, "y": 90}], "arrowSize": 24}, {"type": "polygon", "points": [{"x": 0, "y": 0}, {"x": 10, "y": 0}, {"x": 5, "y": 8}], "strokeWidth": 0, "fill": "#00ff00", "antiAlias": false}]}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "draw_rect,p_10:10:200:80,w_4,c_ff0000,f_ff000040/draw_arrow,p_300:300:210:90,head_24/draw_polygon,p_0:0:10:0:5:8,w_0,f_00ff00,aliased",
+		},
+		{
+			name: "No shapes",
+			body: `{"sessionID": "session-imageId", "shapes": []}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown shape",
+			body: `{"sessionID": "session-imageId", "shapes": [{"type": "star", "points": [{"x": 0, "y": 0}, {"x": 1, "y": 1}]}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Filled line",
+			body: `{"sessionID": "session-imageId", "shapes": [{"type": "line", "points": [{"x": 0, "y": 0}, {"x": 1, "y": 1}], "fill": "#000"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Invalid colour",
+			body: `{"sessionID": "session-imageId", "shapes": [{"type": "ellipse", "points": [{"x": 0, "y": 0}, {"x": 1, "y": 1}], "strokeColor": "red"}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown session",
+			body: `{"sessionID": "missing", "shapes": [{"type": "line", "points": [{"x": 0, "y": 0}, {"x": 1, "y": 1}]}]}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/draw", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.DrawShapes(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantOperation == "" {
+				return
+			}
+
+			var resp struct{
+				Data map[string]interface{} `json:"message"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tc.wantOperation, resp.Data["operation"])
+		})
+	}
+}
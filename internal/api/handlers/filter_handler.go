@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+func (i *ImageHandler) UnsharpMaskImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.UnsharpMaskRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts := imaging.UnsharpMaskOptions{
+		Radius: req.Radius.Float64(),
+		Amount: 1,
+		Threshold: req.Threshold.Float64(),
+		LuminanceOnly: req.LuminanceOnly,
+	}
+	if req.Amount != nil {
+		opts.Amount = req.Amount.Float64()
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(transform.UnsharpMask(opts)))
+	if err != nil {
+		logger.Error("Failed to apply unsharp mask", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": "unsharp",
+			"radius": opts.Radius,
+			"amount": opts.Amount,
+			"threshold": opts.Threshold,
+			"luminanceOnly": opts.LuminanceOnly,
+		},
+		Err: nil,
+	})
+}
+
+func (i *ImageHandler) ConvolveImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.ConvolveRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	op, err := convolveOperation(req)
+	if err != nil {
+		logger.Info("Rejected convolution kernel", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to convolve image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": "convolve",
+			"kernel": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+// ListKernels describes the built-in convolution kernels.
+func (i *ImageHandler) ListKernels(w http.ResponseWriter, r *http.Request) {
+	kernels := make([]map[string]interface{}, 0)
+	for _, name := range imaging.KernelNames() {
+		kernel, _ := imaging.NamedKernel(name)
+		divisor := kernel.Divisor
+		if divisor == 0 {
+			divisor = 1
+		}
+		kernels = append(kernels, map[string]interface{}{
+			"name": name,
+			"matrix": kernel.Matrix(),
+			"divisor": divisor,
+		})
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"kernels": kernels,
+		},
+		Err: nil,
+	})
+}
+
+// convolveOperation builds the operation for a request, reporting kernel
+// problems as field errors.
+func convolveOperation(req request.ConvolveRequest) (transform.Operation, error) {
+	opts := imaging.ConvolveOptions{
+		Normalize: req.Normalize,
+		Bias: req.Bias.Float64(),
+	}
+
+	switch {
+	case req.Kernel != "" && len(req.Matrix) > 0:
+		return nil, validation.Errors{{Field: "kernel", Message: "cannot be combined with matrix"}}
+	case req.Kernel != "":
+		op, err := transform.NamedConvolve(req.Kernel, opts)
+		if err != nil {
+			return nil, validation.Errors{{Field: "kernel", Message: err.Error()}}
+		}
+		return op, nil
+	case len(req.Matrix) == 0:
+		return nil, validation.Errors{{Field: "kernel", Message: "kernel or matrix is required"}}
+	}
+
+	values := make([]float64, 0, len(req.Matrix)*len(req.Matrix))
+	for _, row := range req.Matrix {
+		if len(row) != len(req.Matrix) {
+			return nil, validation.Errors{{Field: "matrix", Message: "must be square"}}
+		}
+		for _, value := range row {
+			values = append(values, value.Float64())
+		}
+	}
+
+	kernel, err := imaging.NewKernel(values)
+	if err != nil {
+		return nil, validation.Errors{{Field: "matrix", Message: err.Error()}}
+	}
+
+	return transform.Convolve(kernel, opts), nil
+}
+
+func (i *ImageHandler) DetectEdges(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.EdgeDetectRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts, err := edgeOptions(req)
+	if err != nil {
+		logger.Info("Rejected edge detection parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(transform.Edges(opts)))
+	if err != nil {
+		logger.Error("Failed to detect edges", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": "edges",
+			"method": req.Method,
+			"binary": opts.Binary || opts.Method == imaging.EdgeCanny,
+		},
+		Err: nil,
+	})
+}
+
+// edgeOptions checks the parameters that depend on the chosen method.
+func edgeOptions(req request.EdgeDetectRequest) (imaging.EdgeOptions, error) {
+	method, err := imaging.EdgeMethodFromName(req.Method)
+	if err != nil {
+		return imaging.EdgeOptions{}, validation.Errors{{Field: "method", Message: "must be one of " + strings.Join(imaging.EdgeMethodNames(), ", ")}}
+	}
+
+	opts := imaging.EdgeOptions{Method: method, Sigma: req.Sigma.Float64()}
+
+	if method != imaging.EdgeCanny {
+		if req.Low != nil || req.High != nil {
+			return opts, validation.Errors{{Field: "low", Message: "low and high only apply to canny"}}
+		}
+		if req.Threshold != nil {
+			opts.Binary = true
+			opts.Threshold = req.Threshold.Float64()
+		}
+		return opts, nil
+	}
+
+	if req.Threshold != nil {
+		return opts, validation.Errors{{Field: "threshold", Message: "does not apply to canny, use low and high"}}
+	}
+	if (req.Low == nil) != (req.High == nil) {
+		return opts, validation.Errors{{Field: "low", Message: "low and high must be given together"}}
+	}
+	if req.Low != nil {
+		opts.Low, opts.High = req.Low.Float64(), req.High.Float64()
+		if opts.Low > opts.High {
+			return opts, validation.Errors{{Field: "low", Message: "must not be above high"}}
+		}
+	}
+
+	return opts, nil
+}
+
+func (i *ImageHandler) DenoiseImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.DenoiseRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts, err := denoiseOptions(req)
+	if err != nil {
+		logger.Info("Rejected denoise parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(transform.Denoise(opts)))
+	if err != nil {
+		logger.Error("Failed to denoise image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": "denoise",
+			"method": req.Method,
+		},
+		Err: nil,
+	})
+}
+
+// denoiseOptions checks the parameters that depend on the chosen method.
+func denoiseOptions(req request.DenoiseRequest) (imaging.DenoiseOptions, error) {
+	method, err := imaging.DenoiseMethodFromName(req.Method)
+	if err != nil {
+		return imaging.DenoiseOptions{}, validation.Errors{{Field: "method", Message: "must be one of " + strings.Join(imaging.DenoiseMethodNames(), ", ")}}
+	}
+
+	opts := imaging.DenoiseOptions{Method: method}
+
+	given := map[string]bool{
+		"radius": req.Radius != nil,
+		"spatialSigma": req.SpatialSigma != nil,
+		"rangeSigma": req.RangeSigma != nil,
+		"strength": req.Strength != nil,
+		"patchRadius": req.PatchRadius != nil,
+	}
+	allowed := map[imaging.DenoiseMethod][]string{
+		imaging.DenoiseMedian: {"radius"},
+		imaging.DenoiseBilateral: {"spatialSigma", "rangeSigma"},
+		imaging.DenoiseNLMeans: {"radius", "strength", "patchRadius"},
+	}
+
+	var errs validation.Errors
+	for _, field := range []string{"radius", "spatialSigma", "rangeSigma", "strength", "patchRadius"} {
+		if given[field] && !slices.Contains(allowed[method], field) {
+			errs = append(errs, validation.FieldError{Field: field, Message: "does not apply to " + req.Method})
+		}
+	}
+	if method == imaging.DenoiseMedian && req.Radius != nil && *req.Radius > imaging.MaxMedianRadius {
+		errs = append(errs, validation.FieldError{Field: "radius", Message: fmt.Sprintf("must be at most %d for median", imaging.MaxMedianRadius)})
+	}
+	if len(errs) > 0 {
+		return opts, errs
+	}
+
+	if req.Radius != nil {
+		opts.Radius = *req.Radius
+	}
+	if req.PatchRadius != nil {
+		opts.PatchRadius = *req.PatchRadius
+	}
+	if req.SpatialSigma != nil {
+		opts.SpatialSigma = req.SpatialSigma.Float64()
+	}
+	if req.RangeSigma != nil {
+		opts.RangeSigma = req.RangeSigma.Float64()
+	}
+	if req.Strength != nil {
+		opts.Strength = req.Strength.Float64()
+	}
+
+	return opts, nil
+}
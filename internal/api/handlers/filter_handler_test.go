@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_UnsharpMaskImage(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+		checkResponse func(map[string]interface{})
+	}{
+		{
+			name: "Defaults amount to 1",
+			body: `{"sessionID": "session-imageId", "radius": 2, "threshold": 3, "luminanceOnly": true}`,
+			wantStatus: http.StatusCreated,
+			checkResponse: func(data map[string]interface{}) {
+				assert.Equal(t, "unsharp", data["operation"])
+				assert.Equal(t, 1.0, data["amount"])
+				assert.Equal(t, true, data["luminanceOnly"])
+			},
+		},
+		{
+			name: "Rejects out of range parameters",
+			body: `{"sessionID": "session-imageId", "radius": 0, "amount": 11, "threshold": 300}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown session",
+			body: `{"sessionID": "missing", "radius": 2}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/unsharp", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.UnsharpMaskImage(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.checkResponse != nil {
+				var resp response.BaseResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+				data, ok := resp.Data.(map[string]interface{})
+				require.True(t, ok)
+				tc.checkResponse(data)
+			}
+		})
+	}
+}
+
+func TestImageHandler_ConvolveImage(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+		wantKernel string
+	}{
+		{
+			name: "Named kernel",
+			body: `{"sessionID": "session-imageId", "kernel": "emboss", "bias": 128}`,
+			wantStatus: http.StatusCreated,
+			wantKernel: "convolve_emboss,bias_128",
+		},
+		{
+			name: "Custom matrix",
+			body: `{"sessionID": "session-imageId", "matrix": [[0, -1, 0], [-1, 5, -1], [0, -1, 0]]}`,
+			wantStatus: http.StatusCreated,
+			wantKernel: "convolve,k_0:-1:0:-1:5:-1:0:-1:0",
+		},
+		{
+			name: "Unknown kernel",
+			body: `{"sessionID": "session-imageId", "kernel": "sparkle"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Even sized matrix",
+			body: `{"sessionID": "session-imageId", "matrix": [[1, 1], [1, 1]]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Ragged matrix",
+			body: `{"sessionID": "session-imageId", "matrix": [[1, 1, 1], [1, 1], [1, 1, 1]]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Neither kernel nor matrix",
+			body: `{"sessionID": "session-imageId"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/convolve", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.ConvolveImage(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantKernel != "" {
+				var resp response.BaseResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+				data, ok := resp.Data.(map[string]interface{})
+				require.True(t, ok)
+				assert.Equal(t, tc.wantKernel, data["kernel"])
+			}
+		})
+	}
+}
+
+func TestImageHandler_DetectEdges(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Canny with thresholds",
+			body: `{"sessionID": "session-imageId", "method": "canny", "low": 10, "high": 40}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Binary Sobel",
+			body: `{"sessionID": "session-imageId", "method": "sobel", "threshold": 64}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Unknown method",
+			body: `{"sessionID": "session-imageId", "method": "roberts"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Low above high",
+			body: `{"sessionID": "session-imageId", "method": "canny", "low": 50, "high": 40}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Threshold with canny",
+			body: `{"sessionID": "session-imageId", "method": "canny", "threshold": 40}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/edges", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.DetectEdges(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestImageHandler_DenoiseImage(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Median with defaults",
+			body: `{"sessionID": "session-imageId", "method": "median"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Bilateral",
+			body: `{"sessionID": "session-imageId", "method": "bilateral", "spatialSigma": 2, "rangeSigma": "25"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Parameter of another method",
+			body: `{"sessionID": "session-imageId", "method": "median", "strength": 10}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Median window too large",
+			body: `{"sessionID": "session-imageId", "method": "median", "radius": 9}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown method",
+			body: `{"sessionID": "session-imageId", "method": "wavelet"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/denoise", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.DenoiseImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/metrics"
+	"go.uber.org/zap"
+)
+
+// imageETag is the strong entity tag for a representation identified by a
+// content hash.
+func imageETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// lastModified is when the session's current image was produced.
+func lastModified(session interfaces.SessionData) time.Time {
+	if !session.ModifiedTime.IsZero() {
+		return session.ModifiedTime
+	}
+
+	return session.UploadTime
+}
+
+func setCacheHeaders(w http.ResponseWriter, etag string, modTime time.Time, cacheControl string) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is no
+// If-None-Match, so a 304 can be sent before doing any expensive work.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !modTime.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+func writeNotModified(w http.ResponseWriter) {
+	// a 304 must not describe a body
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// serveImage writes an image representation, answering conditional and
+// Range requests. The caching headers must already be set.
+func serveImage(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, contentType string, modTime time.Time) {
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", modTime, content)
+}
+
+// negotiateFormat picks the output format from the Accept header, answering
+// 406 when none of the supported formats is acceptable.
+func (i *ImageHandler) negotiateFormat(w http.ResponseWriter, r *http.Request, source imaging.Format) (imaging.Format, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	accept := r.Header.Get("Accept")
+	format, ok := i.formatPolicy.Negotiate(accept, source)
+	if !ok {
+		i.response.WriteError(w, "None of the supported image formats is acceptable", http.StatusNotAcceptable)
+		return format, false
+	}
+
+	logger.LoggerFromContext(r.Context()).Info("Negotiated output format",
+		zap.String("accept", accept),
+		zap.String("source_format", imaging.FormatName(source)),
+		zap.String("format", imaging.FormatName(format)),
+	)
+	metrics.OutputFormats.Add(imaging.FormatName(format), 1)
+
+	return format, true
+}
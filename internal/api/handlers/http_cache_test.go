@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_DownloadImageConditional(t *testing.T) {
+	imagePath := filepath.Join(t.TempDir(), "image.jpg")
+	createTestImage(t, imagePath)
+
+	modified := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		OriginalFilename: "image.jpg",
+		TempPath: imagePath,
+		ContentHash: hashFile(imagePath),
+		ModifiedTime: modified,
+	})
+
+	cfg := config.Default()
+	cfg.DownloadCacheControl = "private, max-age=60"
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), cfg, nil, nil, nil)
+
+	download := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/sessions/session-imageId/download", nil)
+		req.SetPathValue("sessionId", "session-imageId")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.DownloadImage(rec, req)
+		return rec
+	}
+
+	rec := download(nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Equal(t, `"`+hashFile(imagePath)+`"`, etag)
+	assert.Equal(t, modified.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+	assert.Equal(t, "private, max-age=60", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+	full := rec.Body.Bytes()
+
+	rec = download(map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.Bytes())
+
+	rec = download(map[string]string{"If-None-Match": `"stale"`})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = download(map[string]string{"If-Modified-Since": modified.Add(time.Minute).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+
+	rec = download(map[string]string{"Range": "bytes=0-9"})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, full[:10], rec.Body.Bytes())
+}
+
+func TestImageHandler_TransformImageNotModified(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+		ContentHash: "source-hash",
+	})
+
+	mockImaging := newMockImaging()
+	handler := NewImageHandler(response.NewResponse(), mockStore, mockImaging, config.Default(), nil, nil, nil)
+
+	transformImage := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/img/session-imageId/blur_2", nil)
+		req.SetPathValue("sessionId", "session-imageId")
+		req.SetPathValue("ops", "blur_2")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.TransformImage(rec, req)
+		return rec
+	}
+
+	rec := transformImage("")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// a matching ETag is answered without rendering the image
+	mockImaging.openError = errors.New("should not be opened")
+	rec = transformImage(etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestImageHandler_TransformImageNegotiatesFormat(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		ops string
+		accept string
+		wantStatus int
+		wantContentType string
+	}{
+		{
+			name: "Keeps the source format by default",
+			ops: "blur_2",
+			accept: "*/*",
+			wantStatus: http.StatusOK,
+			wantContentType: "image/jpeg",
+		},
+		{
+			name: "Converts to an accepted format",
+			ops: "blur_2",
+			accept: "image/png, image/jpeg;q=0.5",
+			wantStatus: http.StatusOK,
+			wantContentType: "image/png",
+		},
+		{
+			name: "Explicit format wins over Accept",
+			ops: "blur_2/format_gif",
+			accept: "image/png",
+			wantStatus: http.StatusOK,
+			wantContentType: "image/gif",
+		},
+		{
+			name: "Nothing acceptable",
+			ops: "blur_2",
+			accept: "text/html",
+			wantStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/img/session-imageId/"+tc.ops, nil)
+			req.SetPathValue("sessionId", "session-imageId")
+			req.SetPathValue("ops", tc.ops)
+			req.Header.Set("Accept", tc.accept)
+
+			rec := httptest.NewRecorder()
+			handler.TransformImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
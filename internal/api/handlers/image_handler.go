@@ -1,161 +1,281 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"time"
 
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
 	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
 	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
 	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/negotiate"
+	"github.com/dylan0804/image-processing-tool/internal/api/remote"
 	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
 	"github.com/dylan0804/image-processing-tool/internal/api/storage"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/upload"
 	"github.com/dylan0804/image-processing-tool/internal/models/request"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
+
 type ImageHandler struct {
 	response *response.Response
 	sessionStore storage.RedisSessionStore
 	imaging imaging.Imaging
+	config config.Config
+	fetcher *remote.Fetcher
+	signer *signing.Signer
+	cache *cache.Cache
+	formatPolicy negotiate.Policy
+	assets *assets.Store
 }
 
-func NewImageHandler(response *response.Response, sessionStore storage.RedisSessionStore, imaging imaging.Imaging) *ImageHandler {
+func NewImageHandler(response *response.Response, sessionStore storage.RedisSessionStore, imaging imaging.Imaging, config config.Config, signer *signing.Signer, cache *cache.Cache, assets *assets.Store) *ImageHandler {
 	return &ImageHandler{
 		response: response,
 		sessionStore: sessionStore,
 		imaging: imaging,
+		config: config,
+		fetcher: remote.NewFetcher(config.ImportOptions()),
+		signer: signer,
+		cache: cache,
+		formatPolicy: config.FormatPolicy(),
+		assets: assets,
 	}
 }
 
 func (i *ImageHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	logger := logger.LoggerFromContext(r.Context())
 
-	err := r.ParseMultipartForm(10 >> 20)
-	if err != nil {
-		logger.Error("Failed to parse form", zap.Error(err))
-		i.response.WriteError(w, err.Error(), http.StatusBadRequest)
+	r.Body = http.MaxBytesReader(w, r.Body, i.config.MaxUploadBytes)
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = uploadModeSeparate
+	}
+	if mode != uploadModeSeparate && mode != uploadModeSingle {
+		i.response.WriteError(w, "mode must be either separate or single", http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("image")
+	files, err := i.readUploads(r)
 	if err != nil {
-		logger.Error("Failed to get file", zap.Error(err))
-		i.response.WriteError(w, err.Error(), http.StatusBadRequest)
+		logger.Error("Failed to read upload", zap.Error(err))
+		i.response.WriteError(w, err.Error(), uploadErrorStatus(err))
 		return
 	}
-	defer file.Close()
 
-	sessionID := uuid.New().String()
+	// validate everything before creating any session so a bad file
+	// rejects the whole request
+	images := make([]*upload.Image, 0, len(files))
+	for _, file := range files {
+		img, err := upload.Validate(file.data, file.filename, i.config.ImageLimits())
+		if err != nil {
+			logger.Error("Rejected upload", zap.String("filename", file.filename), zap.Error(err))
+			i.response.WriteError(w, fmt.Sprintf("%s: %s", file.filename, err.Error()), uploadErrorStatus(err))
+			return
+		}
+		images = append(images, img)
+	}
 
-	tempDir := os.TempDir()
-	tempPath := filepath.Join(tempDir, sessionID+filepath.Ext(header.Filename))
+	if mode == uploadModeSingle || len(images) == 1 {
+		sessionID, err := i.createSession(r.Context(), images)
+		if err != nil {
+			logger.Error("Failed to create session", zap.Error(err))
+			i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+			return
+		}
 
-	err = i.sessionStore.Set(r.Context(), sessionID, interfaces.SessionData{
-		OriginalFilename: header.Filename,
-		TempPath: tempPath,
-		UploadTime: time.Now(),
-	})
-	if err != nil {
-		logger.Error("Failed to store metadata to redis", zap.Error(err))
-		i.response.WriteError(w, err.Error(), http.StatusInternalServerError)
+		data := map[string]interface{}{
+			"sessionId": sessionID,
+		}
+		if len(images) > 1 {
+			data["images"] = len(images)
+		}
+
+		i.response.WriteSuccess(w, &response.BaseResponse{
+			Success: true,
+			Data: data,
+			Err: nil,
+		})
 		return
 	}
 
-	tempFile, err := os.Create(tempPath)
-	if err != nil {
-        logger.Error("Failed to create temp file", zap.Error(err))
-        i.response.WriteError(w, "Server error", http.StatusInternalServerError)
-        return
-    }
-    defer tempFile.Close()
-
-	_, err = io.Copy(tempFile, file)
-    if err != nil {
-        logger.Error("Failed to save file", zap.Error(err))
-        i.response.WriteError(w, "Server error", http.StatusInternalServerError)
-        return
-    }
-
-	logger.Info("Image stored at", zap.String("temp path", tempPath))
+	sessions := make([]map[string]interface{}, 0, len(images))
+	for _, img := range images {
+		sessionID, err := i.createSession(r.Context(), []*upload.Image{img})
+		if err != nil {
+			logger.Error("Failed to create session", zap.Error(err))
+			// the request fails as a whole, so drop the sessions it did create
+			for _, created := range sessions {
+				i.discardSession(r.Context(), created["sessionId"].(string))
+			}
+			i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		sessions = append(sessions, map[string]interface{}{
+			"sessionId": sessionID,
+			"filename": img.Filename,
+		})
+	}
 
 	i.response.WriteSuccess(w, &response.BaseResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"sessionId": sessionID,
+			"sessions": sessions,
 		},
 		Err: nil,
 	})
 }
 
-func (i *ImageHandler) BlurImage(w http.ResponseWriter, r *http.Request) {
+// maxImportRequestBytes caps the body of an import request, which only
+// carries a URL.
+const maxImportRequestBytes = 64 << 10
+
+func (i *ImageHandler) ImportImage(w http.ResponseWriter, r *http.Request) {
 	logger := logger.LoggerFromContext(r.Context())
 
-	var blurImageRequest request.BlurImageRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportRequestBytes)
+
+	var req request.ImportImageRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&blurImageRequest); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.Error("Failed to decode request body", zap.Error(err))
-		i.response.WriteError(w, "Failed to decode request body", http.StatusBadRequest)
+		i.response.WriteError(w, "Failed to decode request body", uploadErrorStatus(err))
 		return
 	}
 
-	session, ok, err := i.sessionStore.Get(r.Context(), blurImageRequest.SessionID)
-	if err != nil || !ok{
-		logger.Error("Failed to get current image", zap.Error(err))
-		i.response.WriteError(w, "Failed to get current image", http.StatusInternalServerError)
+	result, err := i.fetcher.Fetch(r.Context(), req.URL)
+	if err != nil {
+		logger.Error("Failed to fetch remote image", zap.String("origin", urlOrigin(req.URL)), zap.Error(err))
+		i.response.WriteError(w, err.Error(), importErrorStatus(err))
 		return
 	}
 
-	img, err := i.imaging.Open(session.TempPath)
+	img, err := upload.Validate(result.Data, result.Filename, i.config.ImageLimits())
 	if err != nil {
-		logger.Error("Failed to open image", zap.Error(err))
-		i.response.WriteError(w, "Failed to open image", http.StatusInternalServerError)
+		logger.Error("Rejected imported image", zap.String("origin", urlOrigin(req.URL)), zap.Error(err))
+		i.response.WriteError(w, err.Error(), uploadErrorStatus(err))
 		return
 	}
 
-	sigmaInt, err := strconv.Atoi(blurImageRequest.Sigma)
+	sessionID, err := i.createSession(r.Context(), []*upload.Image{img})
 	if err != nil {
-		logger.Error("Failed to convert sigma to int", zap.Error(err))
-		i.response.WriteError(w, "Failed to convert sigma to int", http.StatusInternalServerError)
+		logger.Error("Failed to create session", zap.Error(err))
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
 		return
-	}	
+	}
 
-	blurredImg := i.imaging.Blur(img, float64(sigmaInt))
+	logger.Info("Imported remote image", zap.String("origin", urlOrigin(req.URL)), zap.String("session_id", sessionID))
 
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+		},
+		Err: nil,
+	})
+}
+
+// createSession writes validated images to temp files and records them under
+// a new session. The first image becomes the one operations are applied to.
+func (i *ImageHandler) createSession(ctx context.Context, images []*upload.Image) (string, error) {
+	logger := logger.LoggerFromContext(ctx)
+
+	sessionID := uuid.New().String()
 	tempDir := os.TempDir()
-	tempPath := filepath.Join(tempDir, uuid.New().String()+filepath.Ext(session.OriginalFilename))
 
-	err = i.imaging.Save(blurredImg, tempPath)
-	if err != nil {
-		logger.Error("Failed to save blurred image", zap.Error(err))
-		i.response.WriteError(w, "Failed to save blurred image", http.StatusInternalServerError)
-		return
+	sessionImages := make([]interfaces.SessionImage, 0, len(images))
+	cleanup := func() {
+		for _, img := range sessionImages {
+			os.Remove(img.TempPath)
+		}
 	}
 
-	oldTempPath := session.TempPath
-	session.TempPath = tempPath
+	for idx, img := range images {
+		name := sessionID
+		if len(images) > 1 {
+			name = fmt.Sprintf("%s-%d", sessionID, idx)
+		}
+		tempPath := filepath.Join(tempDir, name+upload.Extension(img.Format))
 
-	err = i.sessionStore.Set(r.Context(), blurImageRequest.SessionID, session)
-	if err != nil {
-		logger.Error("Failed to update session", zap.Error(err))
-		i.response.WriteError(w, "Failed to update session", http.StatusInternalServerError)
+		if err := os.WriteFile(tempPath, img.Data, 0600); err != nil {
+			cleanup()
+			return "", err
+		}
+
+		sessionImages = append(sessionImages, interfaces.SessionImage{
+			OriginalFilename: img.Filename,
+			TempPath: tempPath,
+		})
+
+		logger.Info("Image stored at", zap.String("temp path", tempPath))
+	}
+
+	session := interfaces.SessionData{
+		OriginalFilename: sessionImages[0].OriginalFilename,
+		TempPath: sessionImages[0].TempPath,
+		UploadTime: time.Now(),
+		ContentHash: hashBytes(images[0].Data),
+		ModifiedTime: time.Now(),
+	}
+	if len(sessionImages) > 1 {
+		session.Images = sessionImages
+	}
+
+	if err := i.sessionStore.Set(ctx, sessionID, session); err != nil {
+		cleanup()
+		return "", err
+	}
+
+	return sessionID, nil
+}
+
+// discardSession deletes a session along with its images.
+func (i *ImageHandler) discardSession(ctx context.Context, sessionID string) {
+	if session, ok, err := i.sessionStore.Get(ctx, sessionID); err == nil && ok {
+		os.Remove(session.TempPath)
+		for _, img := range session.Images {
+			os.Remove(img.TempPath)
+		}
+	}
+
+	i.sessionStore.Delete(ctx, sessionID)
+}
+
+func (i *ImageHandler) BlurImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var blurImageRequest request.BlurImageRequest
+
+	if !i.decodeRequest(w, r, &blurImageRequest) {
 		return
 	}
 
-	// clean up
-	if oldTempPath != "" && tempPath != oldTempPath {
-		os.Remove(oldTempPath)
+	session, err := i.applyToSession(r.Context(), blurImageRequest.SessionID, transform.NewChain(transform.Blur(blurImageRequest.Sigma.Float64())))
+	if err != nil {
+		logger.Error("Failed to blur image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
 	}
 
 	i.response.WriteSuccess(w, &response.BaseResponse{
 		Success: true,
 		Data: map[string]interface{}{
 			"sessionId": blurImageRequest.SessionID,
-			"path": tempPath,
+			"path": session.TempPath,
 			"operation": "blur",
 			"sigma": blurImageRequest.Sigma,
 		},
@@ -168,75 +288,75 @@ func (i *ImageHandler) SharpenImage(w http.ResponseWriter, r *http.Request) {
 
 	var req request.SharpenImageRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Error("Failed to decode request body", zap.Error(err))
-		i.response.WriteError(w, "Failed to decode request body", http.StatusBadRequest)
-		return
-	}
-
-	// check from redis
-	session, exists, err := i.sessionStore.Get(r.Context(), req.SessionID)
-	if err != nil || !exists {
-		logger.Error("Failed to get session ID", zap.Error(err))
-		i.response.WriteError(w, "Failed to get session ID", http.StatusBadRequest)
+	if !i.decodeRequest(w, r, &req) {
 		return
 	}
 
-	// if ok
-	// get image
-	img, err := i.imaging.Open(session.TempPath)
+	// sharpen image and make it the session's current one
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(transform.Sharpen(req.Sigma.Float64())))
 	if err != nil {
-		logger.Error("Failed to open image", zap.Error(err))
-		i.response.WriteError(w, "Failed to open image", http.StatusBadRequest)
+		logger.Error("Failed to sharpen image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
 		return
 	}
 
-	// convert sigma to int
-	sigmaInt, err := strconv.Atoi(req.Sigma)
-	if err != nil {
-		logger.Error("Failed to convert sigma to int", zap.Error(err))
-		i.response.WriteError(w, "Failed to convert sigma to int", http.StatusInternalServerError)
-		return
-	}	
-
-	// sharpen image
-	sharpenedImg := i.imaging.Sharpen(img, float64(sigmaInt))
-
-	// create a new tmp file
-	tempDir := os.TempDir()
-	tempPath := filepath.Join(tempDir, uuid.NewString()+filepath.Ext(session.OriginalFilename))
-
-	// save file
-	err = i.imaging.Save(sharpenedImg, tempPath)
-	if err != nil {
-		logger.Error("Failed to save sharpened image", zap.Error(err))
-		i.response.WriteError(w, "Failed to save sharpened image", http.StatusInternalServerError)
-		return
-	}
-
-	// delete the old one
-	oldTempPath := session.TempPath
-	session.TempPath = tempPath
-
-	err = i.sessionStore.Set(r.Context(), req.SessionID, session)
-	if err != nil {
-		logger.Error("Failed to update session", zap.Error(err))
-		i.response.WriteError(w, "Failed to update session", http.StatusInternalServerError)
-		return
-	}
-
-	if oldTempPath != "" && oldTempPath != tempPath {
-		os.Remove(oldTempPath)
-	}
-
 	// return
 	i.response.WriteSuccess(w, &response.BaseResponse{
 		Success: true,
 		Data: map[string]interface{}{
 			"sessionId": req.SessionID,
-			"path": tempPath,
+			"path": session.TempPath,
 			"operation": "sharpen",
 			"sigma": req.Sigma,
 		},
 	})
+}
+
+// uploadErrorStatus maps errors from reading and validating an upload to the
+// HTTP status reported to the client.
+func uploadErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, imaging.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, upload.ErrUnsupportedFormat), errors.Is(err, errUnsupportedContentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, upload.ErrEmptyFile), errors.Is(err, upload.ErrInvalidImage):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// urlOrigin returns the scheme and host of a URL for logging, leaving out
+// credentials, paths and query strings that may carry secrets.
+func urlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+// importErrorStatus maps errors from fetching a remote image to the HTTP
+// status reported to the client.
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, remote.ErrInvalidURL):
+		return http.StatusBadRequest
+	case errors.Is(err, remote.ErrBlockedDestination):
+		return http.StatusForbidden
+	case errors.Is(err, remote.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, remote.ErrContentType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, remote.ErrTooManyRedirects):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadGateway
+	}
 }
\ No newline at end of file
@@ -3,7 +3,9 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"image"
 	"image/jpeg"
 	"io"
@@ -12,9 +14,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/disintegration/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	imgproc "github.com/dylan0804/image-processing-tool/internal/api/imaging"
 	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
 	"github.com/dylan0804/image-processing-tool/internal/api/response"
 	"github.com/dylan0804/image-processing-tool/internal/models/request"
@@ -47,6 +52,19 @@ func (m *mockSessionStore) Delete(ctx context.Context, sessionID string) error {
 	delete(m.store, sessionID)
 	return nil
 }
+
+// fullSessionStore fails to store more than limit sessions.
+type fullSessionStore struct {
+	*mockSessionStore
+	limit int
+}
+
+func (m *fullSessionStore) Set(ctx context.Context, sessionID string, data interfaces.SessionData) error {
+	if _, exists := m.store[sessionID]; !exists && len(m.store) >= m.limit {
+		return errors.New("store is full")
+	}
+	return m.mockSessionStore.Set(ctx, sessionID, data)
+}
 // =====
 
 type mockImaging struct {
@@ -75,6 +93,63 @@ func (m *mockImaging) Save(img image.Image, path string) error {
 func (m *mockImaging) Sharpen(image image.Image, sigma float64) image.Image {
 	return m.src
 }
+func (m *mockImaging) UnsharpMask(img image.Image, opts imgproc.UnsharpMaskOptions) *image.NRGBA {
+	return imaging.Clone(img)
+}
+func (m *mockImaging) Convolve(img image.Image, kernel imgproc.Kernel, opts imgproc.ConvolveOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) DetectEdges(img image.Image, opts imgproc.EdgeOptions) (*image.Gray, error) {
+	return image.NewGray(img.Bounds()), nil
+}
+func (m *mockImaging) Denoise(img image.Image, opts imgproc.DenoiseOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) AutoLevels(img image.Image, opts imgproc.AutoLevelsOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Equalize(img image.Image) *image.NRGBA {
+	return imaging.Clone(img)
+}
+func (m *mockImaging) CLAHE(img image.Image, opts imgproc.CLAHEOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Levels(img image.Image, channel imgproc.Channel, levels imgproc.Levels) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Curves(img image.Image, channel imgproc.Channel, curve imgproc.Curve) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) ApplyChannelLUTs(img image.Image, luts imgproc.ChannelLUTs) *image.NRGBA {
+	return imaging.Clone(img)
+}
+func (m *mockImaging) ApplyCubeLUT(img image.Image, lut *imgproc.CubeLUT, opts imgproc.CubeLUTOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Overlay(base, overlay image.Image, opts imgproc.OverlayOptions) (*image.NRGBA, error) {
+	return imaging.Clone(base), nil
+}
+func (m *mockImaging) DrawText(img image.Image, opts imgproc.TextOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) DrawShapes(img image.Image, shapes []imgproc.Shape) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Redact(img image.Image, opts imgproc.RedactOptions) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) BlendMask(original, filtered image.Image, opts imgproc.MaskOptions) (*image.NRGBA, error) {
+	return imaging.Clone(filtered), nil
+}
+func (m *mockImaging) ExtendCanvas(img image.Image, insets imgproc.Insets, fill imgproc.CanvasFill) (*image.NRGBA, error) {
+	return imaging.Clone(img), nil
+}
+func (m *mockImaging) Resize(img image.Image, width, height int, mode imgproc.ResizeMode) (*image.NRGBA, error) {
+	return imaging.Resize(img, width, height, imaging.Box), nil
+}
+func (m *mockImaging) Encode(w io.Writer, img image.Image, format imgproc.Format) error {
+	return imaging.Encode(w, img, format)
+}
 // =========
 
 func TestImageHandler_UploadImage(t *testing.T) {
@@ -89,7 +164,7 @@ func TestImageHandler_UploadImage(t *testing.T) {
 	mockStore := newMockSessionStore()
 	respHelper := response.NewResponse()
 
-	handler := NewImageHandler(respHelper, mockStore, nil)
+	handler := NewImageHandler(respHelper, mockStore, nil, config.Default(), nil, nil, nil)
 
 	tests := []struct{
 		name string
@@ -184,6 +259,320 @@ func TestImageHandler_UploadImage(t *testing.T) {
 	}
 }
 
+func TestImageHandler_UploadImageValidation(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	err := jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+	require.NoError(t, err)
+
+	testcases := []struct{
+		name string
+		filename string
+		content []byte
+		configure func(cfg *config.Config)
+		wantStatus int
+	}{
+		{
+			name: "Rejects non-image content",
+			filename: "notes.jpg",
+			content: []byte("definitely not an image"),
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "Rejects truncated image",
+			filename: "broken.jpg",
+			content: jpegBuf.Bytes()[:16],
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Rejects oversized body",
+			filename: "big.jpg",
+			content: jpegBuf.Bytes(),
+			configure: func(cfg *config.Config) {
+				cfg.MaxUploadBytes = 64
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "Rejects image exceeding dimension limits",
+			filename: "wide.jpg",
+			content: jpegBuf.Bytes(),
+			configure: func(cfg *config.Config) {
+				cfg.MaxImageWidth = 2
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := newMockSessionStore()
+			cfg := config.Default()
+			if tc.configure != nil {
+				tc.configure(&cfg)
+			}
+
+			handler := NewImageHandler(response.NewResponse(), mockStore, nil, cfg, nil, nil, nil)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile("image", tc.filename)
+			require.NoError(t, err)
+			_, err = part.Write(tc.content)
+			require.NoError(t, err)
+			writer.Close()
+
+			req := httptest.NewRequest("POST", "/upload", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			rec := httptest.NewRecorder()
+
+			handler.UploadImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Empty(t, mockStore.store)
+		})
+	}
+}
+
+func TestImageHandler_UploadImageSources(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	err := jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+	require.NoError(t, err)
+	jpegBytes := jpegBuf.Bytes()
+
+	multipartBody := func(count int) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for n := 0; n < count; n++ {
+			part, err := writer.CreateFormFile("image", "photo.jpg")
+			require.NoError(t, err)
+			_, err = part.Write(jpegBytes)
+			require.NoError(t, err)
+		}
+		writer.Close()
+		return body, writer.FormDataContentType()
+	}
+
+	testcases := []struct{
+		name string
+		setupRequest func() *http.Request
+		wantSessions int
+		checkData func(data map[string]interface{})
+	}{
+		{
+			name: "Raw body upload",
+			setupRequest: func() *http.Request {
+				req := httptest.NewRequest("POST", "/upload", bytes.NewReader(jpegBytes))
+				req.Header.Set("Content-Type", "image/jpeg")
+				req.Header.Set("X-Filename", "raw.jpg")
+				return req
+			},
+			wantSessions: 1,
+			checkData: func(data map[string]interface{}) {
+				assert.NotEmpty(t, data["sessionId"])
+			},
+		},
+		{
+			name: "Base64 data URI in JSON",
+			setupRequest: func() *http.Request {
+				payload, err := json.Marshal(request.UploadImageRequest{
+					UploadImageData: request.UploadImageData{
+						Filename: "inline.jpg",
+						Data: "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(jpegBytes),
+					},
+				})
+				require.NoError(t, err)
+
+				req := httptest.NewRequest("POST", "/upload", bytes.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantSessions: 1,
+			checkData: func(data map[string]interface{}) {
+				assert.NotEmpty(t, data["sessionId"])
+			},
+		},
+		{
+			name: "Multiple files get one session each",
+			setupRequest: func() *http.Request {
+				body, contentType := multipartBody(3)
+				req := httptest.NewRequest("POST", "/upload", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantSessions: 3,
+			checkData: func(data map[string]interface{}) {
+				sessions, ok := data["sessions"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, sessions, 3)
+			},
+		},
+		{
+			name: "Multiple files in a single session",
+			setupRequest: func() *http.Request {
+				body, contentType := multipartBody(2)
+				req := httptest.NewRequest("POST", "/upload?mode=single", body)
+				req.Header.Set("Content-Type", contentType)
+				return req
+			},
+			wantSessions: 1,
+			checkData: func(data map[string]interface{}) {
+				assert.NotEmpty(t, data["sessionId"])
+				assert.Equal(t, float64(2), data["images"])
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := newMockSessionStore()
+			handler := NewImageHandler(response.NewResponse(), mockStore, nil, config.Default(), nil, nil, nil)
+
+			rec := httptest.NewRecorder()
+
+			handler.UploadImage(rec, tc.setupRequest())
+
+			var resp response.BaseResponse
+			err := json.NewDecoder(rec.Body).Decode(&resp)
+			require.NoError(t, err)
+			require.True(t, resp.Success, "response: %v", resp.Data)
+
+			data, ok := resp.Data.(map[string]interface{})
+			require.True(t, ok)
+			tc.checkData(data)
+
+			assert.Len(t, mockStore.store, tc.wantSessions)
+			for _, session := range mockStore.store {
+				assert.FileExists(t, session.TempPath)
+				os.Remove(session.TempPath)
+				for _, img := range session.Images {
+					os.Remove(img.TempPath)
+				}
+			}
+		})
+	}
+}
+
+func TestImageHandler_UploadImageSeparateFailure(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil))
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for n := 0; n < 3; n++ {
+		part, err := writer.CreateFormFile("image", "photo.jpg")
+		require.NoError(t, err)
+		_, err = part.Write(jpegBuf.Bytes())
+		require.NoError(t, err)
+	}
+	writer.Close()
+
+	mockStore := &fullSessionStore{mockSessionStore: newMockSessionStore(), limit: 2}
+	handler := NewImageHandler(response.NewResponse(), mockStore, nil, config.Default(), nil, nil, nil)
+
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.UploadImage(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	// the sessions created before the failure are gone
+	assert.Empty(t, mockStore.store)
+}
+
+func TestImageHandler_ImportImage(t *testing.T) {
+	var jpegBuf bytes.Buffer
+	err := jpeg.Encode(&jpegBuf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(jpegBuf.Bytes())
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testcases := []struct{
+		name string
+		url string
+		allowedHosts []string
+		wantStatus int
+	}{
+		{
+			name: "Imports allow-listed host",
+			url: server.URL + "/photo.jpg",
+			allowedHosts: []string{"127.0.0.1"},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Blocks loopback by default",
+			url: server.URL + "/photo.jpg",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "Rejects non-image content",
+			url: server.URL + "/page.html",
+			allowedHosts: []string{"127.0.0.0/8"},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "Stops redirect loops",
+			url: server.URL + "/loop",
+			allowedHosts: []string{"127.0.0.1"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Rejects unsupported scheme",
+			url: "file:///etc/passwd",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Rejects oversized request",
+			url: server.URL + "/photo.jpg?pad=" + strings.Repeat("a", maxImportRequestBytes),
+			allowedHosts: []string{"127.0.0.1"},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := newMockSessionStore()
+			cfg := config.Default()
+			cfg.ImportAllowedHosts = tc.allowedHosts
+
+			handler := NewImageHandler(response.NewResponse(), mockStore, nil, cfg, nil, nil, nil)
+
+			body, err := json.Marshal(request.ImportImageRequest{URL: tc.url})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("POST", "/import", bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+
+			handler.ImportImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantStatus == http.StatusCreated {
+				assert.Len(t, mockStore.store, 1)
+				for _, session := range mockStore.store {
+					assert.Equal(t, "photo.jpg", session.OriginalFilename)
+					os.Remove(session.TempPath)
+				}
+			} else {
+				assert.Empty(t, mockStore.store)
+			}
+		})
+	}
+}
+
 func TestImageHandler_BlurImage(t *testing.T) {
 	mockStore := newMockSessionStore()
 	mockStore.Set(context.Background(), "image-sessionId", interfaces.SessionData{
@@ -193,7 +582,7 @@ func TestImageHandler_BlurImage(t *testing.T) {
 	respHelper := response.NewResponse()
 	mockImaging := newMockImaging()
 
-	handler := NewImageHandler(respHelper, mockStore, mockImaging)
+	handler := NewImageHandler(respHelper, mockStore, mockImaging, config.Default(), nil, nil, nil)
 
 	testcases := []struct{
 		name string
@@ -205,7 +594,7 @@ func TestImageHandler_BlurImage(t *testing.T) {
 			setupRequest: func() (*http.Request, error) {
 				blurImageRequest := request.BlurImageRequest{
 					SessionID: "image-sessionId",
-					Sigma: "10",
+					Sigma: 10,
 				}
 
 				jsonBytes, err := json.Marshal(&blurImageRequest)
@@ -236,6 +625,55 @@ func TestImageHandler_BlurImage(t *testing.T) {
 				assert.True(t, exists)
 			},		
 		},
+		{
+			name: "Accepts fractional sigma as a string",
+			setupRequest: func() (*http.Request, error) {
+				body := `{"sessionID": "image-sessionId", "sigma": "1.5"}`
+				return httptest.NewRequest("POST", "/blur", bytes.NewBufferString(body)), nil
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+
+				var resp response.BaseResponse
+				err := json.NewDecoder(rec.Body).Decode(&resp)
+				require.NoError(t, err)
+
+				data, ok := resp.Data.(map[string]interface{})
+				require.True(t, ok)
+				assert.Equal(t, 1.5, data["sigma"])
+			},
+		},
+		{
+			name: "Rejects out of range sigma with field errors",
+			setupRequest: func() (*http.Request, error) {
+				body := `{"sessionID": "image-sessionId", "sigma": -2}`
+				return httptest.NewRequest("POST", "/blur", bytes.NewBufferString(body)), nil
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+				var resp struct {
+					Success bool `json:"success"`
+					Err []map[string]string `json:"error"`
+				}
+				err := json.NewDecoder(rec.Body).Decode(&resp)
+				require.NoError(t, err)
+
+				assert.False(t, resp.Success)
+				require.Len(t, resp.Err, 1)
+				assert.Equal(t, "sigma", resp.Err[0]["field"])
+			},
+		},
+		{
+			name: "Rejects non numeric sigma",
+			setupRequest: func() (*http.Request, error) {
+				body := `{"sessionID": "image-sessionId", "sigma": "abc"}`
+				return httptest.NewRequest("POST", "/blur", bytes.NewBufferString(body)), nil
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -256,12 +694,41 @@ func TestImageHandler_BlurImage(t *testing.T) {
 	}
 }
 
+func TestImageHandler_MultiImageSession(t *testing.T) {
+	tempDir := t.TempDir()
+	first, second := filepath.Join(tempDir, "first.jpg"), filepath.Join(tempDir, "second.jpg")
+	require.NoError(t, os.WriteFile(first, []byte("first"), 0600))
+	require.NoError(t, os.WriteFile(second, []byte("second"), 0600))
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "image-sessionId", interfaces.SessionData{
+		TempPath: first,
+		Images: []interfaces.SessionImage{
+			{OriginalFilename: "first.jpg", TempPath: first},
+			{OriginalFilename: "second.jpg", TempPath: second},
+		},
+	})
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler.BlurImage(rec, httptest.NewRequest("POST", "/blur", bytes.NewBufferString(`{"sessionID": "image-sessionId", "sigma": 2}`)))
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	session, _, _ := mockStore.Get(context.Background(), "image-sessionId")
+	assert.NotEqual(t, first, session.TempPath)
+	assert.NoFileExists(t, first)
+	// the image list follows the replaced file, the other image is untouched
+	assert.Equal(t, session.TempPath, session.Images[0].TempPath)
+	assert.Equal(t, second, session.Images[1].TempPath)
+	assert.FileExists(t, second)
+}
+
 func TestImageHandler_SharpenImage(t *testing.T) {
 	mockStore := newMockSessionStore()
 	respHelper := response.NewResponse()
 	mockImaging := newMockImaging()
 
-	handler := NewImageHandler(respHelper, mockStore, mockImaging)
+	handler := NewImageHandler(respHelper, mockStore, mockImaging, config.Default(), nil, nil, nil)
 
 	testcases := []struct{
 		name string
@@ -274,7 +741,7 @@ func TestImageHandler_SharpenImage(t *testing.T) {
 				// setup json body
 				sharpenImgReq := &request.SharpenImageRequest{
 					SessionID: "session-imageId",
-					Sigma: "42",
+					Sigma: 42,
 				}
 
 				jsonBytes, err := json.Marshal(sharpenImgReq)
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// UploadLUT stores a .cube file under the name in the path, replacing any
+// LUT of that name. The file is sent as the raw body or as the "file" part
+// of a multipart form.
+func (i *ImageHandler) UploadLUT(w http.ResponseWriter, r *http.Request) {
+	i.uploadAsset(w, r, transform.LUTAssets, func(data []byte) (map[string]interface{}, error) {
+		lut, err := imaging.ParseCube(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"title": lut.Title,
+			"dimensions": lut.Dimensions,
+			"size": lut.Size,
+		}, nil
+	})
+}
+
+func (i *ImageHandler) ListLUTs(w http.ResponseWriter, r *http.Request) {
+	i.listAssets(w, r, transform.LUTAssets)
+}
+
+func (i *ImageHandler) DeleteLUT(w http.ResponseWriter, r *http.Request) {
+	i.deleteAsset(w, r, transform.LUTAssets)
+}
+
+// ApplyLUT grades a session image with an uploaded LUT.
+func (i *ImageHandler) ApplyLUT(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.CubeLUTRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts := imaging.CubeLUTOptions{Interpolation: imaging.LUTTrilinear, Intensity: 1}
+	if req.Interpolation != "" {
+		opts.Interpolation, _ = imaging.LUTInterpolationFromName(req.Interpolation)
+	}
+	if req.Intensity != nil {
+		opts.Intensity = req.Intensity.Float64()
+	}
+	op := transform.CubeLUT(req.LUT, opts)
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to apply LUT", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const invertCube = "TITLE \"invert\"\nLUT_1D_SIZE 2\n1 1 1\n0 0 0\n"
+
+func newLUTHandler(t *testing.T) *ImageHandler {
+	assetStore, err := assets.New(t.TempDir())
+	require.NoError(t, err)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+		ContentHash: "source-hash",
+	})
+
+	return NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, assetStore)
+}
+
+func uploadLUT(handler *ImageHandler, name, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PUT", "/api/v1/luts/"+name, bytes.NewBufferString(body))
+	req.SetPathValue("name", name)
+	rec := httptest.NewRecorder()
+	handler.UploadLUT(rec, req)
+	return rec
+}
+
+func TestImageHandler_UploadLUT(t *testing.T) {
+	handler := newLUTHandler(t)
+
+	testcases := []struct{
+		name string
+		lutName string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Valid 1D LUT",
+			lutName: "invert",
+			body: invertCube,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Not a cube file",
+			lutName: "broken",
+			body: "hello",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Invalid name",
+			lutName: "Not..Valid",
+			body: invertCube,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := uploadLUT(handler, tc.lutName, tc.body)
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+
+	// multipart forms are accepted too
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "warm.cube")
+	require.NoError(t, err)
+	part.Write([]byte(invertCube))
+	form.Close()
+
+	req := httptest.NewRequest("PUT", "/api/v1/luts/warm", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.SetPathValue("name", "warm")
+	rec := httptest.NewRecorder()
+	handler.UploadLUT(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ListLUTs(rec, httptest.NewRequest("GET", "/api/v1/luts", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"invert"`)
+	assert.Contains(t, rec.Body.String(), `"warm"`)
+
+	req = httptest.NewRequest("DELETE", "/api/v1/luts/warm", nil)
+	req.SetPathValue("name", "warm")
+	rec = httptest.NewRecorder()
+	handler.DeleteLUT(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.DeleteLUT(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestImageHandler_ApplyLUT(t *testing.T) {
+	handler := newLUTHandler(t)
+	require.Equal(t, http.StatusCreated, uploadLUT(handler, "invert", invertCube).Code)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Tetrahedral at half intensity",
+			body: `{"sessionID": "session-imageId", "lut": "invert", "interpolation": "tetrahedral", "intensity": 0.5}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Unknown LUT",
+			body: `{"sessionID": "session-imageId", "lut": "missing"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Intensity out of range",
+			body: `{"sessionID": "session-imageId", "lut": "invert", "intensity": 2}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown interpolation",
+			body: `{"sessionID": "session-imageId", "lut": "invert", "interpolation": "cubic"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/lut", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.ApplyLUT(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestImageHandler_TransformImageLUTVersion(t *testing.T) {
+	handler := newLUTHandler(t)
+	require.Equal(t, http.StatusCreated, uploadLUT(handler, "grade", invertCube).Code)
+
+	transformImage := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/img/session-imageId/lut_grade", nil)
+		req.SetPathValue("sessionId", "session-imageId")
+		req.SetPathValue("ops", "lut_grade")
+		rec := httptest.NewRecorder()
+		handler.TransformImage(rec, req)
+		return rec
+	}
+
+	rec := transformImage()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	etag := rec.Header().Get("ETag")
+
+	// replacing the LUT changes the result, so the ETag must change too
+	require.Equal(t, http.StatusCreated, uploadLUT(handler, "grade", "LUT_1D_SIZE 2\n0 0 0\n1 1 1\n").Code)
+	rec = transformImage()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	req := httptest.NewRequest("GET", "/img/session-imageId/lut_missing", nil)
+	req.SetPathValue("sessionId", "session-imageId")
+	req.SetPathValue("ops", "lut_missing")
+	rec = httptest.NewRecorder()
+	handler.TransformImage(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bytes"
+	"image"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/upload"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// UploadMask stores an image under the name in the path for image masks to
+// refer to, replacing any mask of that name. Its brightness sets the
+// strength of the mask; colour images are converted to grayscale.
+func (i *ImageHandler) UploadMask(w http.ResponseWriter, r *http.Request) {
+	i.uploadAsset(w, r, transform.MaskAssets, func(data []byte) (map[string]interface{}, error) {
+		img, err := upload.Validate(data, "", i.config.ImageLimits())
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"format": imaging.FormatName(img.Format),
+			"width": cfg.Width,
+			"height": cfg.Height,
+		}, nil
+	})
+}
+
+func (i *ImageHandler) ListMasks(w http.ResponseWriter, r *http.Request) {
+	i.listAssets(w, r, transform.MaskAssets)
+}
+
+func (i *ImageHandler) DeleteMask(w http.ResponseWriter, r *http.Request) {
+	i.deleteAsset(w, r, transform.MaskAssets)
+}
+
+// MaskImage applies a chain of operations to the area of a session image
+// selected by a mask, blending the result with the original by the
+// strength of the mask.
+func (i *ImageHandler) MaskImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.MaskRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	op, err := maskOperation(req)
+	if err != nil {
+		logger.Info("Rejected mask parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to apply masked operations", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+// maskOperation builds the masked operation for a request, reporting missing
+// mask parameters and invalid operations as field errors.
+func maskOperation(req request.MaskRequest) (transform.Operation, error) {
+	var errs validation.Errors
+
+	chain, err := transform.Parse(req.Operations)
+	switch {
+	case err != nil:
+		errs = append(errs, validation.FieldError{Field: "operations", Message: err.Error()})
+	case chain.Format != nil:
+		errs = append(errs, validation.FieldError{Field: "operations", Message: "cannot change the format"})
+	}
+
+	params := req.Mask
+	kind, _ := imaging.MaskKindFromName(params.Type)
+	opts := imaging.MaskOptions{Kind: kind, Invert: params.Invert}
+
+	required := func(field string, present bool) {
+		if !present {
+			errs = append(errs, validation.FieldError{Field: "mask." + field, Message: "is required for " + params.Type + " masks"})
+		}
+	}
+	switch kind {
+	case imaging.MaskRect, imaging.MaskEllipse:
+		required("rect", params.Rect != nil)
+		if params.Rect != nil {
+			opts.Rect = rectangle(*params.Rect)
+		}
+		if params.Feather != nil {
+			opts.Feather = params.Feather.Float64()
+		}
+	case imaging.MaskRadial:
+		required("center", params.Center != nil)
+		required("radius", params.Radius != nil)
+		if params.Center != nil && params.Radius != nil {
+			opts.Center = imaging.Point{X: params.Center.X.Float64(), Y: params.Center.Y.Float64()}
+			opts.Radius = params.Radius.Float64()
+		}
+	case imaging.MaskLinear:
+		required("from", params.From != nil)
+		required("to", params.To != nil)
+		if params.From != nil && params.To != nil {
+			opts.From = imaging.Point{X: params.From.X.Float64(), Y: params.From.Y.Float64()}
+			opts.To = imaging.Point{X: params.To.X.Float64(), Y: params.To.Y.Float64()}
+		}
+	case imaging.MaskImage:
+		required("name", params.Name != "")
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	if kind == imaging.MaskImage {
+		return transform.ImageMask(params.Name, params.Invert, chain.Operations...), nil
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, validation.Errors{{Field: "mask", Message: err.Error()}}
+	}
+
+	return transform.Mask(opts, chain.Operations...), nil
+}
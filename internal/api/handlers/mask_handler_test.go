@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_MaskImage(t *testing.T) {
+	assetStore, err := assets.New(t.TempDir())
+	require.NoError(t, err)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, assetStore)
+
+	var mask bytes.Buffer
+	require.NoError(t, png.Encode(&mask, image.NewGray(image.Rect(0, 0, 16, 16))))
+
+	req := httptest.NewRequest("PUT", "/api/v1/masks/face", bytes.NewReader(mask.Bytes()))
+	req.SetPathValue("name", "face")
+	rec := httptest.NewRecorder()
+	handler.UploadMask(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+		wantOperation string
+	}{
+		{
+			name: "Feathered ellipse",
+			body: `{"sessionID": "session-imageId", "operations": "blur_8/sharpen_1", "mask": {"type": "ellipse", "rect": {"x": 100, "y": 50, "width": 200, "height": 200}, "feather": 40}}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "mask_ellipse,r_100:50:200:200,feather_40/blur_8/sharpen_1/unmask",
+		},
+		{
+			name: "Inverted radial gradient",
+			body: `{"sessionID": "session-imageId", "operations": "blur_4", "mask": {"type": "radial", "center": {"x": 50, "y": 50}, "radius": 80, "invert": true}}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "mask_radial,at_50:50,radius_80,invert/blur_4/unmask",
+		},
+		{
+			name: "Uploaded mask",
+			body: `{"sessionID": "session-imageId", "operations": "levels,g_1.5", "mask": {"type": "image", "name": "face"}}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "mask_image,src_face/levels_rgb,g_1.5/unmask",
+		},
+		{
+			name: "Unknown mask image",
+			body: `{"sessionID": "session-imageId", "operations": "blur_4", "mask": {"type": "image", "name": "missing"}}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Linear gradient without an end",
+			body: `{"sessionID": "session-imageId", "operations": "blur_4", "mask": {"type": "linear", "from": {"x": 0, "y": 0}}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Invalid operations",
+			body: `{"sessionID": "session-imageId", "operations": "blur_4/explode", "mask": {"type": "rect", "rect": {"x": 0, "y": 0, "width": 5, "height": 5}}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Format change",
+			body: `{"sessionID": "session-imageId", "operations": "blur_4/format_png", "mask": {"type": "rect", "rect": {"x": 0, "y": 0, "width": 5, "height": 5}}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Resize within the mask",
+			body: `{"sessionID": "session-imageId", "operations": "w_10,h_10,stretch", "mask": {"type": "rect", "rect": {"x": 0, "y": 0, "width": 5, "height": 5}}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/mask", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.MaskImage(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantOperation == "" {
+				return
+			}
+
+			var resp struct{
+				Data map[string]interface{} `json:"message"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tc.wantOperation, resp.Data["operation"])
+		})
+	}
+}
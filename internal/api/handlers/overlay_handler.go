@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/upload"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// UploadWatermark stores an image under the name in the path for overlays
+// to refer to, replacing any watermark of that name.
+func (i *ImageHandler) UploadWatermark(w http.ResponseWriter, r *http.Request) {
+	i.uploadAsset(w, r, transform.WatermarkAssets, func(data []byte) (map[string]interface{}, error) {
+		img, err := upload.Validate(data, "", i.config.ImageLimits())
+		if err != nil {
+			return nil, err
+		}
+
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"format": imaging.FormatName(img.Format),
+			"width": cfg.Width,
+			"height": cfg.Height,
+		}, nil
+	})
+}
+
+func (i *ImageHandler) ListWatermarks(w http.ResponseWriter, r *http.Request) {
+	i.listAssets(w, r, transform.WatermarkAssets)
+}
+
+func (i *ImageHandler) DeleteWatermark(w http.ResponseWriter, r *http.Request) {
+	i.deleteAsset(w, r, transform.WatermarkAssets)
+}
+
+// OverlayImage composites another session's image or an uploaded watermark
+// onto a session image.
+func (i *ImageHandler) OverlayImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.OverlayRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	if (req.OverlaySessionID == "") == (req.Watermark == "") {
+		err := validation.Errors{{Field: "watermark", Message: "exactly one of overlaySessionID and watermark is required"}}
+		logger.Info("Rejected overlay parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	op, err := i.overlayOperation(r.Context(), req)
+	if err != nil {
+		logger.Error("Failed to load overlay image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to overlay image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+// overlayOperation builds the operation for a request, loading the overlay
+// session's image when one is given.
+func (i *ImageHandler) overlayOperation(ctx context.Context, req request.OverlayRequest) (transform.Operation, error) {
+	opts := imaging.OverlayOptions{Opacity: 1}
+	if req.Anchor != "" {
+		opts.Anchor, _ = imaging.AnchorFromName(req.Anchor)
+	}
+	if req.Tile != "" {
+		opts.Tile, _ = imaging.TileModeFromName(req.Tile)
+	}
+	if req.Blend != "" {
+		opts.Blend, _ = imaging.BlendModeFromName(req.Blend)
+	}
+	if req.OffsetX != nil {
+		opts.OffsetX = *req.OffsetX
+	}
+	if req.OffsetY != nil {
+		opts.OffsetY = *req.OffsetY
+	}
+	if req.Spacing != nil {
+		opts.Spacing = *req.Spacing
+	}
+	if req.Scale != nil {
+		opts.Scale = req.Scale.Float64()
+	}
+	if req.Opacity != nil {
+		opts.Opacity = req.Opacity.Float64()
+	}
+
+	if req.Watermark != "" {
+		return transform.Overlay(req.Watermark, opts), nil
+	}
+
+	overlaySession, err := i.loadSession(ctx, req.OverlaySessionID)
+	if err != nil {
+		return nil, fmt.Errorf("overlay session: %w", err)
+	}
+
+	img, err := i.imaging.Open(overlaySession.TempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open overlay image: %w", err)
+	}
+
+	version := overlaySession.ContentHash
+	if version == "" {
+		version = hashFile(overlaySession.TempPath)
+	}
+
+	return transform.OverlayImage(img, version, opts), nil
+}
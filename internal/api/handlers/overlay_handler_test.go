@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_OverlayImage(t *testing.T) {
+	assetStore, err := assets.New(t.TempDir())
+	require.NoError(t, err)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+	mockStore.Set(context.Background(), "session-logoId", interfaces.SessionData{
+		TempPath: "/path/to/logo.png",
+		ContentHash: "logo-hash",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, assetStore)
+
+	var logo bytes.Buffer
+	require.NoError(t, png.Encode(&logo, image.NewNRGBA(image.Rect(0, 0, 8, 4))))
+
+	upload := func(body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/v1/watermarks/logo", bytes.NewReader(body))
+		req.SetPathValue("name", "logo")
+		rec := httptest.NewRecorder()
+		handler.UploadWatermark(rec, req)
+		return rec
+	}
+
+	rec := upload([]byte("not an image"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code, rec.Body.String())
+
+	rec = upload(logo.Bytes())
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"width":8`)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Watermark in a corner",
+			body: `{"sessionID": "session-imageId", "watermark": "logo", "anchor": "bottom-right", "offsetX": 10, "offsetY": 10, "scale": 0.2, "opacity": 0.5}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Tiled session image",
+			body: `{"sessionID": "session-imageId", "overlaySessionID": "session-logoId", "tile": "stagger", "spacing": 20, "blend": "multiply"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Both sources",
+			body: `{"sessionID": "session-imageId", "watermark": "logo", "overlaySessionID": "session-logoId"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "No source",
+			body: `{"sessionID": "session-imageId"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown blend mode",
+			body: `{"sessionID": "session-imageId", "watermark": "logo", "blend": "dissolve"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown watermark",
+			body: `{"sessionID": "session-imageId", "watermark": "missing"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Unknown overlay session",
+			body: `{"sessionID": "session-imageId", "overlaySessionID": "session-missing"}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/overlay", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.OverlayImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
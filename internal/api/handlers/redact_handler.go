@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"image/color"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// Redact obscures rectangles or polygons of a session image with a solid
+// fill, pixelation or a heavy blur, leaving the rest of it untouched.
+func (i *ImageHandler) Redact(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.RedactRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts, err := redactOptions(req)
+	if err != nil {
+		logger.Info("Rejected redaction parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+	op := transform.Redact(opts)
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to redact image", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+			"regions": len(opts.Regions),
+		},
+		Err: nil,
+	})
+}
+
+func redactOptions(req request.RedactRequest) (imaging.RedactOptions, error) {
+	var errs validation.Errors
+
+	mode, _ := imaging.RedactModeFromName(req.Mode)
+	opts := imaging.RedactOptions{
+		Regions: regions(&errs, "regions", req.Regions),
+		Mode: mode,
+		Color: colorField(&errs, "color", req.Color, color.NRGBA{A: 255}),
+	}
+	if req.BlockSize != nil {
+		opts.BlockSize = *req.BlockSize
+	}
+	if req.Sigma != nil {
+		opts.Sigma = req.Sigma.Float64()
+	}
+
+	if req.Color != "" && mode != imaging.RedactFill {
+		errs = append(errs, validation.FieldError{Field: "color", Message: "only applies to fill"})
+	}
+	if req.BlockSize != nil && mode != imaging.RedactPixelate {
+		errs = append(errs, validation.FieldError{Field: "blockSize", Message: "only applies to pixelate"})
+	}
+	if req.Sigma != nil && mode != imaging.RedactBlur {
+		errs = append(errs, validation.FieldError{Field: "sigma", Message: "only applies to blur"})
+	}
+
+	if len(errs) > 0 {
+		return opts, errs
+	}
+
+	return opts, nil
+}
+
+// regions converts request regions, recording an error for any that does
+// not give exactly one of a rectangle or a polygon.
+func regions(errs *validation.Errors, field string, reqs []request.Region) []imaging.Region {
+	result := make([]imaging.Region, 0, len(reqs))
+	for idx, req := range reqs {
+		name := fmt.Sprintf("%s[%d]", field, idx)
+
+		var region imaging.Region
+		if req.Rect != nil {
+			region.Rect = rectangle(*req.Rect)
+		}
+		for _, p := range req.Points {
+			region.Polygon = append(region.Polygon, imaging.Point{X: p.X.Float64(), Y: p.Y.Float64()})
+		}
+
+		if (req.Rect == nil) == (len(req.Points) == 0) {
+			*errs = append(*errs, validation.FieldError{Field: name, Message: "must give exactly one of rect and points"})
+		} else if err := region.Validate(); err != nil {
+			*errs = append(*errs, validation.FieldError{Field: name, Message: err.Error()})
+		}
+		result = append(result, region)
+	}
+
+	return result
+}
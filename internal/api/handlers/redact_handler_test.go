@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_Redact(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+		wantOperation string
+	}{
+		{
+			name: "Pixelate a rectangle and a polygon",
+			body: `{"sessionID": "session-imageId", "mode": "pixelate", "blockSize": 12, "regions": [{"rect": {"x": 10, "y": 10, "width": 200, "height": 40}}, {"points": [{"x": 0, "y": 0}, {"x": 40, "y": 0}, {"x": 20, "y": 30}]}]}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "redact_pixelate,r_10:10:200:40,block_12/redact_pixelate,p_0:0:40:0:20:30,block_12",
+		},
+		{
+			name: "Black box",
+			body: `{"sessionID": "session-imageId", "mode": "fill", "regions": [{"rect": {"x": 0, "y": 0, "width": 5, "height": 5}}]}`,
+			wantStatus: http.StatusCreated,
+			wantOperation: "redact_fill,r_0:0:5:5",
+		},
+		{
+			name: "Region with a rectangle and points",
+			body: `{"sessionID": "session-imageId", "mode": "blur", "regions": [{"rect": {"x": 0, "y": 0, "width": 5, "height": 5}, "points": [{"x": 0, "y": 0}, {"x": 1, "y": 0}, {"x": 1, "y": 1}]}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Polygon with two points",
+			body: `{"sessionID": "session-imageId", "mode": "blur", "regions": [{"points": [{"x": 0, "y": 0}, {"x": 1, "y": 1}]}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Option of another mode",
+			body: `{"sessionID": "session-imageId", "mode": "blur", "blockSize": 8, "regions": [{"rect": {"x": 0, "y": 0, "width": 5, "height": 5}}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown mode",
+			body: `{"sessionID": "session-imageId", "mode": "erase", "regions": [{"rect": {"x": 0, "y": 0, "width": 5, "height": 5}}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown session",
+			body: `{"sessionID": "missing", "mode": "fill", "regions": [{"rect": {"x": 0, "y": 0, "width": 5, "height": 5}}]}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/redact", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.Redact(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantOperation == "" {
+				return
+			}
+
+			var resp struct{
+				Data map[string]interface{} `json:"message"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, tc.wantOperation, resp.Data["operation"])
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"go.uber.org/zap"
+)
+
+// decodeRequest decodes a JSON body into req and validates it, writing a 400
+// for malformed bodies and a 422 listing the invalid fields otherwise.
+func (i *ImageHandler) decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	logger := logger.LoggerFromContext(r.Context())
+
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		logger.Error("Failed to decode request body", zap.Error(err))
+		i.response.WriteError(w, "Failed to decode request body", http.StatusBadRequest)
+		return false
+	}
+
+	if err := validation.Struct(req); err != nil {
+		logger.Info("Rejected request parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	errSessionNotFound = errors.New("session not found")
+	errSessionNotReady = errors.New("upload is not complete")
+)
+
+// loadSession fetches a session whose image is ready to be processed.
+func (i *ImageHandler) loadSession(ctx context.Context, sessionID string) (interfaces.SessionData, error) {
+	session, exists, err := i.sessionStore.Get(ctx, sessionID)
+	if err != nil {
+		return session, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !exists {
+		return session, errSessionNotFound
+	}
+	if !session.Ready() {
+		return session, errSessionNotReady
+	}
+
+	return session, nil
+}
+
+// applyToSession runs chain over the session's current image and makes the
+// result the session's new image. Results are served from the cache when the
+// same chain was applied to the same content before.
+func (i *ImageHandler) applyToSession(ctx context.Context, sessionID string, chain *transform.Chain) (interfaces.SessionData, error) {
+	logger := logger.LoggerFromContext(ctx)
+
+	session, err := i.loadSession(ctx, sessionID)
+	if err != nil {
+		return session, err
+	}
+
+	if err := i.resolveChain(chain); err != nil {
+		return session, err
+	}
+
+	// the image changes format when the chain needs one the current format
+	// cannot store, such as adding transparency to a JPEG
+	ext := filepath.Ext(session.TempPath)
+	key := ""
+	if source, err := imaging.FormatFromPath(session.TempPath); err == nil {
+		format := chain.OutputFormat(source)
+		if format != source {
+			ext = imaging.Extension(format)
+		}
+		key = i.cacheKey(session, chain, format)
+	}
+
+	tempPath := filepath.Join(os.TempDir(), uuid.NewString()+ext)
+
+	if data, ok := i.cacheGet(key); ok {
+		logger.Info("Serving operation from cache", zap.String("ops", chain.String()))
+		if err := os.WriteFile(tempPath, data, 0600); err != nil {
+			return session, fmt.Errorf("failed to save image: %w", err)
+		}
+	} else {
+		img, err := i.imaging.Open(session.TempPath)
+		if err != nil {
+			return session, fmt.Errorf("failed to open image: %w", err)
+		}
+
+		result, err := chain.Apply(i.imaging, img)
+		if err != nil {
+			return session, fmt.Errorf("failed to apply %s: %w", chain.String(), err)
+		}
+
+		if err := i.imaging.Save(result, tempPath); err != nil {
+			return session, fmt.Errorf("failed to save image: %w", err)
+		}
+
+		if key != "" {
+			if data, err := os.ReadFile(tempPath); err == nil {
+				i.cache.Put(key, data)
+			}
+		}
+	}
+
+	return i.commitSessionImage(ctx, sessionID, session, tempPath)
+}
+
+// commitSessionImage points the session, and the entry of its image list
+// holding the same file, at a newly written image and removes the one it
+// replaces.
+func (i *ImageHandler) commitSessionImage(ctx context.Context, sessionID string, session interfaces.SessionData, tempPath string) (interfaces.SessionData, error) {
+	oldTempPath := session.TempPath
+	session.TempPath = tempPath
+	if len(session.Images) > 0 {
+		images := make([]interfaces.SessionImage, len(session.Images))
+		copy(images, session.Images)
+		for idx := range images {
+			if images[idx].TempPath == oldTempPath {
+				images[idx].TempPath = tempPath
+			}
+		}
+		session.Images = images
+	}
+	session.ContentHash = hashFile(tempPath)
+	session.ModifiedTime = time.Now()
+
+	if err := i.sessionStore.Set(ctx, sessionID, session); err != nil {
+		os.Remove(tempPath)
+		return session, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	// clean up
+	if oldTempPath != "" && oldTempPath != tempPath {
+		os.Remove(oldTempPath)
+	}
+
+	return session, nil
+}
+
+// renderChain runs chain over the session's current image and returns the
+// encoded result without modifying the session. The chain must already be
+// resolved.
+func (i *ImageHandler) renderChain(ctx context.Context, session interfaces.SessionData, chain *transform.Chain, format imaging.Format) ([]byte, error) {
+	key := i.cacheKey(session, chain, format)
+	if data, ok := i.cacheGet(key); ok {
+		logger.LoggerFromContext(ctx).Info("Serving transformation from cache", zap.String("ops", chain.String()))
+		return data, nil
+	}
+
+	img, err := i.imaging.Open(session.TempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+
+	result, err := chain.Apply(i.imaging, img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %w", chain.String(), err)
+	}
+
+	var buf bytes.Buffer
+	if err := i.imaging.Encode(&buf, result, format); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	if key != "" {
+		i.cache.Put(key, buf.Bytes())
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resultKey identifies the result of chain over the session's content, or is
+// empty when the content hash is unknown.
+func resultKey(session interfaces.SessionData, chain *transform.Chain, format imaging.Format) string {
+	if session.ContentHash == "" {
+		return ""
+	}
+
+	return cache.Key(session.ContentHash, chain.Key()+"/format_"+imaging.FormatName(format))
+}
+
+// resolveChain loads the assets chain refers to from the asset store.
+func (i *ImageHandler) resolveChain(chain *transform.Chain) error {
+	// a nil store must reach Resolve as a nil interface
+	var loader transform.AssetLoader
+	if i.assets != nil {
+		loader = i.assets
+	}
+
+	return chain.Resolve(loader)
+}
+
+// cacheKey is the resultKey, or empty when caching is disabled.
+func (i *ImageHandler) cacheKey(session interfaces.SessionData, chain *transform.Chain, format imaging.Format) string {
+	if i.cache == nil {
+		return ""
+	}
+
+	return resultKey(session, chain, format)
+}
+
+func (i *ImageHandler) cacheGet(key string) ([]byte, bool) {
+	if key == "" {
+		return nil, false
+	}
+
+	return i.cache.Get(key)
+}
+
+// sessionErrorStatus maps errors from loading and processing a session image
+// to the HTTP status reported to the client.
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errSessionNotReady):
+		return http.StatusConflict
+	default:
+		return operationErrorStatus(err)
+	}
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashFile returns the content hash of the file at path, or an empty string
+// if it cannot be read.
+func hashFile(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// DownloadImage returns the current image of a session as an attachment.
+func (i *ImageHandler) DownloadImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+	sessionID := r.PathValue("sessionId")
+
+	session, err := i.loadSession(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("Failed to load session", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	source, err := imaging.FormatFromPath(session.TempPath)
+	if err != nil {
+		logger.Error("Failed to determine image format", zap.Error(err))
+		i.response.WriteError(w, "Failed to determine image format", http.StatusInternalServerError)
+		return
+	}
+
+	format, ok := i.negotiateFormat(w, r, source)
+	if !ok {
+		return
+	}
+
+	filename := session.OriginalFilename
+	if format != source {
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + imaging.Extension(format)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	if format != source {
+		i.serveConverted(w, r, session, format)
+		return
+	}
+
+	file, err := os.Open(session.TempPath)
+	if err != nil {
+		logger.Error("Failed to open image", zap.Error(err))
+		i.response.WriteError(w, "Failed to open image", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	hash := session.ContentHash
+	if hash == "" {
+		hash = hashFile(session.TempPath)
+	}
+
+	setCacheHeaders(w, imageETag(hash), lastModified(session), i.config.DownloadCacheControl)
+
+	serveImage(w, r, file, imaging.ContentType(format), lastModified(session))
+}
+
+// serveConverted serves the session image re-encoded in another format.
+func (i *ImageHandler) serveConverted(w http.ResponseWriter, r *http.Request, session interfaces.SessionData, format imaging.Format) {
+	logger := logger.LoggerFromContext(r.Context())
+	chain := transform.NewChain()
+
+	etag := ""
+	if key := resultKey(session, chain, format); key != "" {
+		etag = imageETag(key)
+	}
+	setCacheHeaders(w, etag, lastModified(session), i.config.DownloadCacheControl)
+
+	if notModified(r, etag, lastModified(session)) {
+		writeNotModified(w)
+		return
+	}
+
+	data, err := i.renderChain(r.Context(), session, chain, format)
+	if err != nil {
+		logger.Error("Failed to convert image", zap.String("format", imaging.FormatName(format)), zap.Error(err))
+		i.response.WriteError(w, err.Error(), operationErrorStatus(err))
+		return
+	}
+
+	serveImage(w, r, bytes.NewReader(data), imaging.ContentType(format), lastModified(session))
+}
+
+// CreateSignedURL mints an expiring signed URL for downloading a session's
+// image or, when ops is given, for rendering it through a transformation
+// chain.
+func (i *ImageHandler) CreateSignedURL(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+	sessionID := r.PathValue("sessionId")
+
+	if i.signer == nil {
+		i.response.WriteError(w, "URL signing is not configured", http.StatusNotImplemented)
+		return
+	}
+
+	if !i.authorizedToSign(r) {
+		i.response.WriteError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req request.SignedURLRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode request body", zap.Error(err))
+		i.response.WriteError(w, "Failed to decode request body", http.StatusBadRequest)
+		return
+	}
+
+	ttl := i.config.URLSigningDefaultTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if req.ExpiresIn < 0 || ttl > i.config.URLSigningMaxTTL {
+		i.response.WriteError(w, "expiresIn must be positive and at most "+i.config.URLSigningMaxTTL.String(), http.StatusBadRequest)
+		return
+	}
+
+	_, exists, err := i.sessionStore.Get(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("Failed to get session", zap.Error(err))
+		i.response.WriteError(w, "Failed to get session", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		i.response.WriteError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	path := "/api/v1/sessions/" + url.PathEscape(sessionID) + "/download"
+	if req.Ops != "" {
+		// sign the canonical spelling so equivalent chains share URLs
+		chain, err := transform.Parse(req.Ops)
+		if err != nil {
+			i.response.WriteError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		path = "/img/" + url.PathEscape(sessionID) + "/" + chain.String()
+	}
+
+	expiresAt := time.Now().Add(ttl)
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"url": i.signer.SignURL(path, expiresAt),
+			"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+		},
+		Err: nil,
+	})
+}
+
+// authorizedToSign checks the bearer token required to mint URLs. Without
+// it, anyone who saw a signed URL could mint new ones from the session ID
+// inside it, so no URLs are minted when the token is not configured.
+func (i *ImageHandler) authorizedToSign(r *http.Request) bool {
+	if i.config.URLSigningToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(i.config.URLSigningToken)) == 1
+}
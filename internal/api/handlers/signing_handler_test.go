@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/middleware"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_SignedDownload(t *testing.T) {
+	tempDir := t.TempDir()
+	imagePath := filepath.Join(tempDir, "image.jpg")
+	createTestImage(t, imagePath)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		OriginalFilename: "holiday.jpg",
+		TempPath: imagePath,
+	})
+
+	signer, err := signing.NewSigner([]signing.Key{{ID: "k1", Secret: []byte("secret")}}, "")
+	require.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.URLSigningToken = "minting-token"
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), cfg, signer, nil, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/sessions/{sessionId}/signed-urls", handler.CreateSignedURL)
+	mux.Handle("GET /api/v1/sessions/{sessionId}/download", middleware.RequireSignature(signer, http.HandlerFunc(handler.DownloadImage)))
+
+	mint := func(token string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(request.SignedURLRequest{ExpiresIn: 60})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/sessions/session-imageId/signed-urls", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := mint("wrong-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// without a configured token nothing can be minted
+	unguarded := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), signer, nil, nil)
+	req := httptest.NewRequest("POST", "/api/v1/sessions/session-imageId/signed-urls", bytes.NewBufferString(`{"expiresIn": 60}`))
+	req.SetPathValue("sessionId", "session-imageId")
+	rec = httptest.NewRecorder()
+	unguarded.CreateSignedURL(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = mint("minting-token")
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var resp response.BaseResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	data, ok := resp.Data.(map[string]interface{})
+	require.True(t, ok)
+	signedURL, ok := data["url"].(string)
+	require.True(t, ok)
+
+	// the signed URL serves the image
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", signedURL, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/jpeg", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), "holiday.jpg")
+
+	expected, err := os.ReadFile(imagePath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, rec.Body.Bytes())
+
+	// the bare URL does not
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/sessions/session-imageId/download", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"image"
+	"image/color"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// UploadFont stores a TrueType or OpenType font under the name in the path,
+// replacing any font of that name.
+func (i *ImageHandler) UploadFont(w http.ResponseWriter, r *http.Request) {
+	i.uploadAsset(w, r, transform.FontAssets, func(data []byte) (map[string]interface{}, error) {
+		f, err := imaging.ParseFont(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{
+			"glyphs": f.NumGlyphs(),
+		}, nil
+	})
+}
+
+func (i *ImageHandler) ListFonts(w http.ResponseWriter, r *http.Request) {
+	i.listAssets(w, r, transform.FontAssets)
+}
+
+func (i *ImageHandler) DeleteFont(w http.ResponseWriter, r *http.Request) {
+	i.deleteAsset(w, r, transform.FontAssets)
+}
+
+// DrawText renders text onto a session image.
+func (i *ImageHandler) DrawText(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.TextRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	opts, err := textOptions(req)
+	if err != nil {
+		logger.Info("Rejected text parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+	op := transform.Text(req.Font, opts)
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to draw text", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+// textOptions builds the rendering options for a request, reporting
+// invalid colours and text as field errors.
+func textOptions(req request.TextRequest) (imaging.TextOptions, error) {
+	var errs validation.Errors
+
+	opts := imaging.TextOptions{
+		Text: req.Text,
+		Size: imaging.DefaultFontSize,
+		Color: colorField(&errs, "color", req.Color, color.NRGBA{A: 255}),
+	}
+	if req.Size != nil {
+		opts.Size = req.Size.Float64()
+	}
+	if req.Align != "" {
+		opts.Align, _ = imaging.TextAlignFromName(req.Align)
+	}
+	if req.LineSpacing != nil {
+		opts.LineSpacing = req.LineSpacing.Float64()
+	}
+	if req.Box != nil {
+		opts.Box = rectangle(*req.Box)
+	}
+	if req.Anchor != "" {
+		opts.Anchor, _ = imaging.AnchorFromName(req.Anchor)
+	}
+	if req.Rotation != nil {
+		opts.Rotation = req.Rotation.Float64()
+	}
+	if req.Stroke != nil {
+		opts.StrokeWidth = req.Stroke.Width
+		opts.StrokeColor = colorField(&errs, "stroke.color", req.Stroke.Color, color.NRGBA{A: 255})
+	}
+	if req.Shadow != nil {
+		opts.ShadowOffset = image.Pt(req.Shadow.OffsetX, req.Shadow.OffsetY)
+		opts.ShadowColor = colorField(&errs, "shadow.color", req.Shadow.Color, color.NRGBA{A: 128})
+		if req.Shadow.Blur != nil {
+			opts.ShadowBlur = req.Shadow.Blur.Float64()
+		}
+	}
+
+	if len(errs) > 0 {
+		return opts, errs
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, validation.Errors{{Field: "text", Message: err.Error()}}
+	}
+
+	return opts, nil
+}
+
+// colorField parses a hex colour from a request, recording an error for
+// field when it is invalid and falling back when it is empty.
+func colorField(errs *validation.Errors, field, value string, fallback color.NRGBA) color.NRGBA {
+	if value == "" {
+		return fallback
+	}
+
+	c, err := imaging.ParseHexColor(value)
+	if err != nil {
+		*errs = append(*errs, validation.FieldError{Field: field, Message: "must be a hex colour such as #ff8000 or #ff800080"})
+	}
+
+	return c
+}
+
+func rectangle(rect request.Rect) image.Rectangle {
+	return image.Rect(rect.X, rect.Y, rect.X+rect.Width, rect.Y+rect.Height)
+}
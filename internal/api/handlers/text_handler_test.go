@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+func TestImageHandler_DrawText(t *testing.T) {
+	assetStore, err := assets.New(t.TempDir())
+	require.NoError(t, err)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, assetStore)
+
+	uploadFont := func(body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/v1/fonts/mono", bytes.NewReader(body))
+		req.SetPathValue("name", "mono")
+		rec := httptest.NewRecorder()
+		handler.UploadFont(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnprocessableEntity, uploadFont([]byte("not a font")).Code)
+	require.Equal(t, http.StatusCreated, uploadFont(gomono.TTF).Code)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Caption with the bundled font",
+			body: `{"sessionID": "session-imageId", "text": "Summer sale", "size": 48, "color": "#ffffff", "anchor": "bottom", "align": "center", "box": {"x": 10, "y": 10, "width": 300, "height": 100}, "stroke": {"width": 2, "color": "#000"}, "shadow": {"offsetX": 3, "offsetY": 3, "blur": 2}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Uploaded font and rotation",
+			body: `{"sessionID": "session-imageId", "text": "Draft", "font": "mono", "rotation": 45}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Unknown font",
+			body: `{"sessionID": "session-imageId", "text": "Draft", "font": "missing"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Invalid colour",
+			body: `{"sessionID": "session-imageId", "text": "Draft", "stroke": {"width": 2, "color": "black"}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Blank text",
+			body: `{"sessionID": "session-imageId", "text": "   "}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Empty box",
+			body: `{"sessionID": "session-imageId", "text": "Draft", "box": {"x": 0, "y": 0, "width": 0, "height": 10}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/text", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.DrawText(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
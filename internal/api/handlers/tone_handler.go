@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"github.com/dylan0804/image-processing-tool/internal/api/validation"
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+	"go.uber.org/zap"
+)
+
+// AutoToneImage applies one of the automatic tonal corrections: auto-levels,
+// histogram equalization or CLAHE.
+func (i *ImageHandler) AutoToneImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.AutoToneRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	op, err := autoToneOperation(req)
+	if err != nil {
+		logger.Info("Rejected auto tone parameters", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	session, err := i.applyToSession(r.Context(), req.SessionID, transform.NewChain(op))
+	if err != nil {
+		logger.Error("Failed to adjust image tone", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": req.SessionID,
+			"path": session.TempPath,
+			"operation": op.String(),
+		},
+		Err: nil,
+	})
+}
+
+func autoToneOperation(req request.AutoToneRequest) (transform.Operation, error) {
+	var errs validation.Errors
+	reject := func(given bool, field string) {
+		if given {
+			errs = append(errs, validation.FieldError{Field: field, Message: "does not apply to " + req.Method})
+		}
+	}
+
+	if req.Method != "autolevels" {
+		reject(req.ShadowClip != nil, "shadowClip")
+		reject(req.HighlightClip != nil, "highlightClip")
+		reject(req.PerChannel, "perChannel")
+	}
+	if req.Method != "clahe" {
+		reject(req.TileSize != nil, "tileSize")
+		reject(req.ClipLimit != nil, "clipLimit")
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	switch req.Method {
+	case "autolevels":
+		opts := imaging.AutoLevelsOptions{PerChannel: req.PerChannel}
+		if req.ShadowClip != nil {
+			opts.ShadowClip = req.ShadowClip.Float64()
+		}
+		if req.HighlightClip != nil {
+			opts.HighlightClip = req.HighlightClip.Float64()
+		}
+		if opts.ShadowClip+opts.HighlightClip >= 100 {
+			return nil, validation.Errors{{Field: "highlightClip", Message: "must add up to less than 100 with shadowClip"}}
+		}
+		return transform.AutoLevels(opts), nil
+	case "clahe":
+		opts := imaging.CLAHEOptions{}
+		if req.TileSize != nil {
+			opts.TileSize = *req.TileSize
+		}
+		if req.ClipLimit != nil {
+			opts.ClipLimit = req.ClipLimit.Float64()
+		}
+		return transform.CLAHE(opts), nil
+	default:
+		return transform.Equalize(), nil
+	}
+}
+
+func (i *ImageHandler) LevelsImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.LevelsRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	chain, err := levelsChain(req)
+	if err != nil {
+		logger.Info("Rejected levels", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	i.applyToneChain(w, r, req.SessionID, chain, "levels")
+}
+
+func (i *ImageHandler) CurvesImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	var req request.CurvesRequest
+
+	if !i.decodeRequest(w, r, &req) {
+		return
+	}
+
+	chain, err := curvesChain(req)
+	if err != nil {
+		logger.Info("Rejected curves", zap.Error(err))
+		i.response.WriteValidationError(w, err)
+		return
+	}
+
+	i.applyToneChain(w, r, req.SessionID, chain, "curves")
+}
+
+func (i *ImageHandler) applyToneChain(w http.ResponseWriter, r *http.Request, sessionID string, chain *transform.Chain, operation string) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	session, err := i.applyToSession(r.Context(), sessionID, chain)
+	if err != nil {
+		logger.Error("Failed to apply "+operation, zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	i.response.WriteSuccess(w, &response.BaseResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"sessionId": sessionID,
+			"path": session.TempPath,
+			"operation": operation,
+			"ops": chain.String(),
+		},
+		Err: nil,
+	})
+}
+
+// toneChannels lists the channels of levels and curves requests in the
+// order they are applied.
+var toneChannels = []struct {
+	field string
+	channel imaging.Channel
+}{
+	{"red", imaging.ChannelRed},
+	{"green", imaging.ChannelGreen},
+	{"blue", imaging.ChannelBlue},
+	{"rgb", imaging.ChannelRGB},
+}
+
+func levelsChain(req request.LevelsRequest) (*transform.Chain, error) {
+	params := map[string]*request.LevelsParams{"red": req.Red, "green": req.Green, "blue": req.Blue, "rgb": req.RGB}
+
+	chain := transform.NewChain()
+	var errs validation.Errors
+	for _, entry := range toneChannels {
+		p := params[entry.field]
+		if p == nil {
+			continue
+		}
+
+		levels := imaging.DefaultLevels()
+		levels.InputBlack = p.InputBlack.Float64()
+		levels.OutputBlack = p.OutputBlack.Float64()
+		if p.InputWhite != nil {
+			levels.InputWhite = p.InputWhite.Float64()
+		}
+		if p.OutputWhite != nil {
+			levels.OutputWhite = p.OutputWhite.Float64()
+		}
+		if p.Gamma != nil {
+			levels.Gamma = p.Gamma.Float64()
+		}
+
+		if err := levels.Validate(); err != nil {
+			errs = append(errs, validation.FieldError{Field: entry.field, Message: err.Error()})
+			continue
+		}
+		chain.Operations = append(chain.Operations, transform.Levels(entry.channel, levels))
+	}
+
+	return toneChainResult(chain, errs)
+}
+
+func curvesChain(req request.CurvesRequest) (*transform.Chain, error) {
+	points := map[string][]request.CurvePoint{"red": req.Red, "green": req.Green, "blue": req.Blue, "rgb": req.RGB}
+
+	chain := transform.NewChain()
+	var errs validation.Errors
+	for _, entry := range toneChannels {
+		p := points[entry.field]
+		if p == nil {
+			continue
+		}
+
+		curve := make(imaging.Curve, len(p))
+		for idx, point := range p {
+			curve[idx] = imaging.CurvePoint{In: point.In.Float64(), Out: point.Out.Float64()}
+		}
+
+		if err := curve.Validate(); err != nil {
+			errs = append(errs, validation.FieldError{Field: entry.field, Message: err.Error()})
+			continue
+		}
+		chain.Operations = append(chain.Operations, transform.Curves(entry.channel, curve))
+	}
+
+	return toneChainResult(chain, errs)
+}
+
+func toneChainResult(chain *transform.Chain, errs validation.Errors) (*transform.Chain, error) {
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if len(chain.Operations) == 0 {
+		return nil, validation.Errors{{Field: "rgb", Message: "at least one channel is required"}}
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_AutoToneImage(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		body string
+		wantStatus int
+	}{
+		{
+			name: "Auto levels with clipping",
+			body: `{"sessionID": "session-imageId", "method": "autolevels", "shadowClip": 0.5, "highlightClip": 0.5}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Clipping every pixel",
+			body: `{"sessionID": "session-imageId", "method": "autolevels", "shadowClip": 50, "highlightClip": 50}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Equalize",
+			body: `{"sessionID": "session-imageId", "method": "equalize"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "CLAHE",
+			body: `{"sessionID": "session-imageId", "method": "clahe", "tileSize": 32, "clipLimit": 3}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Parameter of another method",
+			body: `{"sessionID": "session-imageId", "method": "equalize", "tileSize": 32}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Unknown method",
+			body: `{"sessionID": "session-imageId", "method": "magic"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/autotone", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.AutoToneImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestImageHandler_LevelsAndCurves(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		handle http.HandlerFunc
+		body string
+		wantStatus int
+		wantOps string
+	}{
+		{
+			name: "Levels applies channels before rgb",
+			handle: handler.LevelsImage,
+			body: `{"sessionID": "session-imageId", "rgb": {"gamma": 1.2}, "blue": {"inputBlack": 10, "inputWhite": 240}}`,
+			wantStatus: http.StatusCreated,
+			wantOps: "levels_b,in_10:240/levels_rgb,g_1.2",
+		},
+		{
+			name: "Levels with inverted input range",
+			handle: handler.LevelsImage,
+			body: `{"sessionID": "session-imageId", "rgb": {"inputBlack": 200, "inputWhite": 100}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Levels without channels",
+			handle: handler.LevelsImage,
+			body: `{"sessionID": "session-imageId"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Curves",
+			handle: handler.CurvesImage,
+			body: `{"sessionID": "session-imageId", "rgb": [{"in": 0, "out": 0}, {"in": 128, "out": 150}, {"in": 255, "out": 255}]}`,
+			wantStatus: http.StatusCreated,
+			wantOps: "curves_rgb,p_0:0:128:150:255:255",
+		},
+		{
+			name: "Curve with a single point",
+			handle: handler.CurvesImage,
+			body: `{"sessionID": "session-imageId", "red": [{"in": 0, "out": 10}]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/image/levels", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			tc.handle(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.wantOps != "" {
+				var resp response.BaseResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+				data, ok := resp.Data.(map[string]interface{})
+				require.True(t, ok)
+				assert.Equal(t, tc.wantOps, data["ops"])
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/assets"
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/transform"
+	"go.uber.org/zap"
+)
+
+// TransformImage renders a session image through a path-encoded operation
+// chain, e.g. GET /img/{sessionId}/w_400,h_300,fit/blur_2/format_png. The
+// session itself is left untouched.
+func (i *ImageHandler) TransformImage(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+	sessionID := r.PathValue("sessionId")
+
+	chain, err := transform.Parse(r.PathValue("ops"))
+	if err != nil {
+		logger.Error("Failed to parse operations", zap.Error(err))
+		i.response.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := i.resolveChain(chain); err != nil {
+		logger.Error("Failed to resolve operations", zap.Error(err))
+		i.response.WriteError(w, err.Error(), operationErrorStatus(err))
+		return
+	}
+
+	session, err := i.loadSession(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("Failed to load session", zap.Error(err))
+		i.response.WriteError(w, err.Error(), sessionErrorStatus(err))
+		return
+	}
+
+	source, err := imaging.FormatFromPath(session.TempPath)
+	if err != nil {
+		logger.Error("Failed to determine image format", zap.Error(err))
+		i.response.WriteError(w, "Failed to determine image format", http.StatusInternalServerError)
+		return
+	}
+	format := chain.OutputFormat(source)
+	if chain.Format == nil {
+		negotiated, ok := i.negotiateFormat(w, r, format)
+		if !ok {
+			return
+		}
+		format = negotiated
+	}
+
+	// the result is fully determined by the source content and the chain, so
+	// conditional requests are answered without rendering anything
+	etag := ""
+	if key := resultKey(session, chain, format); key != "" {
+		etag = imageETag(key)
+	}
+	setCacheHeaders(w, etag, lastModified(session), i.config.TransformCacheControl)
+
+	if notModified(r, etag, lastModified(session)) {
+		writeNotModified(w)
+		return
+	}
+
+	data, err := i.renderChain(r.Context(), session, chain, format)
+	if err != nil {
+		logger.Error("Failed to transform image", zap.String("ops", chain.String()), zap.Error(err))
+		i.response.WriteError(w, err.Error(), operationErrorStatus(err))
+		return
+	}
+
+	logger.Info("Transformed image", zap.String("session_id", sessionID), zap.String("ops", chain.String()))
+
+	serveImage(w, r, bytes.NewReader(data), imaging.ContentType(format), lastModified(session))
+}
+
+// operationErrorStatus maps errors from applying an operation to the HTTP
+// status reported to the client.
+func operationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, imaging.ErrImageTooLarge), errors.Is(err, imaging.ErrInvalidCube):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, assets.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, transform.ErrInvalidChain), errors.Is(err, assets.ErrInvalidName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
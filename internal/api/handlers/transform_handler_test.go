@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/cache"
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageHandler_TransformImage(t *testing.T) {
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+	})
+
+	handler := NewImageHandler(response.NewResponse(), mockStore, newMockImaging(), config.Default(), nil, nil, nil)
+
+	testcases := []struct{
+		name string
+		sessionID string
+		ops string
+		wantStatus int
+		checkResponse func(*httptest.ResponseRecorder)
+	}{
+		{
+			name: "Applies chain and converts format",
+			sessionID: "session-imageId",
+			ops: "blur_1.5/w_1,stretch/format_png",
+			wantStatus: http.StatusOK,
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+
+				img, err := png.Decode(rec.Body)
+				require.NoError(t, err)
+				assert.Equal(t, 1, img.Bounds().Dx())
+			},
+		},
+		{
+			name: "Rejects unknown operation",
+			sessionID: "session-imageId",
+			ops: "explode_3",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Rejects out of range sigma",
+			sessionID: "session-imageId",
+			ops: "blur_-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Unknown session",
+			sessionID: "missing",
+			ops: "blur_2",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/img/"+tc.sessionID+"/"+tc.ops, nil)
+			req.SetPathValue("sessionId", tc.sessionID)
+			req.SetPathValue("ops", tc.ops)
+
+			rec := httptest.NewRecorder()
+
+			handler.TransformImage(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code, rec.Body.String())
+			if tc.checkResponse != nil {
+				tc.checkResponse(rec)
+			}
+
+			// the session is never modified
+			session, _, _ := mockStore.Get(context.Background(), "session-imageId")
+			assert.Equal(t, "/path/to/temp.jpg", session.TempPath)
+		})
+	}
+}
+
+func TestImageHandler_TransformImageCache(t *testing.T) {
+	resultCache, err := cache.New(cache.Options{MemoryBytes: 1 << 20})
+	require.NoError(t, err)
+
+	mockStore := newMockSessionStore()
+	mockStore.Set(context.Background(), "session-imageId", interfaces.SessionData{
+		TempPath: "/path/to/temp.jpg",
+		ContentHash: "source-hash",
+	})
+
+	mockImaging := newMockImaging()
+	handler := NewImageHandler(response.NewResponse(), mockStore, mockImaging, config.Default(), nil, resultCache, nil)
+
+	transformImage := func(ops string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/img/session-imageId/"+ops, nil)
+		req.SetPathValue("sessionId", "session-imageId")
+		req.SetPathValue("ops", ops)
+		rec := httptest.NewRecorder()
+		handler.TransformImage(rec, req)
+		return rec
+	}
+
+	first := transformImage("blur_2/format_png")
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// once cached, the source image is not needed again
+	mockImaging.openError = errors.New("should not be opened")
+
+	second := transformImage("blur_2.0/format_png")
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body.Bytes(), second.Body.Bytes())
+
+	// a different chain is a cache miss
+	third := transformImage("blur_3/format_png")
+	assert.Equal(t, http.StatusInternalServerError, third.Code)
+}
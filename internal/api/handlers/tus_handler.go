@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/interfaces"
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/upload"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io) with the
+// creation, expiration and termination extensions. The tus upload ID is the
+// session ID, so once the last chunk arrives the session can be used like one
+// created by UploadImage.
+
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,expiration,termination"
+	tusContentType = "application/offset+octet-stream"
+	tusBasePath    = "/api/v1/tus/"
+	// tusPartPattern matches the files of unfinished uploads, named after
+	// their session IDs.
+	tusPartPattern = "????????-????-????-????-????????????.part"
+	// tusSweepInterval spaces out the sweeps for abandoned uploads.
+	tusSweepInterval = time.Minute
+)
+
+// tusLocks serialises chunks for the same upload within this process.
+var tusLocks sync.Map
+
+func tusLock(sessionID string) func() {
+	value, _ := tusLocks.LoadOrStore(sessionID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	return mu.Unlock
+}
+
+// lastTusSweep is when abandoned upload files were last swept, in unix
+// nanoseconds.
+var lastTusSweep atomic.Int64
+
+// sweepTusParts removes upload files in dir that have not been written to
+// for longer than maxAge. Their uploads have expired, but when the session
+// disappears from redis first nothing else would ever remove them.
+func sweepTusParts(dir string, maxAge time.Duration) {
+	paths, _ := filepath.Glob(filepath.Join(dir, tusPartPattern))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > maxAge {
+			os.Remove(path)
+		}
+	}
+}
+
+func setTusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// checkTusVersion rejects requests from clients speaking another protocol
+// version, as required by the spec.
+func (i *ImageHandler) checkTusVersion(w http.ResponseWriter, r *http.Request) bool {
+	setTusHeaders(w)
+
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		i.response.WriteError(w, "Unsupported tus version", http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+func (i *ImageHandler) TusOptions(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(i.config.TusMaxSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (i *ImageHandler) TusCreateUpload(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	if !i.checkTusVersion(w, r) {
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		i.response.WriteError(w, "Upload-Length must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	if length > i.config.TusMaxSize {
+		i.response.WriteError(w, fmt.Sprintf("Upload-Length exceeds the limit of %d bytes", i.config.TusMaxSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		i.response.WriteError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if last := lastTusSweep.Load(); time.Since(time.Unix(0, last)) > tusSweepInterval && lastTusSweep.CompareAndSwap(last, time.Now().UnixNano()) {
+		sweepTusParts(os.TempDir(), i.config.TusUploadExpiry)
+	}
+
+	sessionID := uuid.New().String()
+	partPath := filepath.Join(os.TempDir(), sessionID+".part")
+
+	partFile, err := os.OpenFile(partPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Error("Failed to create upload file", zap.Error(err))
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	partFile.Close()
+
+	expiresAt := time.Now().Add(i.config.TusUploadExpiry)
+
+	err = i.sessionStore.Set(r.Context(), sessionID, interfaces.SessionData{
+		OriginalFilename: metadata["filename"],
+		UploadTime: time.Now(),
+		Upload: &interfaces.UploadState{
+			Length: length,
+			Offset: 0,
+			PartPath: partPath,
+			ExpiresAt: expiresAt,
+		},
+	})
+	if err != nil {
+		logger.Error("Failed to store metadata to redis", zap.Error(err))
+		os.Remove(partPath)
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Resumable upload created", zap.String("session_id", sessionID), zap.Int64("length", length))
+
+	w.Header().Set("Location", tusBasePath+sessionID)
+	w.Header().Set("Upload-Expires", expiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (i *ImageHandler) TusUploadStatus(w http.ResponseWriter, r *http.Request) {
+	if !i.checkTusVersion(w, r) {
+		return
+	}
+
+	session, ok := i.loadTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.Upload.Length, 10))
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.Upload.Offset, 10))
+	w.Header().Set("Upload-Expires", session.Upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (i *ImageHandler) TusPatchUpload(w http.ResponseWriter, r *http.Request) {
+	logger := logger.LoggerFromContext(r.Context())
+
+	if !i.checkTusVersion(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != tusContentType {
+		i.response.WriteError(w, "Content-Type must be "+tusContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		i.response.WriteError(w, "Upload-Offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	unlock := tusLock(r.PathValue("id"))
+	defer unlock()
+
+	session, ok := i.loadTusUpload(w, r)
+	if !ok {
+		return
+	}
+	state := session.Upload
+
+	if offset != state.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(state.Offset, 10))
+		i.response.WriteError(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+		return
+	}
+
+	partFile, err := os.OpenFile(state.PartPath, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		logger.Error("Failed to open upload file", zap.Error(err))
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	// keep whatever arrived even if the connection drops part way through,
+	// that is what lets the client resume from the new offset
+	remaining := state.Length - state.Offset
+	written, copyErr := io.Copy(partFile, io.LimitReader(r.Body, remaining))
+	partFile.Close()
+
+	state.Offset += written
+	state.ExpiresAt = time.Now().Add(i.config.TusUploadExpiry)
+
+	sessionID := r.PathValue("id")
+
+	if state.Offset == state.Length {
+		if err := i.completeTusUpload(r, sessionID, &session); err != nil {
+			logger.Error("Rejected resumable upload", zap.String("session_id", sessionID), zap.Error(err))
+			i.discardTusUpload(r, sessionID, state)
+			i.response.WriteError(w, err.Error(), uploadErrorStatus(err))
+			return
+		}
+	} else if err := i.sessionStore.Set(r.Context(), sessionID, session); err != nil {
+		logger.Error("Failed to update session", zap.Error(err))
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+
+	if copyErr != nil {
+		logger.Warn("Upload chunk interrupted", zap.String("session_id", sessionID), zap.Int64("offset", state.Offset), zap.Error(copyErr))
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(state.Offset, 10))
+	if session.Upload != nil {
+		w.Header().Set("Upload-Expires", state.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (i *ImageHandler) TusDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if !i.checkTusVersion(w, r) {
+		return
+	}
+
+	unlock := tusLock(r.PathValue("id"))
+	defer unlock()
+
+	session, ok := i.loadTusUpload(w, r)
+	if !ok {
+		return
+	}
+
+	i.discardTusUpload(r, r.PathValue("id"), session.Upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTusUpload fetches the session for an in-progress upload, writing the
+// appropriate error response when there is none.
+func (i *ImageHandler) loadTusUpload(w http.ResponseWriter, r *http.Request) (interfaces.SessionData, bool) {
+	logger := logger.LoggerFromContext(r.Context())
+	sessionID := r.PathValue("id")
+
+	session, exists, err := i.sessionStore.Get(r.Context(), sessionID)
+	if err != nil {
+		logger.Error("Failed to get session", zap.Error(err))
+		i.response.WriteError(w, "Server error", http.StatusInternalServerError)
+		return session, false
+	}
+	if !exists || session.Upload == nil {
+		i.response.WriteError(w, "Upload not found", http.StatusNotFound)
+		return session, false
+	}
+
+	if time.Now().After(session.Upload.ExpiresAt) {
+		i.discardTusUpload(r, sessionID, session.Upload)
+		i.response.WriteError(w, "Upload has expired", http.StatusGone)
+		return session, false
+	}
+
+	return session, true
+}
+
+// completeTusUpload validates the assembled file exactly like a regular
+// upload and turns the session into a usable one.
+func (i *ImageHandler) completeTusUpload(r *http.Request, sessionID string, session *interfaces.SessionData) error {
+	state := session.Upload
+
+	// the upload may be far larger than a request body, so it is validated
+	// and hashed from the file rather than read into memory
+	img, err := upload.ValidateFile(state.PartPath, session.OriginalFilename, i.config.ImageLimits())
+	if err != nil {
+		return err
+	}
+
+	tempPath := filepath.Join(os.TempDir(), sessionID+upload.Extension(img.Format))
+	if err := os.Rename(state.PartPath, tempPath); err != nil {
+		return err
+	}
+
+	session.OriginalFilename = img.Filename
+	session.TempPath = tempPath
+	session.UploadTime = time.Now()
+	session.ContentHash = hashFile(tempPath)
+	session.ModifiedTime = session.UploadTime
+	session.Upload = nil
+
+	if err := i.sessionStore.Set(r.Context(), sessionID, *session); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	tusLocks.Delete(sessionID)
+
+	logger.LoggerFromContext(r.Context()).Info("Image stored at", zap.String("temp path", tempPath))
+
+	return nil
+}
+
+func (i *ImageHandler) discardTusUpload(r *http.Request, sessionID string, state *interfaces.UploadState) {
+	os.Remove(state.PartPath)
+	i.sessionStore.Delete(r.Context(), sessionID)
+	tusLocks.Delete(sessionID)
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma-separated pairs
+// of a key and an optional base64-encoded value.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata header")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
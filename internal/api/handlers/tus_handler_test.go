@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTusRequest(method, target string, body []byte) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.SetPathValue("id", path.Base(target))
+	return req
+}
+
+func TestImageHandler_TusUpload(t *testing.T) {
+	var pngBuf bytes.Buffer
+	err := png.Encode(&pngBuf, image.NewRGBA(image.Rect(0, 0, 16, 16)))
+	require.NoError(t, err)
+	data := pngBuf.Bytes()
+
+	mockStore := newMockSessionStore()
+	handler := NewImageHandler(response.NewResponse(), mockStore, nil, config.Default(), nil, nil, nil)
+
+	// create
+	req := newTusRequest("POST", "/api/v1/tus", nil)
+	req.Header.Set("Upload-Length", strconv.Itoa(len(data)))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("scan.png")))
+	rec := httptest.NewRecorder()
+	handler.TusCreateUpload(rec, req)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	location := rec.Header().Get("Location")
+	require.NotEmpty(t, location)
+	sessionID := path.Base(location)
+
+	patch := func(offset int, chunk []byte) *httptest.ResponseRecorder {
+		req := newTusRequest("PATCH", location, chunk)
+		req.Header.Set("Content-Type", tusContentType)
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		rec := httptest.NewRecorder()
+		handler.TusPatchUpload(rec, req)
+		return rec
+	}
+
+	half := len(data) / 2
+
+	rec = patch(0, data[:half])
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, strconv.Itoa(half), rec.Header().Get("Upload-Offset"))
+
+	session, _, _ := mockStore.Get(context.Background(), sessionID)
+	assert.False(t, session.Ready())
+
+	// a chunk sent against a stale offset is refused
+	rec = patch(0, data[half:])
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// progress
+	rec = httptest.NewRecorder()
+	handler.TusUploadStatus(rec, newTusRequest("HEAD", location, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, strconv.Itoa(half), rec.Header().Get("Upload-Offset"))
+	assert.Equal(t, strconv.Itoa(len(data)), rec.Header().Get("Upload-Length"))
+
+	// final chunk makes the session usable
+	rec = patch(half, data[half:])
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	session, exists, err := mockStore.Get(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.True(t, session.Ready())
+	assert.Equal(t, "scan.png", session.OriginalFilename)
+	assert.FileExists(t, session.TempPath)
+	os.Remove(session.TempPath)
+
+	// the lock of a finished upload is released
+	_, locked := tusLocks.Load(sessionID)
+	assert.False(t, locked)
+}
+
+func TestSweepTusParts(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "3f1c2b4e-8a9d-4c1e-9b7a-2d5e6f708192.part")
+	fresh := filepath.Join(dir, "7a6b5c4d-3e2f-4a1b-8c9d-0e1f2a3b4c5d.part")
+	other := filepath.Join(dir, "notes.part")
+	for _, path := range []string{stale, fresh, other} {
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+	}
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, old, old))
+	require.NoError(t, os.Chtimes(other, old, old))
+
+	sweepTusParts(dir, 30*time.Minute)
+
+	assert.NoFileExists(t, stale)
+	assert.FileExists(t, fresh)
+	// files that are not uploads are left alone
+	assert.FileExists(t, other)
+}
+
+func TestImageHandler_TusRequiresVersion(t *testing.T) {
+	handler := NewImageHandler(response.NewResponse(), newMockSessionStore(), nil, config.Default(), nil, nil, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/tus", nil)
+	req.Header.Set("Upload-Length", "10")
+	rec := httptest.NewRecorder()
+
+	handler.TusCreateUpload(rec, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, tusVersion, rec.Header().Get("Tus-Version"))
+}
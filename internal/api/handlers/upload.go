@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/models/request"
+)
+
+var (
+	errNoImages               = errors.New("no image provided")
+	errTooManyImages          = errors.New("too many images in one request")
+	errUnsupportedContentType = errors.New("unsupported content type")
+)
+
+// maxUploadMemory is how much of a multipart upload is held in memory before
+// the rest spills to temporary files on disk.
+const maxUploadMemory = 10 << 20
+
+const (
+	// uploadModeSeparate creates one session per uploaded image.
+	uploadModeSeparate = "separate"
+	// uploadModeSingle groups every uploaded image into one session.
+	uploadModeSingle = "single"
+)
+
+type uploadedFile struct {
+	filename string
+	data     []byte
+}
+
+// readUploads extracts the uploaded images from a request body, which may be
+// a multipart form with one or more "image" fields, a JSON document carrying
+// base64 data, or the raw image bytes.
+func (i *ImageHandler) readUploads(r *http.Request) ([]uploadedFile, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnsupportedContentType, err)
+	}
+
+	var files []uploadedFile
+
+	switch {
+	case mediaType == "multipart/form-data":
+		files, err = readMultipartUploads(r)
+	case mediaType == "application/json":
+		files, err = readJSONUploads(r)
+	case strings.HasPrefix(mediaType, "image/"), mediaType == "application/octet-stream":
+		files, err = readRawUpload(r)
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedContentType, mediaType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errNoImages
+	}
+	if int64(len(files)) > i.config.MaxUploadFiles {
+		return nil, fmt.Errorf("%w: got %d, limit is %d", errTooManyImages, len(files), i.config.MaxUploadFiles)
+	}
+
+	return files, nil
+}
+
+func readMultipartUploads(r *http.Request) ([]uploadedFile, error) {
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, err
+	}
+
+	var files []uploadedFile
+	for _, header := range r.MultipartForm.File["image"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, uploadedFile{
+			filename: header.Filename,
+			data:     data,
+		})
+	}
+
+	return files, nil
+}
+
+func readJSONUploads(r *http.Request) ([]uploadedFile, error) {
+	var req request.UploadImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	payloads := req.Images
+	if req.Data != "" {
+		payloads = append([]request.UploadImageData{req.UploadImageData}, payloads...)
+	}
+
+	files := make([]uploadedFile, 0, len(payloads))
+	for idx, payload := range payloads {
+		data, err := decodeBase64Image(payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", idx, err)
+		}
+
+		files = append(files, uploadedFile{
+			filename: payload.Filename,
+			data:     data,
+		})
+	}
+
+	return files, nil
+}
+
+func readRawUpload(r *http.Request) ([]uploadedFile, error) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	filename := r.Header.Get("X-Filename")
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = params["filename"]
+	}
+
+	return []uploadedFile{{filename: filename, data: data}}, nil
+}
+
+// decodeBase64Image accepts either a data URI or bare base64, padded or not.
+// The media type declared in a data URI is ignored; the bytes are sniffed
+// during validation like any other upload.
+func decodeBase64Image(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		header, payload, ok := strings.Cut(value, ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("data URI must be base64 encoded")
+		}
+		value = payload
+	}
+
+	value = strings.TrimRight(strings.TrimSpace(value), "=")
+
+	return base64.RawStdEncoding.DecodeString(value)
+}
@@ -0,0 +1,199 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// FillMode selects what fills the area added around an image.
+type FillMode int
+
+const (
+	// FillColor fills with a solid colour.
+	FillColor FillMode = iota
+	// FillBlur fills with a blurred copy of the image stretched to the
+	// whole canvas.
+	FillBlur
+	// FillTransparent leaves the area transparent.
+	FillTransparent
+)
+
+var fillModes = map[string]FillMode{
+	"color":       FillColor,
+	"blur":        FillBlur,
+	"transparent": FillTransparent,
+}
+
+// FillModeFromName looks up a canvas fill by name.
+func FillModeFromName(name string) (FillMode, error) {
+	mode, ok := fillModes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown fill %q", name)
+	}
+
+	return mode, nil
+}
+
+func FillModeName(mode FillMode) string {
+	for name, m := range fillModes {
+		if m == mode {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Defaults and limits for canvas operations.
+const (
+	DefaultCanvasBlur = 20
+	MaxCanvasBlur     = 100
+	MaxCanvasInset    = 10000
+	MaxAspectRatio    = 100
+)
+
+type CanvasFill struct {
+	Mode FillMode
+	// Color fills the canvas in FillColor mode.
+	Color color.NRGBA
+	// Sigma is the blur radius in FillBlur mode, 20 when it is 0.
+	Sigma float64
+}
+
+// Transparent reports whether the fill leaves any transparency, which only
+// some formats can store.
+func (f CanvasFill) Transparent() bool {
+	return f.Mode == FillTransparent || (f.Mode == FillColor && f.Color.A < 255)
+}
+
+// Insets are the widths in pixels added to each side of an image.
+type Insets struct {
+	Top, Right, Bottom, Left int
+}
+
+// UniformInsets returns insets of the same width on every side.
+func UniformInsets(width int) Insets {
+	return Insets{Top: width, Right: width, Bottom: width, Left: width}
+}
+
+// PadOptions describe the canvas an image is padded to. Width and Height
+// set its size, or AspectWidth and AspectHeight its aspect ratio, such as
+// 16 and 9. Sizes smaller than the image are raised to it, so padding never
+// crops.
+type PadOptions struct {
+	Width        int
+	Height       int
+	AspectWidth  float64
+	AspectHeight float64
+	Anchor       Anchor
+}
+
+func (o PadOptions) Validate() error {
+	ratio := o.AspectWidth > 0 || o.AspectHeight > 0
+	switch {
+	case o.Width < 0 || o.Height < 0:
+		return fmt.Errorf("imaging: canvas size must be positive")
+	case ratio && (o.Width > 0 || o.Height > 0):
+		return fmt.Errorf("imaging: pad to a size or an aspect ratio, not both")
+	case !ratio && o.Width == 0 && o.Height == 0:
+		return fmt.Errorf("imaging: pad needs a size or an aspect ratio")
+	}
+
+	if ratio {
+		aspect := o.AspectWidth / o.AspectHeight
+		if !(aspect >= 1.0/MaxAspectRatio && aspect <= MaxAspectRatio) {
+			return fmt.Errorf("imaging: aspect ratio must be positive and at most %d:1 either way", MaxAspectRatio)
+		}
+	}
+
+	return nil
+}
+
+// PadInsets returns the insets that grow an image of the given size to the
+// canvas described by opts, placing it inside by the anchor.
+func PadInsets(size image.Point, opts PadOptions) (Insets, error) {
+	if err := opts.Validate(); err != nil {
+		return Insets{}, err
+	}
+
+	canvas := size
+	if opts.AspectWidth > 0 {
+		// grow whichever side is short of the ratio
+		aspect := opts.AspectWidth / opts.AspectHeight
+		if float64(size.X) < float64(size.Y)*aspect {
+			canvas.X = int(math.Round(float64(size.Y) * aspect))
+		} else {
+			canvas.Y = int(math.Round(float64(size.X) / aspect))
+		}
+	}
+	canvas.X = max(canvas.X, opts.Width)
+	canvas.Y = max(canvas.Y, opts.Height)
+
+	pos := opts.Anchor.Position(image.Rectangle{Max: canvas}, size, 0, 0)
+
+	return Insets{
+		Top:    pos.Y,
+		Right:  canvas.X - size.X - pos.X,
+		Bottom: canvas.Y - size.Y - pos.Y,
+		Left:   pos.X,
+	}, nil
+}
+
+// ExtendCanvas adds insets around img and fills the new area. Insets wider
+// than MaxCanvasInset fail with ErrImageTooLarge, like canvases past the
+// limits, since padding to a valid size or ratio can produce them.
+func (i *ImagingImpl) ExtendCanvas(img image.Image, insets Insets, fill CanvasFill) (*image.NRGBA, error) {
+	for _, inset := range []int{insets.Top, insets.Right, insets.Bottom, insets.Left} {
+		if inset < 0 {
+			return nil, fmt.Errorf("imaging: insets must not be negative")
+		}
+		if inset > MaxCanvasInset {
+			return nil, fmt.Errorf("%w: insets must be at most %d", ErrImageTooLarge, MaxCanvasInset)
+		}
+	}
+	if !(fill.Sigma >= 0 && fill.Sigma <= MaxCanvasBlur) {
+		return nil, fmt.Errorf("imaging: fill blur must be between 0 and %d", MaxCanvasBlur)
+	}
+
+	bounds := img.Bounds()
+	width := bounds.Dx() + insets.Left + insets.Right
+	height := bounds.Dy() + insets.Top + insets.Bottom
+	if err := i.limits.Check(width, height); err != nil {
+		return nil, err
+	}
+
+	var dst *image.NRGBA
+	switch fill.Mode {
+	case FillBlur:
+		dst = blurredBackground(img, width, height, fill.Sigma)
+	case FillTransparent:
+		dst = image.NewNRGBA(image.Rect(0, 0, width, height))
+	default:
+		dst = imaging.New(width, height, fill.Color)
+	}
+
+	inner := image.Rect(insets.Left, insets.Top, insets.Left+bounds.Dx(), insets.Top+bounds.Dy())
+	draw.Draw(dst, inner, img, bounds.Min, draw.Src)
+
+	return dst, nil
+}
+
+// blurredBackground stretches img to the canvas and blurs it. The blur is
+// done at a reduced size, which looks the same for large radii at a
+// fraction of the cost.
+func blurredBackground(img image.Image, width, height int, sigma float64) *image.NRGBA {
+	if sigma == 0 {
+		sigma = DefaultCanvasBlur
+	}
+
+	scale := math.Max(1, sigma/4)
+	small := imaging.Resize(img, max(1, int(float64(width)/scale)), max(1, int(float64(height)/scale)), imaging.Linear)
+	small = imaging.Blur(small, sigma/scale)
+
+	return imaging.Resize(small, width, height, imaging.Linear)
+}
@@ -0,0 +1,93 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPadInsets(t *testing.T) {
+	testcases := []struct{
+		name string
+		size image.Point
+		opts PadOptions
+		want Insets
+	}{
+		{
+			name: "Square from landscape",
+			size: image.Pt(400, 300),
+			opts: PadOptions{AspectWidth: 1, AspectHeight: 1},
+			want: Insets{Top: 50, Bottom: 50},
+		},
+		{
+			name: "Widescreen from portrait, anchored left",
+			size: image.Pt(90, 100),
+			opts: PadOptions{AspectWidth: 16, AspectHeight: 9, Anchor: AnchorLeft},
+			want: Insets{Right: 88},
+		},
+		{
+			name: "Target size anchored bottom-right",
+			size: image.Pt(100, 50),
+			opts: PadOptions{Width: 120, Height: 80, Anchor: AnchorBottomRight},
+			want: Insets{Top: 30, Left: 20},
+		},
+		{
+			name: "Target smaller than the image",
+			size: image.Pt(100, 50),
+			opts: PadOptions{Width: 80, Height: 60},
+			want: Insets{Top: 5, Bottom: 5},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			insets, err := PadInsets(tc.size, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, insets)
+		})
+	}
+
+	_, err := PadInsets(image.Pt(10, 10), PadOptions{Width: 20, AspectWidth: 1, AspectHeight: 1})
+	assert.Error(t, err)
+	_, err = PadInsets(image.Pt(10, 10), PadOptions{})
+	assert.Error(t, err)
+}
+
+func TestExtendCanvas(t *testing.T) {
+	im := &ImagingImpl{limits: Limits{MaxWidth: 100, MaxHeight: 100}}
+	red := color.NRGBA{255, 0, 0, 255}
+	base := solidImage(20, 10, red)
+	insets := Insets{Top: 1, Right: 2, Bottom: 3, Left: 4}
+
+	dst, err := im.ExtendCanvas(base, insets, CanvasFill{Mode: FillColor, Color: color.NRGBA{0, 0, 255, 255}})
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 26, 14), dst.Rect)
+	assert.Equal(t, color.NRGBA{0, 0, 255, 255}, dst.NRGBAAt(0, 0))
+	assert.Equal(t, red, dst.NRGBAAt(4, 1))
+	assert.Equal(t, red, dst.NRGBAAt(23, 10))
+	assert.Equal(t, color.NRGBA{0, 0, 255, 255}, dst.NRGBAAt(24, 10))
+
+	dst, err = im.ExtendCanvas(base, insets, CanvasFill{Mode: FillTransparent})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0), dst.NRGBAAt(25, 13).A)
+	assert.Equal(t, red, dst.NRGBAAt(10, 5))
+
+	// the blurred copy carries the colours of the image into the border
+	dst, err = im.ExtendCanvas(base, UniformInsets(10), CanvasFill{Mode: FillBlur, Sigma: 8})
+	require.NoError(t, err)
+	assert.Equal(t, red, dst.NRGBAAt(0, 0))
+
+	_, err = im.ExtendCanvas(base, UniformInsets(50), CanvasFill{})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	// padding to a valid size can need wider insets than are allowed
+	_, err = (&ImagingImpl{}).ExtendCanvas(base, Insets{Right: 65535 - 20}, CanvasFill{})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	_, err = im.ExtendCanvas(base, UniformInsets(10), CanvasFill{Mode: FillBlur, Sigma: math.NaN()})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ParseHexColor parses an RGB or RGBA colour written in hex as 3, 4, 6 or 8
+// digits, with or without a leading "#".
+func ParseHexColor(value string) (color.NRGBA, error) {
+	digits := strings.TrimPrefix(value, "#")
+
+	switch len(digits) {
+	case 3, 4:
+		var expanded strings.Builder
+		for _, digit := range digits {
+			expanded.WriteRune(digit)
+			expanded.WriteRune(digit)
+		}
+		digits = expanded.String()
+	case 6, 8:
+	default:
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", value)
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	parsed, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", value)
+	}
+
+	return color.NRGBA{
+		R: uint8(parsed >> 24),
+		G: uint8(parsed >> 16),
+		B: uint8(parsed >> 8),
+		A: uint8(parsed),
+	}, nil
+}
+
+// HexColor formats c as 6 hex digits, or 8 when it is not opaque, without
+// a leading "#".
+func HexColor(c color.NRGBA) string {
+	if c.A == 255 {
+		return fmt.Sprintf("%02x%02x%02x", c.R, c.G, c.B)
+	}
+
+	return fmt.Sprintf("%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// blendPixel paints c over the NRGBA pixel at the start of px with the given
+// coverage in [0, 1], using the source-over operator.
+func blendPixel(px []uint8, c color.NRGBA, coverage float64) {
+	sa := coverage * float64(c.A) / 255
+	if sa <= 0 {
+		return
+	}
+	da := float64(px[3]) / 255
+	outA := sa + da*(1-sa)
+
+	src := [3]uint8{c.R, c.G, c.B}
+	for ch := range 3 {
+		out := (sa*float64(src[ch]) + da*(1-sa)*float64(px[ch])) / outA
+		px[ch] = clampUint8(out)
+	}
+	px[3] = clampUint8(outA * 255)
+}
@@ -0,0 +1,195 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+// MaxKernelSize bounds the width of a convolution kernel, the cost per pixel
+// grows with its square.
+const MaxKernelSize = 15
+
+var ErrInvalidKernel = errors.New("imaging: invalid convolution kernel")
+
+// Kernel is a square convolution matrix with an odd size, stored row by row.
+type Kernel struct {
+	Size   int
+	Values []float64
+	// Divisor scales down the result, so the built-in blurs can be written
+	// with whole numbers. It is 1 when 0.
+	Divisor float64
+}
+
+// NewKernel builds a kernel from its values, which must form an odd-sized
+// square no wider than MaxKernelSize.
+func NewKernel(values []float64) (Kernel, error) {
+	size := int(math.Sqrt(float64(len(values))))
+	if size*size != len(values) || size%2 == 0 {
+		return Kernel{}, fmt.Errorf("%w: %d values do not form an odd-sized square", ErrInvalidKernel, len(values))
+	}
+	if size > MaxKernelSize {
+		return Kernel{}, fmt.Errorf("%w: larger than %dx%d", ErrInvalidKernel, MaxKernelSize, MaxKernelSize)
+	}
+	for _, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return Kernel{}, fmt.Errorf("%w: values must be finite", ErrInvalidKernel)
+		}
+	}
+
+	return Kernel{Size: size, Values: values}, nil
+}
+
+// Matrix returns the kernel as rows.
+func (k Kernel) Matrix() [][]float64 {
+	rows := make([][]float64, k.Size)
+	for row := range rows {
+		rows[row] = k.Values[row*k.Size : (row+1)*k.Size]
+	}
+
+	return rows
+}
+
+func (k Kernel) sum() float64 {
+	total := 0.0
+	for _, value := range k.Values {
+		total += value
+	}
+
+	return total
+}
+
+var namedKernels = map[string]Kernel{
+	"identity": {Size: 3, Values: []float64{
+		0, 0, 0,
+		0, 1, 0,
+		0, 0, 0,
+	}},
+	"box_blur": {Size: 3, Divisor: 9, Values: []float64{
+		1, 1, 1,
+		1, 1, 1,
+		1, 1, 1,
+	}},
+	"gaussian_blur": {Size: 5, Divisor: 256, Values: []float64{
+		1, 4, 6, 4, 1,
+		4, 16, 24, 16, 4,
+		6, 24, 36, 24, 6,
+		4, 16, 24, 16, 4,
+		1, 4, 6, 4, 1,
+	}},
+	"motion_blur": {Size: 5, Divisor: 5, Values: []float64{
+		1, 0, 0, 0, 0,
+		0, 1, 0, 0, 0,
+		0, 0, 1, 0, 0,
+		0, 0, 0, 1, 0,
+		0, 0, 0, 0, 1,
+	}},
+	"sharpen": {Size: 3, Values: []float64{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	}},
+	"edge_enhance": {Size: 3, Divisor: 2, Values: []float64{
+		-1, -1, -1,
+		-1, 10, -1,
+		-1, -1, -1,
+	}},
+	"outline": {Size: 3, Values: []float64{
+		-1, -1, -1,
+		-1, 8, -1,
+		-1, -1, -1,
+	}},
+	"emboss": {Size: 3, Values: []float64{
+		-2, -1, 0,
+		-1, 1, 1,
+		0, 1, 2,
+	}},
+}
+
+// NamedKernel looks up one of the built-in kernels.
+func NamedKernel(name string) (Kernel, bool) {
+	kernel, ok := namedKernels[name]
+	return kernel, ok
+}
+
+// KernelNames returns the names of the built-in kernels in sorted order.
+func KernelNames() []string {
+	names := make([]string, 0, len(namedKernels))
+	for name := range namedKernels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type ConvolveOptions struct {
+	// Normalize divides the result by the sum of the kernel instead of its
+	// divisor, so blurring kernels keep the overall brightness. Kernels
+	// summing to zero are left as they are.
+	Normalize bool
+	// Bias is added to every channel after convolving, e.g. 128 to centre
+	// the output of an edge kernel on grey.
+	Bias float64
+}
+
+// Convolve applies kernel to the colour channels of img, extending the edge
+// pixels outwards. Alpha is kept from the source.
+func (i *ImagingImpl) Convolve(img image.Image, kernel Kernel, opts ConvolveOptions) (*image.NRGBA, error) {
+	if kernel.Size%2 == 0 || kernel.Size*kernel.Size != len(kernel.Values) || kernel.Size > MaxKernelSize {
+		return nil, ErrInvalidKernel
+	}
+
+	src := imaging.Clone(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(src.Rect)
+
+	scale := 1.0
+	if kernel.Divisor != 0 {
+		scale = 1 / kernel.Divisor
+	}
+	if sum := kernel.sum(); opts.Normalize && sum != 0 {
+		scale = 1 / sum
+	}
+	radius := kernel.Size / 2
+
+	parallelRows(height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				var r, g, b float64
+
+				for ky := 0; ky < kernel.Size; ky++ {
+					sy := clampInt(y+ky-radius, 0, height-1)
+					for kx := 0; kx < kernel.Size; kx++ {
+						weight := kernel.Values[ky*kernel.Size+kx]
+						if weight == 0 {
+							continue
+						}
+						sx := clampInt(x+kx-radius, 0, width-1)
+
+						px := src.Pix[sy*src.Stride+sx*4:]
+						r += weight * float64(px[0])
+						g += weight * float64(px[1])
+						b += weight * float64(px[2])
+					}
+				}
+
+				out := dst.Pix[y*dst.Stride+x*4:]
+				out[0] = clampUint8(r*scale + opts.Bias)
+				out[1] = clampUint8(g*scale + opts.Bias)
+				out[2] = clampUint8(b*scale + opts.Bias)
+				out[3] = src.Pix[y*src.Stride+x*4+3]
+			}
+		}
+	})
+
+	return dst, nil
+}
+
+func clampInt(value, low, high int) int {
+	return max(low, min(value, high))
+}
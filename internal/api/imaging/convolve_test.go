@@ -0,0 +1,63 @@
+package imaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKernel(t *testing.T) {
+	_, err := NewKernel(make([]float64, 9))
+	assert.NoError(t, err)
+
+	for _, size := range []int{4, 8, 17 * 17} {
+		_, err := NewKernel(make([]float64, size))
+		assert.ErrorIs(t, err, ErrInvalidKernel, "%d values", size)
+	}
+}
+
+func TestConvolve(t *testing.T) {
+	im := &ImagingImpl{}
+	src := edgeImage()
+
+	t.Run("Identity leaves the image unchanged", func(t *testing.T) {
+		kernel, _ := NamedKernel("identity")
+		out, err := im.Convolve(src, kernel, ConvolveOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, src.Pix, out.Pix)
+	})
+
+	t.Run("Normalized blur keeps flat areas", func(t *testing.T) {
+		kernel, _ := NamedKernel("box_blur")
+		out, err := im.Convolve(src, kernel, ConvolveOptions{Normalize: true})
+		require.NoError(t, err)
+		assert.Equal(t, src.NRGBAAt(0, 0), out.NRGBAAt(0, 0))
+		assert.Equal(t, src.NRGBAAt(7, 7), out.NRGBAAt(7, 7))
+		// the edge is softened
+		assert.Greater(t, out.NRGBAAt(3, 4).R, src.NRGBAAt(3, 4).R)
+	})
+
+	t.Run("Built-in blurs keep flat areas without normalizing", func(t *testing.T) {
+		for _, name := range []string{"box_blur", "gaussian_blur", "motion_blur", "edge_enhance"} {
+			kernel, _ := NamedKernel(name)
+			out, err := im.Convolve(src, kernel, ConvolveOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, src.NRGBAAt(0, 0), out.NRGBAAt(0, 0), name)
+			assert.Equal(t, src.NRGBAAt(7, 7), out.NRGBAAt(7, 7), name)
+		}
+	})
+
+	t.Run("Bias centres a zero-sum kernel on grey", func(t *testing.T) {
+		kernel, _ := NamedKernel("outline")
+		out, err := im.Convolve(src, kernel, ConvolveOptions{Bias: 128})
+		require.NoError(t, err)
+		assert.Equal(t, uint8(128), out.NRGBAAt(0, 0).R)
+		assert.Equal(t, uint8(255), out.NRGBAAt(0, 0).A)
+	})
+
+	t.Run("Rejects a malformed kernel", func(t *testing.T) {
+		_, err := im.Convolve(src, Kernel{Size: 2, Values: make([]float64, 4)}, ConvolveOptions{})
+		assert.ErrorIs(t, err, ErrInvalidKernel)
+	})
+}
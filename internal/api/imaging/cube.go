@@ -0,0 +1,360 @@
+package imaging
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+var ErrInvalidCube = errors.New("imaging: invalid .cube file")
+
+// Limits for .cube LUTs. 3D tables grow with the cube of their size, so they
+// are capped well below 1D ones.
+const (
+	MaxCube1DSize = 65536
+	MaxCube3DSize = 65
+)
+
+// CubeLUT is a colour lookup table in the Adobe/Resolve .cube format. Table
+// holds Size entries for a 1D LUT and Size³ entries for a 3D one, with red
+// varying fastest. Inputs are scaled from [DomainMin, DomainMax] to the
+// table before lookup.
+type CubeLUT struct {
+	Title      string
+	Size       int
+	Dimensions int
+	DomainMin  [3]float64
+	DomainMax  [3]float64
+	Table      [][3]float64
+}
+
+// ParseCube reads a 1D or 3D .cube file.
+func ParseCube(r io.Reader) (*CubeLUT, error) {
+	lut := &CubeLUT{DomainMax: [3]float64{1, 1, 1}}
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if err := lut.parseLine(fields, text); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCube, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCube, err)
+	}
+
+	if err := lut.Validate(); err != nil {
+		return nil, err
+	}
+
+	return lut, nil
+}
+
+func (c *CubeLUT) parseLine(fields []string, text string) error {
+	keyword := strings.ToUpper(fields[0])
+
+	switch keyword {
+	case "TITLE":
+		c.Title = strings.Trim(strings.TrimSpace(text[len(fields[0]):]), `"`)
+		return nil
+	case "LUT_1D_SIZE", "LUT_3D_SIZE":
+		if c.Dimensions != 0 {
+			return fmt.Errorf("size given more than once")
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("%s takes one value", keyword)
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("%s must be an integer", keyword)
+		}
+		c.Size = size
+		c.Dimensions = 1
+		if keyword == "LUT_3D_SIZE" {
+			c.Dimensions = 3
+		}
+		if maxSize := c.maxSize(); size < 2 || size > maxSize {
+			return fmt.Errorf("%s must be between 2 and %d", keyword, maxSize)
+		}
+		c.Table = make([][3]float64, 0, c.entries())
+		return nil
+	case "DOMAIN_MIN", "DOMAIN_MAX":
+		values, err := parseFloats(fields[1:], 3)
+		if err != nil {
+			return fmt.Errorf("%s: %v", keyword, err)
+		}
+		if keyword == "DOMAIN_MIN" {
+			c.DomainMin = [3]float64{values[0], values[1], values[2]}
+		} else {
+			c.DomainMax = [3]float64{values[0], values[1], values[2]}
+		}
+		return nil
+	case "LUT_1D_INPUT_RANGE", "LUT_3D_INPUT_RANGE":
+		// the older Resolve form of the domain, shared by all channels
+		values, err := parseFloats(fields[1:], 2)
+		if err != nil {
+			return fmt.Errorf("%s: %v", keyword, err)
+		}
+		c.DomainMin = [3]float64{values[0], values[0], values[0]}
+		c.DomainMax = [3]float64{values[1], values[1], values[1]}
+		return nil
+	}
+
+	if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+		return fmt.Errorf("unknown keyword %q", fields[0])
+	}
+	if c.Dimensions == 0 {
+		return fmt.Errorf("table data before LUT_1D_SIZE or LUT_3D_SIZE")
+	}
+	if len(c.Table) >= c.entries() {
+		return fmt.Errorf("more than %d table entries", c.entries())
+	}
+
+	values, err := parseFloats(fields, 3)
+	if err != nil {
+		return err
+	}
+	c.Table = append(c.Table, [3]float64{values[0], values[1], values[2]})
+
+	return nil
+}
+
+func parseFloats(fields []string, count int) ([]float64, error) {
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d numbers", count)
+	}
+
+	values := make([]float64, count)
+	for idx, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%q is not a number", field)
+		}
+		values[idx] = value
+	}
+
+	return values, nil
+}
+
+func (c *CubeLUT) maxSize() int {
+	if c.Dimensions == 3 {
+		return MaxCube3DSize
+	}
+
+	return MaxCube1DSize
+}
+
+func (c *CubeLUT) entries() int {
+	if c.Dimensions == 3 {
+		return c.Size * c.Size * c.Size
+	}
+
+	return c.Size
+}
+
+func (c *CubeLUT) Validate() error {
+	if c.Dimensions != 1 && c.Dimensions != 3 {
+		return fmt.Errorf("%w: missing LUT_1D_SIZE or LUT_3D_SIZE", ErrInvalidCube)
+	}
+	if c.Size < 2 || c.Size > c.maxSize() {
+		return fmt.Errorf("%w: size must be between 2 and %d", ErrInvalidCube, c.maxSize())
+	}
+	if len(c.Table) != c.entries() {
+		return fmt.Errorf("%w: expected %d table entries, got %d", ErrInvalidCube, c.entries(), len(c.Table))
+	}
+	for ch := range 3 {
+		if c.DomainMin[ch] >= c.DomainMax[ch] {
+			return fmt.Errorf("%w: DOMAIN_MIN must be below DOMAIN_MAX", ErrInvalidCube)
+		}
+	}
+
+	return nil
+}
+
+// LUTInterpolation selects how 3D LUTs are sampled between table entries.
+// 1D LUTs are always interpolated linearly.
+type LUTInterpolation int
+
+const (
+	LUTTrilinear LUTInterpolation = iota
+	// LUTTetrahedral blends 4 instead of 8 entries, which is faster and
+	// keeps neutral greys neutral.
+	LUTTetrahedral
+)
+
+var lutInterpolationNames = map[string]LUTInterpolation{
+	"trilinear":   LUTTrilinear,
+	"tetrahedral": LUTTetrahedral,
+}
+
+func LUTInterpolationFromName(name string) (LUTInterpolation, error) {
+	interpolation, ok := lutInterpolationNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown interpolation %q", name)
+	}
+
+	return interpolation, nil
+}
+
+func LUTInterpolationName(interpolation LUTInterpolation) string {
+	for name, value := range lutInterpolationNames {
+		if value == interpolation {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// CubeLUTOptions configure ApplyCubeLUT. Intensity mixes the graded result
+// with the original, from 0 (unchanged) to 1 (fully graded).
+type CubeLUTOptions struct {
+	Interpolation LUTInterpolation
+	Intensity     float64
+}
+
+func (i *ImagingImpl) ApplyCubeLUT(img image.Image, lut *CubeLUT, opts CubeLUTOptions) (*image.NRGBA, error) {
+	if err := lut.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Intensity < 0 || opts.Intensity > 1 {
+		return nil, fmt.Errorf("imaging: intensity must be between 0 and 1")
+	}
+
+	src := imaging.Clone(img)
+	dst := image.NewNRGBA(src.Rect)
+
+	parallelRows(src.Rect.Dy(), func(start, end int) {
+		for idx := start * src.Stride; idx < end*src.Stride; idx += 4 {
+			var in [3]float64
+			for ch := range 3 {
+				in[ch] = lut.tableCoord(ch, float64(src.Pix[idx+ch])/255)
+			}
+
+			var out [3]float64
+			switch {
+			case lut.Dimensions == 1:
+				out = lut.sample1D(in)
+			case opts.Interpolation == LUTTetrahedral:
+				out = lut.sampleTetrahedral(in)
+			default:
+				out = lut.sampleTrilinear(in)
+			}
+
+			for ch := range 3 {
+				value := float64(src.Pix[idx+ch])
+				dst.Pix[idx+ch] = clampUint8(value + opts.Intensity*(out[ch]*255-value))
+			}
+			dst.Pix[idx+3] = src.Pix[idx+3]
+		}
+	})
+
+	return dst, nil
+}
+
+// tableCoord maps a 0-1 channel value to a fractional table index.
+func (c *CubeLUT) tableCoord(ch int, value float64) float64 {
+	t := (value - c.DomainMin[ch]) / (c.DomainMax[ch] - c.DomainMin[ch])
+	t = math.Max(0, math.Min(1, t))
+
+	return t * float64(c.Size-1)
+}
+
+// cell splits a table coordinate into the lower index and the fraction
+// towards the next one.
+func (c *CubeLUT) cell(coord float64) (int, float64) {
+	base := min(int(coord), c.Size-2)
+	return base, coord - float64(base)
+}
+
+func (c *CubeLUT) sample1D(in [3]float64) [3]float64 {
+	var out [3]float64
+	for ch := range 3 {
+		base, frac := c.cell(in[ch])
+		out[ch] = c.Table[base][ch] + frac*(c.Table[base+1][ch]-c.Table[base][ch])
+	}
+
+	return out
+}
+
+func (c *CubeLUT) at(r, g, b int) [3]float64 {
+	return c.Table[r+g*c.Size+b*c.Size*c.Size]
+}
+
+func (c *CubeLUT) sampleTrilinear(in [3]float64) [3]float64 {
+	r, fr := c.cell(in[0])
+	g, fg := c.cell(in[1])
+	b, fb := c.cell(in[2])
+
+	var out [3]float64
+	for ch := range 3 {
+		c00 := lerp(c.at(r, g, b)[ch], c.at(r+1, g, b)[ch], fr)
+		c10 := lerp(c.at(r, g+1, b)[ch], c.at(r+1, g+1, b)[ch], fr)
+		c01 := lerp(c.at(r, g, b+1)[ch], c.at(r+1, g, b+1)[ch], fr)
+		c11 := lerp(c.at(r, g+1, b+1)[ch], c.at(r+1, g+1, b+1)[ch], fr)
+		out[ch] = lerp(lerp(c00, c10, fg), lerp(c01, c11, fg), fb)
+	}
+
+	return out
+}
+
+// sampleTetrahedral splits the cell into six tetrahedra along its diagonal
+// and blends the four corners of the one containing the input.
+func (c *CubeLUT) sampleTetrahedral(in [3]float64) [3]float64 {
+	r, fr := c.cell(in[0])
+	g, fg := c.cell(in[1])
+	b, fb := c.cell(in[2])
+
+	c000 := c.at(r, g, b)
+	c111 := c.at(r+1, g+1, b+1)
+
+	// the path from c000 to c111 steps along the axes in order of their
+	// fractions, largest first
+	var first, second [3]float64
+	var w0, w1, w2 float64
+	switch {
+	case fr >= fg && fg >= fb:
+		first, second = c.at(r+1, g, b), c.at(r+1, g+1, b)
+		w0, w1, w2 = fr, fg, fb
+	case fr >= fb && fb >= fg:
+		first, second = c.at(r+1, g, b), c.at(r+1, g, b+1)
+		w0, w1, w2 = fr, fb, fg
+	case fb >= fr && fr >= fg:
+		first, second = c.at(r, g, b+1), c.at(r+1, g, b+1)
+		w0, w1, w2 = fb, fr, fg
+	case fb >= fg && fg >= fr:
+		first, second = c.at(r, g, b+1), c.at(r, g+1, b+1)
+		w0, w1, w2 = fb, fg, fr
+	case fg >= fb && fb >= fr:
+		first, second = c.at(r, g+1, b), c.at(r, g+1, b+1)
+		w0, w1, w2 = fg, fb, fr
+	default:
+		first, second = c.at(r, g+1, b), c.at(r+1, g+1, b)
+		w0, w1, w2 = fg, fr, fb
+	}
+
+	var out [3]float64
+	for ch := range 3 {
+		out[ch] = c000[ch] + w0*(first[ch]-c000[ch]) + w1*(second[ch]-first[ch]) + w2*(c111[ch]-second[ch])
+	}
+
+	return out
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + t*(b-a)
+}
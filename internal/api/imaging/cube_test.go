@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cube3D writes a 3D .cube file of the given size whose entries are fn of
+// the normalized grid coordinates.
+func cube3D(size int, fn func(r, g, b float64) (float64, float64, float64)) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "TITLE \"test\"\n# comment\nLUT_3D_SIZE %d\n", size)
+	step := float64(size - 1)
+	for b := 0; b < size; b++ {
+		for g := 0; g < size; g++ {
+			for r := 0; r < size; r++ {
+				or, og, ob := fn(float64(r)/step, float64(g)/step, float64(b)/step)
+				fmt.Fprintf(&sb, "%f %f %f\n", or, og, ob)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func TestParseCube(t *testing.T) {
+	lut, err := ParseCube(strings.NewReader(cube3D(3, func(r, g, b float64) (float64, float64, float64) { return r, g, b })))
+	require.NoError(t, err)
+	assert.Equal(t, "test", lut.Title)
+	assert.Equal(t, 3, lut.Dimensions)
+	assert.Equal(t, 3, lut.Size)
+	assert.Len(t, lut.Table, 27)
+	assert.Equal(t, [3]float64{1, 1, 1}, lut.DomainMax)
+
+	lut, err = ParseCube(strings.NewReader("LUT_1D_SIZE 2\nDOMAIN_MIN 0 0 0\nDOMAIN_MAX 2 2 2\n0 0 0\n1 1 1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, lut.Dimensions)
+	assert.Equal(t, [3]float64{2, 2, 2}, lut.DomainMax)
+}
+
+func TestParseCubeErrors(t *testing.T) {
+	testcases := []struct{
+		name string
+		input string
+	}{
+		{name: "No size", input: "0 0 0\n"},
+		{name: "Too few entries", input: "LUT_3D_SIZE 2\n0 0 0\n"},
+		{name: "Too many entries", input: "LUT_1D_SIZE 2\n0 0 0\n1 1 1\n1 1 1\n"},
+		{name: "Size too large", input: "LUT_3D_SIZE 100\n"},
+		{name: "Unknown keyword", input: "LUT_4D_SIZE 2\n"},
+		{name: "Bad number", input: "LUT_1D_SIZE 2\n0 0 x\n1 1 1\n"},
+		{name: "Empty domain", input: "LUT_1D_SIZE 2\nDOMAIN_MIN 1 1 1\n0 0 0\n1 1 1\n"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseCube(strings.NewReader(tc.input))
+			assert.ErrorIs(t, err, ErrInvalidCube)
+		})
+	}
+}
+
+func TestApplyCubeLUT(t *testing.T) {
+	im := &ImagingImpl{}
+	src := edgeImage()
+
+	// both interpolations reproduce a linear mapping exactly, so swapping red
+	// and blue on a coarse grid must match the swap computed directly
+	swap, err := ParseCube(strings.NewReader(cube3D(5, func(r, g, b float64) (float64, float64, float64) { return b, g, r })))
+	require.NoError(t, err)
+
+	for _, interpolation := range []LUTInterpolation{LUTTrilinear, LUTTetrahedral} {
+		t.Run(LUTInterpolationName(interpolation), func(t *testing.T) {
+			dst, err := im.ApplyCubeLUT(src, swap, CubeLUTOptions{Interpolation: interpolation, Intensity: 1})
+			require.NoError(t, err)
+
+			for _, point := range []image.Point{{0, 0}, {7, 7}} {
+				in := src.NRGBAAt(point.X, point.Y)
+				out := dst.NRGBAAt(point.X, point.Y)
+				assert.InDelta(t, in.B, out.R, 1)
+				assert.InDelta(t, in.G, out.G, 1)
+				assert.InDelta(t, in.R, out.B, 1)
+				assert.Equal(t, in.A, out.A)
+			}
+		})
+	}
+
+	// half intensity lands halfway between the original and the graded value
+	invert, err := ParseCube(strings.NewReader("LUT_1D_SIZE 2\n1 1 1\n0 0 0\n"))
+	require.NoError(t, err)
+
+	dst, err := im.ApplyCubeLUT(src, invert, CubeLUTOptions{Intensity: 1})
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{175, 155, 135, 255}, dst.NRGBAAt(0, 0))
+
+	dst, err = im.ApplyCubeLUT(src, invert, CubeLUTOptions{Intensity: 0.5})
+	require.NoError(t, err)
+	assert.InDelta(t, 127.5, float64(dst.NRGBAAt(0, 0).R), 1)
+
+	_, err = im.ApplyCubeLUT(src, invert, CubeLUTOptions{Intensity: 2})
+	assert.Error(t, err)
+}
+
+func TestApplyCubeLUTDomain(t *testing.T) {
+	im := &ImagingImpl{}
+
+	// with a domain of [0, 2] the full 0-1 input range only reaches the
+	// middle of the table
+	lut, err := ParseCube(strings.NewReader("LUT_1D_SIZE 3\nDOMAIN_MAX 2 2 2\n0 0 0\n0.5 0.5 0.5\n1 1 1\n"))
+	require.NoError(t, err)
+
+	src := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{127, 255, 0, 255})
+
+	dst, err := im.ApplyCubeLUT(src, lut, CubeLUTOptions{Intensity: 1})
+	require.NoError(t, err)
+	assert.InDelta(t, 63, float64(dst.NRGBAAt(0, 0).R), 1)
+	assert.InDelta(t, 127, float64(dst.NRGBAAt(0, 0).G), 1)
+	assert.Equal(t, uint8(0), dst.NRGBAAt(0, 0).B)
+}
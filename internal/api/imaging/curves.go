@@ -0,0 +1,245 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Channel selects which colour channels a tonal adjustment applies to.
+type Channel int
+
+const (
+	ChannelRGB Channel = iota
+	ChannelRed
+	ChannelGreen
+	ChannelBlue
+)
+
+var channelNames = map[string]Channel{
+	"rgb": ChannelRGB,
+	"r":   ChannelRed,
+	"g":   ChannelGreen,
+	"b":   ChannelBlue,
+}
+
+func ChannelFromName(name string) (Channel, error) {
+	channel, ok := channelNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown channel %q", name)
+	}
+
+	return channel, nil
+}
+
+func ChannelName(channel Channel) string {
+	for name, c := range channelNames {
+		if c == channel {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Limits for levels and curves.
+const (
+	MinLevelsGamma = 0.1
+	MaxLevelsGamma = 10
+	MaxCurvePoints = 16
+)
+
+// Levels remaps values from the input range to the output range, bending
+// the midtones with gamma. Values are on a 0-255 scale.
+type Levels struct {
+	InputBlack  float64
+	InputWhite  float64
+	Gamma       float64
+	OutputBlack float64
+	OutputWhite float64
+}
+
+// DefaultLevels returns levels that leave values unchanged.
+func DefaultLevels() Levels {
+	return Levels{InputWhite: 255, Gamma: 1, OutputWhite: 255}
+}
+
+func (l Levels) Validate() error {
+	for _, value := range []float64{l.InputBlack, l.InputWhite, l.OutputBlack, l.OutputWhite} {
+		if value < 0 || value > 255 {
+			return fmt.Errorf("levels values must be between 0 and 255")
+		}
+	}
+	if l.InputBlack >= l.InputWhite {
+		return fmt.Errorf("input black point must be below the white point")
+	}
+	if l.Gamma < MinLevelsGamma || l.Gamma > MaxLevelsGamma {
+		return fmt.Errorf("gamma must be between %v and %v", MinLevelsGamma, MaxLevelsGamma)
+	}
+
+	return nil
+}
+
+// LUT compiles the levels into a lookup table.
+func (l Levels) LUT() [256]uint8 {
+	var lut [256]uint8
+	for value := range lut {
+		normalized := math.Max(0, math.Min(1, (float64(value)-l.InputBlack)/(l.InputWhite-l.InputBlack)))
+		normalized = math.Pow(normalized, 1/l.Gamma)
+		lut[value] = clampUint8(l.OutputBlack + normalized*(l.OutputWhite-l.OutputBlack))
+	}
+
+	return lut
+}
+
+type CurvePoint struct {
+	In  float64
+	Out float64
+}
+
+// Curve maps input values to output values through control points, which
+// are joined by a monotone cubic spline so the curve never overshoots
+// between them. Values are on a 0-255 scale.
+type Curve []CurvePoint
+
+func (c Curve) Validate() error {
+	if len(c) < 2 || len(c) > MaxCurvePoints {
+		return fmt.Errorf("curves need between 2 and %d points", MaxCurvePoints)
+	}
+	for idx, point := range c {
+		if point.In < 0 || point.In > 255 || point.Out < 0 || point.Out > 255 {
+			return fmt.Errorf("curve points must be between 0 and 255")
+		}
+		if idx > 0 && point.In <= c[idx-1].In {
+			return fmt.Errorf("curve points must be in increasing input order")
+		}
+	}
+
+	return nil
+}
+
+// LUT compiles the curve into a lookup table. Inputs outside the first and
+// last points keep the value of the nearest one.
+func (c Curve) LUT() [256]uint8 {
+	n := len(c)
+
+	// Fritsch-Carlson tangents
+	secants := make([]float64, n-1)
+	for k := 0; k < n-1; k++ {
+		secants[k] = (c[k+1].Out - c[k].Out) / (c[k+1].In - c[k].In)
+	}
+
+	tangents := make([]float64, n)
+	tangents[0], tangents[n-1] = secants[0], secants[n-2]
+	for k := 1; k < n-1; k++ {
+		if secants[k-1]*secants[k] > 0 {
+			tangents[k] = (secants[k-1] + secants[k]) / 2
+		}
+	}
+	for k := 0; k < n-1; k++ {
+		if secants[k] == 0 {
+			tangents[k], tangents[k+1] = 0, 0
+			continue
+		}
+
+		a, b := tangents[k]/secants[k], tangents[k+1]/secants[k]
+		if a < 0 {
+			tangents[k] = 0
+		}
+		if b < 0 {
+			tangents[k+1] = 0
+		}
+		if a*a+b*b > 9 {
+			t := 3 / math.Sqrt(a*a+b*b)
+			tangents[k] = t * a * secants[k]
+			tangents[k+1] = t * b * secants[k]
+		}
+	}
+
+	var lut [256]uint8
+	segment := 0
+	for value := range lut {
+		x := float64(value)
+		switch {
+		case x <= c[0].In:
+			lut[value] = clampUint8(c[0].Out)
+			continue
+		case x >= c[n-1].In:
+			lut[value] = clampUint8(c[n-1].Out)
+			continue
+		}
+
+		for x > c[segment+1].In {
+			segment++
+		}
+
+		// cubic Hermite interpolation within the segment
+		h := c[segment+1].In - c[segment].In
+		t := (x - c[segment].In) / h
+		t2, t3 := t*t, t*t*t
+
+		y := (2*t3-3*t2+1)*c[segment].Out +
+			(t3-2*t2+t)*h*tangents[segment] +
+			(-2*t3+3*t2)*c[segment+1].Out +
+			(t3-t2)*h*tangents[segment+1]
+		lut[value] = clampUint8(y)
+	}
+
+	return lut
+}
+
+func (i *ImagingImpl) Levels(img image.Image, channel Channel, levels Levels) (*image.NRGBA, error) {
+	if err := levels.Validate(); err != nil {
+		return nil, fmt.Errorf("imaging: %w", err)
+	}
+
+	return i.ApplyChannelLUTs(img, IdentityLUTs().Then(channel, levels.LUT())), nil
+}
+
+func (i *ImagingImpl) Curves(img image.Image, channel Channel, curve Curve) (*image.NRGBA, error) {
+	if err := curve.Validate(); err != nil {
+		return nil, fmt.Errorf("imaging: %w", err)
+	}
+
+	return i.ApplyChannelLUTs(img, IdentityLUTs().Then(channel, curve.LUT())), nil
+}
+
+// ChannelLUTs holds a lookup table for each of the red, green and blue
+// channels. Several levels and curves adjustments compose into one set, so
+// they can be applied in a single pass.
+type ChannelLUTs [3][256]uint8
+
+// IdentityLUTs returns tables that leave every value unchanged.
+func IdentityLUTs() ChannelLUTs {
+	var luts ChannelLUTs
+	for c := range luts {
+		for value := range luts[c] {
+			luts[c][value] = uint8(value)
+		}
+	}
+
+	return luts
+}
+
+// Then returns the tables that apply l and then lut to the selected
+// channels.
+func (l ChannelLUTs) Then(channel Channel, lut [256]uint8) ChannelLUTs {
+	for c := range l {
+		if channel != ChannelRGB && int(channel)-1 != c {
+			continue
+		}
+		for value := range l[c] {
+			l[c][value] = lut[l[c][value]]
+		}
+	}
+
+	return l
+}
+
+// ApplyChannelLUTs maps the colour channels of img through luts, keeping
+// alpha.
+func (i *ImagingImpl) ApplyChannelLUTs(img image.Image, luts ChannelLUTs) *image.NRGBA {
+	return applyLUTs(imaging.Clone(img), luts)
+}
@@ -0,0 +1,88 @@
+package imaging
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelsLUT(t *testing.T) {
+	identity := DefaultLevels().LUT()
+	for value, mapped := range identity {
+		assert.Equal(t, uint8(value), mapped)
+	}
+
+	lut := Levels{InputBlack: 50, InputWhite: 200, Gamma: 1, OutputBlack: 10, OutputWhite: 240}.LUT()
+	assert.Equal(t, uint8(10), lut[0])
+	assert.Equal(t, uint8(10), lut[50])
+	assert.Equal(t, uint8(125), lut[125])
+	assert.Equal(t, uint8(240), lut[200])
+	assert.Equal(t, uint8(240), lut[255])
+
+	// gamma above 1 brightens the midtones
+	bright := Levels{InputWhite: 255, Gamma: 2, OutputWhite: 255}.LUT()
+	assert.Greater(t, bright[128], uint8(128))
+}
+
+func TestLevelsValidate(t *testing.T) {
+	assert.NoError(t, DefaultLevels().Validate())
+	assert.Error(t, Levels{InputBlack: 200, InputWhite: 100, Gamma: 1, OutputWhite: 255}.Validate())
+	assert.Error(t, Levels{InputWhite: 255, Gamma: 0, OutputWhite: 255}.Validate())
+}
+
+func TestCurveLUT(t *testing.T) {
+	curve := Curve{{In: 0, Out: 0}, {In: 64, Out: 40}, {In: 192, Out: 220}, {In: 255, Out: 255}}
+	require.NoError(t, curve.Validate())
+
+	lut := curve.LUT()
+	assert.Equal(t, uint8(0), lut[0])
+	assert.Equal(t, uint8(40), lut[64])
+	assert.Equal(t, uint8(220), lut[192])
+	assert.Equal(t, uint8(255), lut[255])
+
+	// monotone data gives a monotone curve, without overshooting
+	for value := 1; value < 256; value++ {
+		assert.GreaterOrEqual(t, lut[value], lut[value-1], "value %d", value)
+	}
+
+	// outside the control points the end values are held
+	clamped := Curve{{In: 50, Out: 30}, {In: 200, Out: 180}}.LUT()
+	assert.Equal(t, uint8(30), clamped[0])
+	assert.Equal(t, uint8(180), clamped[255])
+}
+
+func TestCurveValidate(t *testing.T) {
+	assert.Error(t, Curve{{In: 0, Out: 0}}.Validate())
+	assert.Error(t, Curve{{In: 100, Out: 0}, {In: 50, Out: 255}}.Validate())
+	assert.Error(t, Curve{{In: 0, Out: 0}, {In: 300, Out: 255}}.Validate())
+}
+
+func TestCurvesSingleChannel(t *testing.T) {
+	src := edgeImage()
+	invert := Curve{{In: 0, Out: 255}, {In: 255, Out: 0}}
+
+	out, err := (&ImagingImpl{}).Curves(src, ChannelRed, invert)
+	require.NoError(t, err)
+
+	assert.Equal(t, color.NRGBA{R: 175, G: 100, B: 120, A: 255}, out.NRGBAAt(0, 0))
+}
+
+func TestChannelLUTsThen(t *testing.T) {
+	invert := Curve{{In: 0, Out: 255}, {In: 255, Out: 0}}.LUT()
+	halve := Levels{InputWhite: 255, Gamma: 1, OutputWhite: 127.5}.LUT()
+
+	luts := IdentityLUTs().Then(ChannelRed, invert).Then(ChannelRGB, halve)
+	assert.Equal(t, halve[invert[80]], luts[0][80])
+	assert.Equal(t, halve[80], luts[1][80])
+	assert.Equal(t, halve[80], luts[2][80])
+
+	// composed tables match applying the adjustments one after another
+	im := &ImagingImpl{}
+	red, err := im.Curves(edgeImage(), ChannelRed, Curve{{In: 0, Out: 255}, {In: 255, Out: 0}})
+	require.NoError(t, err)
+	both, err := im.Levels(red, ChannelRGB, Levels{InputWhite: 255, Gamma: 1, OutputWhite: 127.5})
+	require.NoError(t, err)
+	assert.Equal(t, both.Pix, im.ApplyChannelLUTs(edgeImage(), luts).Pix)
+}
@@ -0,0 +1,364 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+type DenoiseMethod int
+
+const (
+	// DenoiseMedian replaces every pixel with the median of its window,
+	// which removes speckles and salt-and-pepper noise.
+	DenoiseMedian DenoiseMethod = iota
+	// DenoiseBilateral averages neighbours weighted by both distance and
+	// colour similarity, so edges between different colours are kept.
+	DenoiseBilateral
+	// DenoiseNLMeans averages pixels whose surroundings look alike, wherever
+	// they are within the search window.
+	DenoiseNLMeans
+)
+
+var denoiseMethods = map[string]DenoiseMethod{
+	"median":    DenoiseMedian,
+	"bilateral": DenoiseBilateral,
+	"nlmeans":   DenoiseNLMeans,
+}
+
+// DenoiseMethodFromName looks up a noise reduction method by name.
+func DenoiseMethodFromName(name string) (DenoiseMethod, error) {
+	method, ok := denoiseMethods[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown denoise method %q", name)
+	}
+
+	return method, nil
+}
+
+func DenoiseMethodName(method DenoiseMethod) string {
+	for name, m := range denoiseMethods {
+		if m == method {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// DenoiseMethodNames returns the noise reduction methods in sorted order.
+func DenoiseMethodNames() []string {
+	names := make([]string, 0, len(denoiseMethods))
+	for name := range denoiseMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Upper bounds for the denoise parameters, which otherwise make the filters
+// arbitrarily slow.
+const (
+	MaxMedianRadius       = 7
+	MaxBilateralSigma     = 10
+	MaxNLMeansRadius      = 10
+	MaxNLMeansPatchRadius = 3
+	// MaxNLMeansPixels bounds the size of images non-local means takes,
+	// which needs about 28 bytes of working memory per pixel, and
+	// MaxNLMeansWork its pixels times the area of the search window, which
+	// is how many full-frame passes it makes.
+	MaxNLMeansPixels = 16_000_000
+	MaxNLMeansWork   = 2_000_000_000
+	// MaxWindowFilterWork bounds the pixels times the window area of the
+	// median and bilateral filters, which visit the whole window for every
+	// pixel. A bilateral spatial sigma of 10 has a 41x41 window, for example.
+	MaxWindowFilterWork = 4_000_000_000
+)
+
+// DenoiseOptions configures Denoise. Zero values select the defaults noted
+// on each field.
+type DenoiseOptions struct {
+	Method DenoiseMethod
+	// Radius is the median window radius, the window being 2*Radius+1 wide
+	// (default 1), or the non-local means search radius (default 5).
+	Radius int
+	// SpatialSigma and RangeSigma are the bilateral falloffs for distance in
+	// pixels (default 3) and for colour difference on a 0-255 scale
+	// (default 30).
+	SpatialSigma float64
+	RangeSigma   float64
+	// Strength is the non-local means filtering parameter h on a 0-255
+	// scale (default 10). Higher values smooth more.
+	Strength float64
+	// PatchRadius sets the size of the patches non-local means compares
+	// (default 1, i.e. 3x3).
+	PatchRadius int
+}
+
+func (o DenoiseOptions) withDefaults() DenoiseOptions {
+	defaultFloat := func(value *float64, fallback float64) {
+		if *value == 0 {
+			*value = fallback
+		}
+	}
+	defaultInt := func(value *int, fallback int) {
+		if *value == 0 {
+			*value = fallback
+		}
+	}
+
+	switch o.Method {
+	case DenoiseMedian:
+		defaultInt(&o.Radius, 1)
+	case DenoiseBilateral:
+		defaultFloat(&o.SpatialSigma, 3)
+		defaultFloat(&o.RangeSigma, 30)
+	case DenoiseNLMeans:
+		defaultInt(&o.Radius, 5)
+		defaultInt(&o.PatchRadius, 1)
+		defaultFloat(&o.Strength, 10)
+	}
+
+	return o
+}
+
+func (o DenoiseOptions) validate() error {
+	switch o.Method {
+	case DenoiseMedian:
+		if o.Radius < 1 || o.Radius > MaxMedianRadius {
+			return fmt.Errorf("imaging: median radius must be between 1 and %d", MaxMedianRadius)
+		}
+	case DenoiseBilateral:
+		if o.SpatialSigma <= 0 || o.SpatialSigma > MaxBilateralSigma || o.RangeSigma <= 0 {
+			return fmt.Errorf("imaging: bilateral sigmas must be positive and the spatial one at most %d", MaxBilateralSigma)
+		}
+	case DenoiseNLMeans:
+		if o.Radius < 1 || o.Radius > MaxNLMeansRadius || o.PatchRadius < 1 || o.PatchRadius > MaxNLMeansPatchRadius || o.Strength <= 0 {
+			return fmt.Errorf("imaging: invalid non-local means parameters")
+		}
+	default:
+		return fmt.Errorf("imaging: unknown denoise method %d", o.Method)
+	}
+
+	return nil
+}
+
+// Denoise reduces noise in the colour channels of img. Alpha is kept from
+// the source.
+func (i *ImagingImpl) Denoise(img image.Image, opts DenoiseOptions) (*image.NRGBA, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if err := checkDenoiseCost(img.Bounds().Size(), opts); err != nil {
+		return nil, err
+	}
+
+	src := imaging.Clone(img)
+
+	switch opts.Method {
+	case DenoiseMedian:
+		return medianFilter(src, opts.Radius), nil
+	case DenoiseBilateral:
+		return bilateralFilter(src, opts.SpatialSigma, opts.RangeSigma), nil
+	default:
+		return nlMeansFilter(src, opts.Radius, opts.PatchRadius, opts.Strength), nil
+	}
+}
+
+// checkDenoiseCost rejects images too large for the window of the method,
+// before any work is done on them.
+func checkDenoiseCost(size image.Point, opts DenoiseOptions) error {
+	pixels := int64(size.X) * int64(size.Y)
+
+	radius, budget := opts.Radius, int64(MaxWindowFilterWork)
+	switch opts.Method {
+	case DenoiseBilateral:
+		radius = bilateralRadius(opts.SpatialSigma)
+	case DenoiseNLMeans:
+		if pixels > MaxNLMeansPixels {
+			return fmt.Errorf("%w: non-local means takes at most %d pixels", ErrImageTooLarge, MaxNLMeansPixels)
+		}
+		budget = MaxNLMeansWork
+	}
+
+	window := int64(2*radius+1) * int64(2*radius+1)
+	if pixels*window > budget {
+		return fmt.Errorf("%w: the %s window is too large for a %dx%d image", ErrImageTooLarge, DenoiseMethodName(opts.Method), size.X, size.Y)
+	}
+
+	return nil
+}
+
+// bilateralRadius is the window radius of the bilateral filter, beyond
+// which the spatial weights are negligible.
+func bilateralRadius(spatialSigma float64) int {
+	return int(math.Ceil(2 * spatialSigma))
+}
+
+func medianFilter(src *image.NRGBA, radius int) *image.NRGBA {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(src.Rect)
+	window := (2*radius + 1) * (2*radius + 1)
+
+	parallelRows(height, func(start, end int) {
+		values := make([]uint8, 0, window)
+
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				out := dst.Pix[y*dst.Stride+x*4:]
+
+				for c := 0; c < 3; c++ {
+					values = values[:0]
+					for wy := y - radius; wy <= y+radius; wy++ {
+						row := clampInt(wy, 0, height-1) * src.Stride
+						for wx := x - radius; wx <= x+radius; wx++ {
+							values = append(values, src.Pix[row+clampInt(wx, 0, width-1)*4+c])
+						}
+					}
+					slices.Sort(values)
+					out[c] = values[len(values)/2]
+				}
+				out[3] = src.Pix[y*src.Stride+x*4+3]
+			}
+		}
+	})
+
+	return dst
+}
+
+func bilateralFilter(src *image.NRGBA, spatialSigma, rangeSigma float64) *image.NRGBA {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewNRGBA(src.Rect)
+
+	radius := bilateralRadius(spatialSigma)
+	size := 2*radius + 1
+	spatial := make([]float64, size*size)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*size+dx+radius] = math.Exp(-float64(dx*dx+dy*dy) / (2 * spatialSigma * spatialSigma))
+		}
+	}
+	rangeScale := -1 / (2 * rangeSigma * rangeSigma)
+
+	parallelRows(height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				center := src.Pix[y*src.Stride+x*4:]
+				var r, g, b, total float64
+
+				for dy := -radius; dy <= radius; dy++ {
+					row := clampInt(y+dy, 0, height-1) * src.Stride
+					for dx := -radius; dx <= radius; dx++ {
+						px := src.Pix[row+clampInt(x+dx, 0, width-1)*4:]
+
+						dr := float64(px[0]) - float64(center[0])
+						dg := float64(px[1]) - float64(center[1])
+						db := float64(px[2]) - float64(center[2])
+
+						weight := spatial[(dy+radius)*size+dx+radius] * math.Exp((dr*dr+dg*dg+db*db)*rangeScale)
+						r += weight * float64(px[0])
+						g += weight * float64(px[1])
+						b += weight * float64(px[2])
+						total += weight
+					}
+				}
+
+				out := dst.Pix[y*dst.Stride+x*4:]
+				out[0] = clampUint8(r / total)
+				out[1] = clampUint8(g / total)
+				out[2] = clampUint8(b / total)
+				out[3] = center[3]
+			}
+		}
+	})
+
+	return dst
+}
+
+// nlMeansFilter is a fast non-local means: patches are compared on
+// luminance only, and for each offset in the search window the patch
+// distances of all pixels are computed together as separable box sums of
+// the squared differences, rather than patch by patch.
+func nlMeansFilter(src *image.NRGBA, radius, patchRadius int, strength float64) *image.NRGBA {
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	pixels := width * height
+
+	luma := make([]float32, pixels)
+	parallelRows(height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				luma[y*width+x] = float32(luminance(src.Pix[y*src.Stride+x*4:]))
+			}
+		}
+	})
+
+	rowSums := make([]float32, pixels)
+	accum := make([]float32, pixels*3)
+	weights := make([]float32, pixels)
+
+	patchArea := float32((2*patchRadius + 1) * (2*patchRadius + 1))
+	scale := float32(-1 / (strength * strength))
+
+	for oy := -radius; oy <= radius; oy++ {
+		for ox := -radius; ox <= radius; ox++ {
+			// horizontal sums of squared differences against the offset image
+			parallelRows(height, func(start, end int) {
+				for y := start; y < end; y++ {
+					for x := 0; x < width; x++ {
+						var sum float32
+						for px := x - patchRadius; px <= x+patchRadius; px++ {
+							sx := clampInt(px, 0, width-1)
+							d := luma[y*width+sx] - luma[clampInt(y+oy, 0, height-1)*width+clampInt(sx+ox, 0, width-1)]
+							sum += d * d
+						}
+						rowSums[y*width+x] = sum
+					}
+				}
+			})
+
+			// vertical sums complete the patch distance, which weighs the
+			// offset pixel's colour
+			parallelRows(height, func(start, end int) {
+				for y := start; y < end; y++ {
+					for x := 0; x < width; x++ {
+						var distance float32
+						for py := y - patchRadius; py <= y+patchRadius; py++ {
+							distance += rowSums[clampInt(py, 0, height-1)*width+x]
+						}
+
+						weight := float32(math.Exp(float64(distance / patchArea * scale)))
+						px := src.Pix[clampInt(y+oy, 0, height-1)*src.Stride+clampInt(x+ox, 0, width-1)*4:]
+
+						idx := y*width + x
+						accum[idx*3] += weight * float32(px[0])
+						accum[idx*3+1] += weight * float32(px[1])
+						accum[idx*3+2] += weight * float32(px[2])
+						weights[idx] += weight
+					}
+				}
+			})
+		}
+	}
+
+	dst := image.NewNRGBA(src.Rect)
+	parallelRows(height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				idx := y*width + x
+				out := dst.Pix[y*dst.Stride+x*4:]
+				for c := 0; c < 3; c++ {
+					out[c] = clampUint8(float64(accum[idx*3+c] / weights[idx]))
+				}
+				out[3] = src.Pix[y*src.Stride+x*4+3]
+			}
+		}
+	})
+
+	return dst
+}
@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// speckledImage is the edge image with a single white speckle on each side.
+func speckledImage() *image.NRGBA {
+	img := edgeImage()
+	img.SetNRGBA(1, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	img.SetNRGBA(6, 6, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	return img
+}
+
+func TestDenoise(t *testing.T) {
+	im := &ImagingImpl{}
+	src := speckledImage()
+	clean := edgeImage()
+
+	testcases := []struct{
+		name string
+		opts DenoiseOptions
+	}{
+		{
+			name: "Median",
+			opts: DenoiseOptions{Method: DenoiseMedian},
+		},
+		{
+			name: "Bilateral",
+			opts: DenoiseOptions{Method: DenoiseBilateral, SpatialSigma: 1, RangeSigma: 200},
+		},
+		{
+			name: "Non-local means",
+			opts: DenoiseOptions{Method: DenoiseNLMeans, Radius: 2, Strength: 30},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := im.Denoise(src, tc.opts)
+			require.NoError(t, err)
+
+			// the speckles are pulled towards their surroundings
+			for _, p := range []image.Point{{1, 1}, {6, 6}} {
+				before := absDiff(src.NRGBAAt(p.X, p.Y).R, clean.NRGBAAt(p.X, p.Y).R)
+				after := absDiff(out.NRGBAAt(p.X, p.Y).R, clean.NRGBAAt(p.X, p.Y).R)
+				assert.Less(t, after, before, "speckle at %v", p)
+			}
+		})
+	}
+}
+
+func TestDenoiseMedianRemovesSpeckles(t *testing.T) {
+	out, err := (&ImagingImpl{}).Denoise(speckledImage(), DenoiseOptions{Method: DenoiseMedian})
+	require.NoError(t, err)
+	assert.Equal(t, edgeImage().Pix, out.Pix)
+}
+
+func TestDenoiseKeepsEdges(t *testing.T) {
+	im := &ImagingImpl{}
+	src := edgeImage()
+
+	// the colours across the edge differ by 80, far outside the range sigma
+	out, err := im.Denoise(src, DenoiseOptions{Method: DenoiseBilateral, SpatialSigma: 2, RangeSigma: 10})
+	require.NoError(t, err)
+	assert.Equal(t, src.Pix, out.Pix)
+
+	out, err = im.Denoise(src, DenoiseOptions{Method: DenoiseNLMeans, Strength: 5})
+	require.NoError(t, err)
+	assert.InDelta(t, src.NRGBAAt(3, 4).R, out.NRGBAAt(3, 4).R, 2)
+	assert.InDelta(t, src.NRGBAAt(4, 4).R, out.NRGBAAt(4, 4).R, 2)
+}
+
+func TestDenoiseRejectsInvalidOptions(t *testing.T) {
+	_, err := (&ImagingImpl{}).Denoise(edgeImage(), DenoiseOptions{Method: DenoiseMedian, Radius: MaxMedianRadius + 1})
+	assert.Error(t, err)
+}
+
+func TestCheckDenoiseCost(t *testing.T) {
+	testcases := []struct{
+		name string
+		size image.Point
+		opts DenoiseOptions
+		wantErr bool
+	}{
+		{name: "Non-local means defaults", size: image.Pt(4000, 3000), opts: DenoiseOptions{Method: DenoiseNLMeans, Radius: 5}},
+		{name: "Non-local means largest search", size: image.Pt(4000, 3000), opts: DenoiseOptions{Method: DenoiseNLMeans, Radius: MaxNLMeansRadius}, wantErr: true},
+		{name: "Non-local means too many pixels", size: image.Pt(8000, 6000), opts: DenoiseOptions{Method: DenoiseNLMeans, Radius: 1}, wantErr: true},
+		{name: "Small median window", size: image.Pt(8000, 6000), opts: DenoiseOptions{Method: DenoiseMedian, Radius: 1}},
+		{name: "Largest median window", size: image.Pt(4000, 3000), opts: DenoiseOptions{Method: DenoiseMedian, Radius: MaxMedianRadius}},
+		{name: "Largest median window on a large image", size: image.Pt(8000, 6000), opts: DenoiseOptions{Method: DenoiseMedian, Radius: MaxMedianRadius}, wantErr: true},
+		{name: "Bilateral defaults", size: image.Pt(4000, 3000), opts: DenoiseOptions{Method: DenoiseBilateral, SpatialSigma: 3}},
+		{name: "Largest bilateral window", size: image.Pt(4000, 3000), opts: DenoiseOptions{Method: DenoiseBilateral, SpatialSigma: MaxBilateralSigma}, wantErr: true},
+		{name: "Largest bilateral window on a small image", size: image.Pt(1000, 1000), opts: DenoiseOptions{Method: DenoiseBilateral, SpatialSigma: MaxBilateralSigma}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkDenoiseCost(tc.size, tc.opts)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrImageTooLarge)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
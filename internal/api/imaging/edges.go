@@ -0,0 +1,290 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+)
+
+type EdgeMethod int
+
+const (
+	// EdgeSobel and EdgePrewitt report the strength of the brightness
+	// gradient at every pixel.
+	EdgeSobel EdgeMethod = iota
+	EdgePrewitt
+	// EdgeLaplacian is the Laplacian of Gaussian, which responds to both
+	// sides of an edge and is less sensitive to its direction.
+	EdgeLaplacian
+	// EdgeCanny thins gradients to one pixel wide lines and links them with
+	// hysteresis. Its output is always binary.
+	EdgeCanny
+)
+
+var edgeMethods = map[string]EdgeMethod{
+	"sobel":     EdgeSobel,
+	"prewitt":   EdgePrewitt,
+	"laplacian": EdgeLaplacian,
+	"canny":     EdgeCanny,
+}
+
+// EdgeMethodFromName looks up an edge detection method by name.
+func EdgeMethodFromName(name string) (EdgeMethod, error) {
+	method, ok := edgeMethods[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown edge detection method %q", name)
+	}
+
+	return method, nil
+}
+
+func EdgeMethodName(method EdgeMethod) string {
+	for name, m := range edgeMethods {
+		if m == method {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// EdgeMethodNames returns the edge detection methods in sorted order.
+func EdgeMethodNames() []string {
+	names := make([]string, 0, len(edgeMethods))
+	for name := range edgeMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+const (
+	defaultEdgeSigma = 1.4
+	defaultCannyLow  = 20
+	defaultCannyHigh = 50
+	edgeForeground   = 255
+	edgeBackground   = 0
+)
+
+// Edge strengths are scaled so a full black to white step along one axis
+// measures 255, which keeps thresholds on the same scale as pixel values.
+
+type EdgeOptions struct {
+	Method EdgeMethod
+	// Sigma smooths the image before detection, which suppresses edges from
+	// noise. The Laplacian and Canny need some smoothing and use 1.4 when it
+	// is 0.
+	Sigma float64
+	// Binary turns the output into white edges on black, keeping pixels whose
+	// strength is at least Threshold. Canny output is always binary.
+	Binary    bool
+	Threshold float64
+	// Low and High are the Canny hysteresis thresholds: pixels above High
+	// start an edge, which then continues through pixels above Low. They
+	// default to 20 and 50 when High is 0.
+	Low  float64
+	High float64
+}
+
+// DetectEdges returns a grayscale image of the edges in img.
+func (i *ImagingImpl) DetectEdges(img image.Image, opts EdgeOptions) (*image.Gray, error) {
+	sigma := opts.Sigma
+	if sigma == 0 && (opts.Method == EdgeLaplacian || opts.Method == EdgeCanny) {
+		sigma = defaultEdgeSigma
+	}
+
+	src := imaging.Clone(img)
+	if sigma > 0 {
+		src = imaging.Blur(src, sigma)
+	}
+	luma := newLumaPlane(src)
+
+	var strength []float64
+	switch opts.Method {
+	case EdgeSobel:
+		strength, _ = luma.gradient(sobelX, sobelY, 4)
+	case EdgePrewitt:
+		strength, _ = luma.gradient(prewittX, prewittY, 3)
+	case EdgeLaplacian:
+		strength = luma.laplacian()
+	case EdgeCanny:
+		low, high := opts.Low, opts.High
+		if high == 0 {
+			low, high = defaultCannyLow, defaultCannyHigh
+		}
+		if low > high {
+			return nil, fmt.Errorf("imaging: canny low threshold %v is above high threshold %v", low, high)
+		}
+		return luma.canny(low, high), nil
+	default:
+		return nil, fmt.Errorf("imaging: unknown edge detection method %d", opts.Method)
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, luma.width, luma.height))
+	for idx, value := range strength {
+		switch {
+		case !opts.Binary:
+			dst.Pix[idx] = clampUint8(value)
+		case value >= opts.Threshold:
+			dst.Pix[idx] = edgeForeground
+		default:
+			dst.Pix[idx] = edgeBackground
+		}
+	}
+
+	return dst, nil
+}
+
+var (
+	sobelX   = [9]float64{-1, 0, 1, -2, 0, 2, -1, 0, 1}
+	sobelY   = [9]float64{-1, -2, -1, 0, 0, 0, 1, 2, 1}
+	prewittX = [9]float64{-1, 0, 1, -1, 0, 1, -1, 0, 1}
+	prewittY = [9]float64{-1, -1, -1, 0, 0, 0, 1, 1, 1}
+)
+
+// lumaPlane holds the luminance of an image as floats, row by row.
+type lumaPlane struct {
+	width, height int
+	values        []float64
+}
+
+func newLumaPlane(img *image.NRGBA) lumaPlane {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	plane := lumaPlane{width: width, height: height, values: make([]float64, width*height)}
+
+	parallelRows(height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				plane.values[y*width+x] = luminance(img.Pix[y*img.Stride+x*4:])
+			}
+		}
+	})
+
+	return plane
+}
+
+// at returns the value at (x, y), extending the edge pixels outwards.
+func (p lumaPlane) at(x, y int) float64 {
+	return p.values[clampInt(y, 0, p.height-1)*p.width+clampInt(x, 0, p.width-1)]
+}
+
+// gradient returns the gradient magnitude and direction in radians at every
+// pixel, with the magnitude divided by scale.
+func (p lumaPlane) gradient(kx, ky [9]float64, scale float64) ([]float64, []float64) {
+	magnitude := make([]float64, len(p.values))
+	direction := make([]float64, len(p.values))
+
+	parallelRows(p.height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < p.width; x++ {
+				var gx, gy float64
+				for k := 0; k < 9; k++ {
+					value := p.at(x+k%3-1, y+k/3-1)
+					gx += kx[k] * value
+					gy += ky[k] * value
+				}
+
+				idx := y*p.width + x
+				magnitude[idx] = math.Hypot(gx, gy) / scale
+				direction[idx] = math.Atan2(gy, gx)
+			}
+		}
+	})
+
+	return magnitude, direction
+}
+
+// laplacian returns the absolute response of the 4-neighbour Laplacian.
+func (p lumaPlane) laplacian() []float64 {
+	response := make([]float64, len(p.values))
+
+	parallelRows(p.height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < p.width; x++ {
+				sum := p.at(x-1, y) + p.at(x+1, y) + p.at(x, y-1) + p.at(x, y+1) - 4*p.at(x, y)
+				response[y*p.width+x] = math.Abs(sum)
+			}
+		}
+	})
+
+	return response
+}
+
+func (p lumaPlane) canny(low, high float64) *image.Gray {
+	magnitude, direction := p.gradient(sobelX, sobelY, 4)
+
+	// keep only pixels that are the strongest across the edge
+	thin := make([]float64, len(magnitude))
+	parallelRows(p.height, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < p.width; x++ {
+				idx := y*p.width + x
+				dx, dy := gradientNeighbour(direction[idx])
+
+				before := magnitudeAt(magnitude, p.width, p.height, x-dx, y-dy)
+				after := magnitudeAt(magnitude, p.width, p.height, x+dx, y+dy)
+				// ties go to the earlier pixel so plateaus stay one pixel wide
+				if magnitude[idx] >= before && magnitude[idx] > after {
+					thin[idx] = magnitude[idx]
+				}
+			}
+		}
+	})
+
+	// hysteresis: grow edges from strong pixels through weak ones
+	dst := image.NewGray(image.Rect(0, 0, p.width, p.height))
+	stack := make([]int, 0)
+	for idx, value := range thin {
+		if value >= high {
+			dst.Pix[idx] = edgeForeground
+			stack = append(stack, idx)
+		}
+	}
+
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		x, y := idx%p.width, idx/p.width
+
+		for ny := max(y-1, 0); ny <= min(y+1, p.height-1); ny++ {
+			for nx := max(x-1, 0); nx <= min(x+1, p.width-1); nx++ {
+				next := ny*p.width + nx
+				if dst.Pix[next] == edgeBackground && thin[next] >= low && thin[next] > 0 {
+					dst.Pix[next] = edgeForeground
+					stack = append(stack, next)
+				}
+			}
+		}
+	}
+
+	return dst
+}
+
+// gradientNeighbour rounds a gradient direction to one of the four pixel
+// neighbour axes.
+func gradientNeighbour(angle float64) (int, int) {
+	degrees := math.Mod(angle*180/math.Pi+180, 180)
+
+	switch {
+	case degrees < 22.5 || degrees >= 157.5:
+		return 1, 0
+	case degrees < 67.5:
+		return 1, 1
+	case degrees < 112.5:
+		return 0, 1
+	default:
+		return -1, 1
+	}
+}
+
+func magnitudeAt(magnitude []float64, width, height, x, y int) float64 {
+	if x < 0 || y < 0 || x >= width || y >= height {
+		return 0
+	}
+
+	return magnitude[y*width+x]
+}
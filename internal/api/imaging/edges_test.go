@@ -0,0 +1,72 @@
+package imaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectEdges(t *testing.T) {
+	im := &ImagingImpl{}
+	src := edgeImage()
+
+	testcases := []struct{
+		name string
+		opts EdgeOptions
+		check func(t *testing.T, pix func(x, y int) uint8)
+	}{
+		{
+			name: "Sobel responds at the edge only",
+			opts: EdgeOptions{Method: EdgeSobel},
+			check: func(t *testing.T, pix func(x, y int) uint8) {
+				assert.Greater(t, pix(4, 4), uint8(50))
+				assert.Equal(t, uint8(0), pix(0, 4))
+			},
+		},
+		{
+			name: "Threshold makes Prewitt output binary",
+			opts: EdgeOptions{Method: EdgePrewitt, Binary: true, Threshold: 10},
+			check: func(t *testing.T, pix func(x, y int) uint8) {
+				assert.Equal(t, uint8(255), pix(4, 4))
+				assert.Equal(t, uint8(0), pix(0, 4))
+			},
+		},
+		{
+			name: "Laplacian smooths by default",
+			opts: EdgeOptions{Method: EdgeLaplacian},
+			check: func(t *testing.T, pix func(x, y int) uint8) {
+				assert.Greater(t, pix(3, 4), pix(0, 4))
+				assert.Greater(t, pix(4, 4), pix(7, 4))
+			},
+		},
+		{
+			name: "Canny draws a single line along the edge",
+			opts: EdgeOptions{Method: EdgeCanny, Low: 5, High: 10},
+			check: func(t *testing.T, pix func(x, y int) uint8) {
+				for y := 0; y < 8; y++ {
+					lit := 0
+					for x := 0; x < 8; x++ {
+						if pix(x, y) == 255 {
+							lit++
+						}
+					}
+					assert.Equal(t, 1, lit, "row %d", y)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := im.DetectEdges(src, tc.opts)
+			require.NoError(t, err)
+			tc.check(t, func(x, y int) uint8 { return out.GrayAt(x, y).Y })
+		})
+	}
+}
+
+func TestDetectEdgesCannyThresholdOrder(t *testing.T) {
+	_, err := (&ImagingImpl{}).DetectEdges(edgeImage(), EdgeOptions{Method: EdgeCanny, Low: 40, High: 10})
+	assert.Error(t, err)
+}
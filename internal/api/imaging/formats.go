@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+type Format = imaging.Format
+
+const (
+	JPEG = imaging.JPEG
+	PNG  = imaging.PNG
+	GIF  = imaging.GIF
+	TIFF = imaging.TIFF
+	BMP  = imaging.BMP
+)
+
+var ErrUnsupportedFormat = imaging.ErrUnsupportedFormat
+
+type formatInfo struct {
+	name        string
+	extension   string
+	contentType string
+	alpha       bool
+}
+
+// formats lists every format the service can encode, in no particular order.
+var formats = map[Format]formatInfo{
+	JPEG: {"jpeg", ".jpg", "image/jpeg", false},
+	PNG:  {"png", ".png", "image/png", true},
+	GIF:  {"gif", ".gif", "image/gif", true},
+	TIFF: {"tiff", ".tiff", "image/tiff", true},
+	// BMP can hold alpha, but few readers honour it
+	BMP: {"bmp", ".bmp", "image/bmp", false},
+}
+
+// FormatFromName parses a short format name such as "png" or "jpg".
+func FormatFromName(name string) (Format, error) {
+	return imaging.FormatFromExtension(strings.ToLower(name))
+}
+
+// FormatFromPath returns the format implied by a file's extension.
+func FormatFromPath(path string) (Format, error) {
+	return imaging.FormatFromFilename(path)
+}
+
+func FormatName(format Format) string {
+	return formats[format].name
+}
+
+func Extension(format Format) string {
+	return formats[format].extension
+}
+
+// SupportsAlpha reports whether images keep their transparency when encoded
+// in format.
+func SupportsAlpha(format Format) bool {
+	return formats[format].alpha
+}
+
+func ContentType(format Format) string {
+	return formats[format].contentType
+}
+
+// SupportedFormats returns every format the service can encode.
+func SupportedFormats() []Format {
+	supported := make([]Format, 0, len(formats))
+	for format := range formats {
+		supported = append(supported, format)
+	}
+	sort.Slice(supported, func(a, b int) bool {
+		return supported[a] < supported[b]
+	})
+
+	return supported
+}
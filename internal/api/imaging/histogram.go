@@ -0,0 +1,154 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// MaxHistogramBins is the finest histogram, one bin per 8-bit value.
+const MaxHistogramBins = 256
+
+type ChannelStats struct {
+	// Histogram counts pixels per bin, bins splitting 0-255 evenly.
+	Histogram []int   `json:"histogram"`
+	Mean      float64 `json:"mean"`
+	Median    uint8   `json:"median"`
+	StdDev    float64 `json:"stdDev"`
+	Min       uint8   `json:"min"`
+	Max       uint8   `json:"max"`
+	// ClippedShadows and ClippedHighlights are the percentages of pixels at
+	// 0 and 255.
+	ClippedShadows    float64 `json:"clippedShadows"`
+	ClippedHighlights float64 `json:"clippedHighlights"`
+
+	counts [256]int
+}
+
+// Histogram describes the distribution of values in an image. Fully
+// transparent pixels are left out.
+type Histogram struct {
+	Bins      int          `json:"bins"`
+	Pixels    int          `json:"pixels"`
+	Red       ChannelStats `json:"red"`
+	Green     ChannelStats `json:"green"`
+	Blue      ChannelStats `json:"blue"`
+	Luminance ChannelStats `json:"luminance"`
+}
+
+func NewHistogram(img image.Image, bins int) (*Histogram, error) {
+	if bins < 1 || bins > MaxHistogramBins {
+		return nil, fmt.Errorf("imaging: bins must be between 1 and %d", MaxHistogramBins)
+	}
+
+	src := imaging.Clone(img)
+	hist := &Histogram{Bins: bins}
+	channels := hist.channels()
+
+	for idx := 0; idx < len(src.Pix); idx += 4 {
+		px := src.Pix[idx : idx+4]
+		if px[3] == 0 {
+			continue
+		}
+
+		hist.Pixels++
+		hist.Red.counts[px[0]]++
+		hist.Green.counts[px[1]]++
+		hist.Blue.counts[px[2]]++
+		hist.Luminance.counts[clampUint8(luminance(px))]++
+	}
+
+	for _, channel := range channels {
+		channel.summarize(hist.Pixels, bins)
+	}
+
+	return hist, nil
+}
+
+func (h *Histogram) channels() []*ChannelStats {
+	return []*ChannelStats{&h.Red, &h.Green, &h.Blue, &h.Luminance}
+}
+
+func (c *ChannelStats) summarize(pixels, bins int) {
+	c.Histogram = make([]int, bins)
+	for value, count := range c.counts {
+		c.Histogram[value*bins/256] += count
+	}
+
+	if pixels == 0 {
+		return
+	}
+
+	var sum, sumSquares float64
+	seen, minSet := 0, false
+	for value, count := range c.counts {
+		if count == 0 {
+			continue
+		}
+		if !minSet {
+			c.Min, minSet = uint8(value), true
+		}
+		c.Max = uint8(value)
+
+		if seen < (pixels+1)/2 && seen+count >= (pixels+1)/2 {
+			c.Median = uint8(value)
+		}
+		seen += count
+
+		sum += float64(value * count)
+		sumSquares += float64(value * value * count)
+	}
+
+	c.Mean = sum / float64(pixels)
+	c.StdDev = math.Sqrt(max(0, sumSquares/float64(pixels)-c.Mean*c.Mean))
+	c.ClippedShadows = 100 * float64(c.counts[0]) / float64(pixels)
+	c.ClippedHighlights = 100 * float64(c.counts[255]) / float64(pixels)
+}
+
+// Render draws the histogram as a chart of the given size, the red, green
+// and blue channels overlapping additively with luminance as a white line.
+func (h *Histogram) Render(width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	peak := 1
+	for _, channel := range h.channels() {
+		for _, count := range channel.Histogram {
+			peak = max(peak, count)
+		}
+	}
+
+	// barHeight scales a bin to the chart, reaching the top at the peak
+	barHeight := func(channel *ChannelStats, x int) int {
+		count := channel.Histogram[x*h.Bins/width]
+		return int(math.Round(float64(count) / float64(peak) * float64(height)))
+	}
+
+	for x := 0; x < width; x++ {
+		red, green, blue := barHeight(&h.Red, x), barHeight(&h.Green, x), barHeight(&h.Blue, x)
+		luma := barHeight(&h.Luminance, x)
+
+		for y := 0; y < height; y++ {
+			level := height - y
+			px := dst.Pix[y*dst.Stride+x*4:]
+			px[3] = 255
+
+			if level == max(luma, 1) {
+				px[0], px[1], px[2] = 255, 255, 255
+				continue
+			}
+			if level <= red {
+				px[0] = 200
+			}
+			if level <= green {
+				px[1] = 200
+			}
+			if level <= blue {
+				px[2] = 200
+			}
+		}
+	}
+
+	return dst
+}
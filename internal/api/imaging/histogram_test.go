@@ -0,0 +1,52 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHistogram(t *testing.T) {
+	// 8x8 edge image plus one transparent pixel that must be ignored
+	src := edgeImage()
+	src.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 0})
+
+	hist, err := NewHistogram(src, 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, 63, hist.Pixels)
+	assert.Equal(t, []int{0, 31, 32, 0}, hist.Red.Histogram)
+	assert.Equal(t, uint8(80), hist.Red.Min)
+	assert.Equal(t, uint8(160), hist.Red.Max)
+	assert.Equal(t, uint8(160), hist.Red.Median)
+	assert.InDelta(t, (31*80+32*160)/63.0, hist.Red.Mean, 0.001)
+	assert.InDelta(t, 40, hist.Red.StdDev, 0.5)
+	assert.Zero(t, hist.Red.ClippedHighlights)
+}
+
+func TestNewHistogramClipping(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{A: 255})
+	src.SetNRGBA(0, 1, color.NRGBA{A: 255})
+	src.SetNRGBA(1, 1, color.NRGBA{R: 128, G: 128, B: 128, A: 255})
+
+	hist, err := NewHistogram(src, 256)
+	require.NoError(t, err)
+
+	assert.Equal(t, 25.0, hist.Luminance.ClippedHighlights)
+	assert.Equal(t, 50.0, hist.Luminance.ClippedShadows)
+
+	chart := hist.Render(64, 32)
+	assert.Equal(t, image.Rect(0, 0, 64, 32), chart.Bounds())
+}
+
+func TestNewHistogramRejectsBins(t *testing.T) {
+	_, err := NewHistogram(edgeImage(), 0)
+	assert.Error(t, err)
+	_, err = NewHistogram(edgeImage(), 512)
+	assert.Error(t, err)
+}
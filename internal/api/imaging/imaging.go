@@ -1,7 +1,10 @@
 package imaging
 
 import (
+	"fmt"
 	"image"
+	"io"
+	"os"
 
 	"github.com/disintegration/imaging"
 )
@@ -11,18 +14,67 @@ type Imaging interface {
 	Blur(img image.Image, sigma float64) *image.NRGBA
 	Save(img image.Image, path string) error
 	Sharpen(img image.Image, sigma float64) image.Image
+	UnsharpMask(img image.Image, opts UnsharpMaskOptions) *image.NRGBA
+	Convolve(img image.Image, kernel Kernel, opts ConvolveOptions) (*image.NRGBA, error)
+	DetectEdges(img image.Image, opts EdgeOptions) (*image.Gray, error)
+	Denoise(img image.Image, opts DenoiseOptions) (*image.NRGBA, error)
+	AutoLevels(img image.Image, opts AutoLevelsOptions) (*image.NRGBA, error)
+	Equalize(img image.Image) *image.NRGBA
+	CLAHE(img image.Image, opts CLAHEOptions) (*image.NRGBA, error)
+	Levels(img image.Image, channel Channel, levels Levels) (*image.NRGBA, error)
+	Curves(img image.Image, channel Channel, curve Curve) (*image.NRGBA, error)
+	ApplyChannelLUTs(img image.Image, luts ChannelLUTs) *image.NRGBA
+	ApplyCubeLUT(img image.Image, lut *CubeLUT, opts CubeLUTOptions) (*image.NRGBA, error)
+	Overlay(base, overlay image.Image, opts OverlayOptions) (*image.NRGBA, error)
+	DrawText(img image.Image, opts TextOptions) (*image.NRGBA, error)
+	DrawShapes(img image.Image, shapes []Shape) (*image.NRGBA, error)
+	Redact(img image.Image, opts RedactOptions) (*image.NRGBA, error)
+	BlendMask(original, filtered image.Image, opts MaskOptions) (*image.NRGBA, error)
+	ExtendCanvas(img image.Image, insets Insets, fill CanvasFill) (*image.NRGBA, error)
+	Resize(img image.Image, width, height int, mode ResizeMode) (*image.NRGBA, error)
+	Encode(w io.Writer, img image.Image, format Format) error
 }
 
+type ResizeMode int
+
+const (
+	// ResizeFit scales the image down to fit within the box, keeping its
+	// aspect ratio. Images already inside the box are left as they are.
+	ResizeFit ResizeMode = iota
+	// ResizeFill scales and crops the image to cover the box exactly.
+	ResizeFill
+	// ResizeStretch scales the image to the box, ignoring its aspect ratio.
+	ResizeStretch
+)
+
 type ImagingImpl struct {
 	src image.Image
+	limits Limits
 }
 
-func NewImaging() Imaging {
-	return &ImagingImpl{}
+func NewImaging(limits Limits) Imaging {
+	return &ImagingImpl{
+		limits: limits,
+	}
 }
 
 func (i *ImagingImpl) Open(path string) (image.Image, error) {
-	src, err := imaging.Open(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// check the declared dimensions before decoding any pixels
+	if err := i.limits.CheckHeader(file); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	src, err := imaging.Decode(file)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +89,81 @@ func (i *ImagingImpl) Blur(img image.Image, sigma float64) *image.NRGBA {
 }
 
 func (i *ImagingImpl) Save(img image.Image, path string) error {
+	bounds := img.Bounds()
+	if err := i.limits.Check(bounds.Dx(), bounds.Dy()); err != nil {
+		return err
+	}
+
 	return imaging.Save(img, path)
 }
 
 func (i *ImagingImpl) Sharpen(image image.Image, sigma float64) image.Image {
 	return imaging.Sharpen(image, sigma)
 }
+
+func (i *ImagingImpl) Resize(img image.Image, width, height int, mode ResizeMode) (*image.NRGBA, error) {
+	outWidth, outHeight, err := resizeDimensions(img.Bounds(), width, height, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// check before the output buffer is allocated
+	if err := i.limits.Check(outWidth, outHeight); err != nil {
+		return nil, err
+	}
+
+	if width == 0 || height == 0 {
+		return imaging.Resize(img, width, height, imaging.Lanczos), nil
+	}
+
+	switch mode {
+	case ResizeFill:
+		return imaging.Fill(img, width, height, imaging.Center, imaging.Lanczos), nil
+	case ResizeStretch:
+		return imaging.Resize(img, width, height, imaging.Lanczos), nil
+	default:
+		return imaging.Fit(img, width, height, imaging.Lanczos), nil
+	}
+}
+
+func (i *ImagingImpl) Encode(w io.Writer, img image.Image, format Format) error {
+	bounds := img.Bounds()
+	if err := i.limits.Check(bounds.Dx(), bounds.Dy()); err != nil {
+		return err
+	}
+
+	return imaging.Encode(w, img, format)
+}
+
+// resizeDimensions works out the size Resize will produce. A zero width or
+// height is derived from the other one, preserving the aspect ratio.
+func resizeDimensions(bounds image.Rectangle, width, height int, mode ResizeMode) (int, int, error) {
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+
+	if width < 0 || height < 0 || (width == 0 && height == 0) {
+		return 0, 0, fmt.Errorf("imaging: invalid resize dimensions %dx%d", width, height)
+	}
+	if srcWidth <= 0 || srcHeight <= 0 {
+		return 0, 0, fmt.Errorf("imaging: cannot resize empty image")
+	}
+
+	switch {
+	case width == 0:
+		width = max(1, int(float64(srcWidth)*float64(height)/float64(srcHeight)+0.5))
+		return width, height, nil
+	case height == 0:
+		height = max(1, int(float64(srcHeight)*float64(width)/float64(srcWidth)+0.5))
+		return width, height, nil
+	}
+
+	if mode == ResizeFit {
+		if srcWidth <= width && srcHeight <= height {
+			return srcWidth, srcHeight, nil
+		}
+
+		scale := min(float64(width)/float64(srcWidth), float64(height)/float64(srcHeight))
+		return max(1, int(float64(srcWidth)*scale+0.5)), max(1, int(float64(srcHeight)*scale+0.5)), nil
+	}
+
+	return width, height, nil
+}
@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+var ErrImageTooLarge = errors.New("imaging: image exceeds size limits")
+
+// Limits bounds the dimensions of images the service is willing to decode or
+// produce. A zero value for any field disables that particular check.
+type Limits struct {
+	MaxWidth  int
+	MaxHeight int
+	MaxPixels int64
+}
+
+// Check reports whether an image of the given dimensions is within the limits.
+func (l Limits) Check(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("imaging: invalid image dimensions %dx%d", width, height)
+	}
+	if l.MaxWidth > 0 && width > l.MaxWidth {
+		return fmt.Errorf("%w: width %d exceeds %d", ErrImageTooLarge, width, l.MaxWidth)
+	}
+	if l.MaxHeight > 0 && height > l.MaxHeight {
+		return fmt.Errorf("%w: height %d exceeds %d", ErrImageTooLarge, height, l.MaxHeight)
+	}
+	if l.MaxPixels > 0 && int64(width)*int64(height) > l.MaxPixels {
+		return fmt.Errorf("%w: %d pixels exceeds %d", ErrImageTooLarge, int64(width)*int64(height), l.MaxPixels)
+	}
+
+	return nil
+}
+
+// CheckHeader reads only the image header from r and checks the declared
+// dimensions, so oversized images are rejected before any pixel data is
+// decoded.
+func (l Limits) CheckHeader(r io.Reader) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return err
+	}
+
+	return l.Check(cfg.Width, cfg.Height)
+}
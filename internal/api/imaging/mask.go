@@ -0,0 +1,254 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// MaskKind selects the shape of a mask.
+type MaskKind int
+
+const (
+	// MaskRect and MaskEllipse are full strength inside Rect, fading out
+	// over Feather pixels centred on its edge.
+	MaskRect MaskKind = iota
+	MaskEllipse
+	// MaskRadial fades from full strength at Center to nothing at Radius.
+	MaskRadial
+	// MaskLinear fades from nothing at From to full strength at To.
+	MaskLinear
+	// MaskImage takes its strength from the brightness of Image, stretched
+	// to the size of the masked image.
+	MaskImage
+)
+
+var maskKinds = map[string]MaskKind{
+	"rect":    MaskRect,
+	"ellipse": MaskEllipse,
+	"radial":  MaskRadial,
+	"linear":  MaskLinear,
+	"image":   MaskImage,
+}
+
+// MaskKindFromName looks up a kind of mask by name.
+func MaskKindFromName(name string) (MaskKind, error) {
+	kind, ok := maskKinds[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown mask %q", name)
+	}
+
+	return kind, nil
+}
+
+func MaskKindName(kind MaskKind) string {
+	for name, k := range maskKinds {
+		if k == kind {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// MaxMaskFeather bounds the feathering of rectangle and ellipse masks.
+const MaxMaskFeather = 1000
+
+// MaskOptions describe where an effect applies. Which fields are used
+// depends on Kind.
+type MaskOptions struct {
+	Kind    MaskKind
+	Rect    image.Rectangle
+	Feather float64
+	Center  Point
+	Radius  float64
+	From    Point
+	To      Point
+	Image   image.Image
+	// Invert applies the effect where the mask would otherwise leave the
+	// image alone.
+	Invert bool
+}
+
+func (o MaskOptions) Validate() error {
+	switch o.Kind {
+	case MaskRect, MaskEllipse:
+		if o.Rect.Empty() {
+			return fmt.Errorf("imaging: mask rectangle is empty")
+		}
+		if !(o.Feather >= 0 && o.Feather <= MaxMaskFeather) {
+			return fmt.Errorf("imaging: feather must be between 0 and %d", MaxMaskFeather)
+		}
+	case MaskRadial:
+		if !(o.Radius > 0) || math.IsInf(o.Radius, 0) {
+			return fmt.Errorf("imaging: mask radius must be positive")
+		}
+		if !o.Center.finite() {
+			return fmt.Errorf("imaging: invalid point")
+		}
+	case MaskLinear:
+		if !o.From.finite() || !o.To.finite() {
+			return fmt.Errorf("imaging: invalid point")
+		}
+		if o.From == o.To {
+			return fmt.Errorf("imaging: linear mask needs two different points")
+		}
+	case MaskImage:
+		if o.Image == nil || o.Image.Bounds().Empty() {
+			return fmt.Errorf("imaging: mask image is missing")
+		}
+	default:
+		return fmt.Errorf("imaging: unknown mask")
+	}
+
+	return nil
+}
+
+// BlendMask mixes filtered into original by the strength of the mask: where
+// it is full the filtered pixel is used, where it is empty the original one
+// is kept, and in between they are mixed. Both images must be the same size.
+func (i *ImagingImpl) BlendMask(original, filtered image.Image, opts MaskOptions) (*image.NRGBA, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	src := imaging.Clone(original)
+	dst := imaging.Clone(filtered)
+	if src.Rect.Size() != dst.Rect.Size() {
+		return nil, fmt.Errorf("imaging: masked operations must keep the image size")
+	}
+
+	mask := buildMask(src.Rect.Size(), opts)
+
+	parallelRows(dst.Rect.Dy(), func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < dst.Rect.Dx(); x++ {
+				m := float64(mask.Pix[y*mask.Stride+x]) / 255
+				if opts.Invert {
+					m = 1 - m
+				}
+				offset := y*dst.Stride + x*4
+				mixPixel(dst.Pix[offset:offset+4], src.Pix[offset:offset+4], m)
+			}
+		}
+	})
+
+	return dst, nil
+}
+
+// mixPixel replaces filtered with the mix of original and filtered in
+// proportion m, in premultiplied alpha so transparent pixels do not bleed
+// their colour.
+func mixPixel(filtered, original []uint8, m float64) {
+	if m >= 1 {
+		return
+	}
+	if m <= 0 {
+		copy(filtered, original)
+		return
+	}
+
+	fa, oa := float64(filtered[3]), float64(original[3])
+	alpha := oa + (fa-oa)*m
+	if alpha == 0 {
+		copy(filtered, original)
+		return
+	}
+
+	for c := 0; c < 3; c++ {
+		premultiplied := float64(original[c])*oa + (float64(filtered[c])*fa-float64(original[c])*oa)*m
+		filtered[c] = clampUint8(premultiplied / alpha)
+	}
+	filtered[3] = clampUint8(alpha)
+}
+
+// buildMask renders the strength of the mask at every pixel of an image of
+// the given size, sampling at pixel centres.
+func buildMask(size image.Point, opts MaskOptions) *image.Gray {
+	if opts.Kind == MaskImage {
+		resized := imaging.Resize(opts.Image, size.X, size.Y, imaging.Linear)
+		mask := image.NewGray(image.Rect(0, 0, size.X, size.Y))
+		for idx := 0; idx < len(mask.Pix); idx++ {
+			px := resized.Pix[idx*4 : idx*4+4]
+			// transparent parts of the mask image leave the image alone
+			mask.Pix[idx] = clampUint8(luminance(px) * float64(px[3]) / 255)
+		}
+		return mask
+	}
+
+	strength := maskFunc(opts)
+	mask := image.NewGray(image.Rect(0, 0, size.X, size.Y))
+	parallelRows(size.Y, func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < size.X; x++ {
+				mask.Pix[y*mask.Stride+x] = clampUint8(255 * strength(float64(x)+0.5, float64(y)+0.5))
+			}
+		}
+	})
+
+	return mask
+}
+
+// maskFunc returns the strength, from 0 to 1, of a geometric mask at a
+// point.
+func maskFunc(opts MaskOptions) func(x, y float64) float64 {
+	switch opts.Kind {
+	case MaskRadial:
+		return func(x, y float64) float64 {
+			return clamp01(1 - math.Hypot(x-opts.Center.X, y-opts.Center.Y)/opts.Radius)
+		}
+	case MaskLinear:
+		dx, dy := opts.To.X-opts.From.X, opts.To.Y-opts.From.Y
+		lengthSq := dx*dx + dy*dy
+		return func(x, y float64) float64 {
+			return clamp01(((x-opts.From.X)*dx + (y-opts.From.Y)*dy) / lengthSq)
+		}
+	case MaskEllipse:
+		rect := opts.Rect
+		cx, cy := float64(rect.Min.X+rect.Max.X)/2, float64(rect.Min.Y+rect.Max.Y)/2
+		rx, ry := float64(rect.Dx())/2, float64(rect.Dy())/2
+		return func(x, y float64) float64 {
+			nx, ny := (x-cx)/rx, (y-cy)/ry
+			r := math.Hypot(nx, ny)
+			if r == 0 {
+				return feathered(-math.Min(rx, ry), opts.Feather)
+			}
+			// distance to the outline, to first order, from the gradient of
+			// the normalized radius
+			gradient := math.Hypot(nx/(rx*r), ny/(ry*r))
+			return feathered((r-1)/gradient, opts.Feather)
+		}
+	default:
+		rect := opts.Rect
+		return func(x, y float64) float64 {
+			dx := math.Max(float64(rect.Min.X)-x, x-float64(rect.Max.X))
+			dy := math.Max(float64(rect.Min.Y)-y, y-float64(rect.Max.Y))
+			var distance float64
+			if dx > 0 || dy > 0 {
+				distance = math.Hypot(math.Max(dx, 0), math.Max(dy, 0))
+			} else {
+				distance = math.Max(dx, dy)
+			}
+			return feathered(distance, opts.Feather)
+		}
+	}
+}
+
+// feathered turns the signed distance from an outline, negative inside it,
+// into a strength that ramps over feather pixels centred on the outline.
+func feathered(distance, feather float64) float64 {
+	if feather == 0 {
+		if distance <= 0 {
+			return 1
+		}
+		return 0
+	}
+
+	return clamp01(0.5 - distance/feather)
+}
+
+func clamp01(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
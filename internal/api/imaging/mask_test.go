@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlendMask(t *testing.T) {
+	im := &ImagingImpl{}
+	white := color.NRGBA{255, 255, 255, 255}
+	black := color.NRGBA{0, 0, 0, 255}
+	original := solidImage(100, 100, white)
+	filtered := solidImage(100, 100, black)
+
+	gradient := image.NewGray(image.Rect(0, 0, 2, 1))
+	gradient.Pix[1] = 255
+
+	testcases := []struct{
+		name string
+		opts MaskOptions
+		// red value expected at each point
+		want map[image.Point]uint8
+	}{
+		{
+			name: "Hard rectangle",
+			opts: MaskOptions{Kind: MaskRect, Rect: image.Rect(20, 20, 60, 60)},
+			want: map[image.Point]uint8{{20, 20}: 0, {59, 59}: 0, {19, 40}: 255, {60, 40}: 255},
+		},
+		{
+			name: "Feathered rectangle",
+			opts: MaskOptions{Kind: MaskRect, Rect: image.Rect(20, 20, 60, 60), Feather: 20},
+			want: map[image.Point]uint8{{40, 40}: 0, {19, 40}: 134, {5, 40}: 255},
+		},
+		{
+			name: "Inverted ellipse",
+			opts: MaskOptions{Kind: MaskEllipse, Rect: image.Rect(0, 0, 100, 50), Invert: true},
+			want: map[image.Point]uint8{{50, 25}: 255, {99, 25}: 255, {50, 75}: 0, {2, 2}: 0},
+		},
+		{
+			name: "Radial gradient",
+			opts: MaskOptions{Kind: MaskRadial, Center: Point{50, 50}, Radius: 50},
+			want: map[image.Point]uint8{{50, 50}: 4, {74, 49}: 125, {99, 50}: 251, {0, 0}: 255},
+		},
+		{
+			name: "Linear gradient",
+			opts: MaskOptions{Kind: MaskLinear, From: Point{0, 0}, To: Point{100, 0}},
+			want: map[image.Point]uint8{{0, 10}: 254, {49, 10}: 127, {99, 90}: 1},
+		},
+		{
+			name: "Mask image",
+			opts: MaskOptions{Kind: MaskImage, Image: gradient},
+			want: map[image.Point]uint8{{0, 0}: 255, {99, 99}: 0},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst, err := im.BlendMask(original, filtered, tc.opts)
+			require.NoError(t, err)
+
+			for p, want := range tc.want {
+				assert.InDelta(t, want, dst.NRGBAAt(p.X, p.Y).R, 2, "pixel %v", p)
+			}
+		})
+	}
+}
+
+func TestBlendMaskSize(t *testing.T) {
+	im := &ImagingImpl{}
+	_, err := im.BlendMask(solidImage(10, 10, color.NRGBA{}), solidImage(5, 10, color.NRGBA{}), MaskOptions{Kind: MaskRadial, Radius: 4})
+	assert.Error(t, err)
+}
+
+func TestMaskOptionsValidate(t *testing.T) {
+	testcases := []struct{
+		name string
+		opts MaskOptions
+	}{
+		{name: "Empty rectangle", opts: MaskOptions{Kind: MaskRect}},
+		{name: "Negative feather", opts: MaskOptions{Kind: MaskEllipse, Rect: image.Rect(0, 0, 4, 4), Feather: -1}},
+		{name: "Feather not a number", opts: MaskOptions{Kind: MaskRect, Rect: image.Rect(0, 0, 4, 4), Feather: math.NaN()}},
+		{name: "Gradient from an invalid point", opts: MaskOptions{Kind: MaskLinear, From: Point{math.NaN(), 0}, To: Point{1, 1}}},
+		{name: "Zero radius", opts: MaskOptions{Kind: MaskRadial}},
+		{name: "Linear gradient without length", opts: MaskOptions{Kind: MaskLinear, From: Point{1, 1}, To: Point{1, 1}}},
+		{name: "Missing mask image", opts: MaskOptions{Kind: MaskImage}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, tc.opts.Validate())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Anchor places one image relative to the edges of another.
+type Anchor int
+
+const (
+	AnchorCenter Anchor = iota
+	AnchorTopLeft
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+var anchorNames = map[string]Anchor{
+	"center":       AnchorCenter,
+	"top-left":     AnchorTopLeft,
+	"top":          AnchorTop,
+	"top-right":    AnchorTopRight,
+	"left":         AnchorLeft,
+	"right":        AnchorRight,
+	"bottom-left":  AnchorBottomLeft,
+	"bottom":       AnchorBottom,
+	"bottom-right": AnchorBottomRight,
+}
+
+func AnchorFromName(name string) (Anchor, error) {
+	anchor, ok := anchorNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown anchor %q", name)
+	}
+
+	return anchor, nil
+}
+
+func AnchorName(anchor Anchor) string {
+	for name, a := range anchorNames {
+		if a == anchor {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// AnchorNames returns the names accepted by AnchorFromName.
+func AnchorNames() []string {
+	return []string{"center", "top-left", "top", "top-right", "left", "right", "bottom-left", "bottom", "bottom-right"}
+}
+
+// Position returns the top-left corner of an inner rectangle of the given
+// size anchored inside outer. Offsets move it away from the anchored edges,
+// or right and down for centred axes.
+func (a Anchor) Position(outer image.Rectangle, size image.Point, offsetX, offsetY int) image.Point {
+	x := outer.Min.X + (outer.Dx()-size.X)/2 + offsetX
+	switch a {
+	case AnchorTopLeft, AnchorLeft, AnchorBottomLeft:
+		x = outer.Min.X + offsetX
+	case AnchorTopRight, AnchorRight, AnchorBottomRight:
+		x = outer.Max.X - size.X - offsetX
+	}
+
+	y := outer.Min.Y + (outer.Dy()-size.Y)/2 + offsetY
+	switch a {
+	case AnchorTopLeft, AnchorTop, AnchorTopRight:
+		y = outer.Min.Y + offsetY
+	case AnchorBottomLeft, AnchorBottom, AnchorBottomRight:
+		y = outer.Max.Y - size.Y - offsetY
+	}
+
+	return image.Pt(x, y)
+}
+
+// BlendMode selects how overlay colours combine with the colours beneath.
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+)
+
+var blendModeNames = map[string]BlendMode{
+	"normal":   BlendNormal,
+	"multiply": BlendMultiply,
+	"screen":   BlendScreen,
+	"overlay":  BlendOverlay,
+}
+
+func BlendModeFromName(name string) (BlendMode, error) {
+	mode, ok := blendModeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown blend mode %q", name)
+	}
+
+	return mode, nil
+}
+
+func BlendModeName(mode BlendMode) string {
+	for name, m := range blendModeNames {
+		if m == mode {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// blend combines a backdrop and a source channel, both in [0, 1].
+func (m BlendMode) blend(backdrop, source float64) float64 {
+	switch m {
+	case BlendMultiply:
+		return backdrop * source
+	case BlendScreen:
+		return backdrop + source - backdrop*source
+	case BlendOverlay:
+		if backdrop <= 0.5 {
+			return 2 * backdrop * source
+		}
+		return 1 - 2*(1-backdrop)*(1-source)
+	default:
+		return source
+	}
+}
+
+// TileMode selects whether an overlay is placed once or repeated.
+type TileMode int
+
+const (
+	TileNone TileMode = iota
+	// TileRepeat covers the image with a grid of copies.
+	TileRepeat
+	// TileStagger shifts every other row by half a tile, the usual layout
+	// for watermarks that should be hard to crop out.
+	TileStagger
+)
+
+var tileModeNames = map[string]TileMode{
+	"none":    TileNone,
+	"repeat":  TileRepeat,
+	"stagger": TileStagger,
+}
+
+func TileModeFromName(name string) (TileMode, error) {
+	mode, ok := tileModeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown tiling mode %q", name)
+	}
+
+	return mode, nil
+}
+
+func TileModeName(mode TileMode) string {
+	for name, m := range tileModeNames {
+		if m == mode {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Limits for overlays.
+const (
+	MaxOverlayScale   = 1
+	MaxOverlayOffset  = 10000
+	MaxOverlaySpacing = 10000
+)
+
+// OverlayOptions configure Overlay. Scale sets the overlay width as a
+// fraction of the base width, keeping its aspect ratio; zero keeps its own
+// size. When tiling, the anchored copy fixes the grid, and Spacing is the
+// gap between copies.
+type OverlayOptions struct {
+	Anchor  Anchor
+	OffsetX int
+	OffsetY int
+	Scale   float64
+	Opacity float64
+	Tile    TileMode
+	Spacing int
+	Blend   BlendMode
+}
+
+func (o OverlayOptions) validate() error {
+	if o.Scale < 0 || o.Scale > MaxOverlayScale {
+		return fmt.Errorf("imaging: overlay scale must be between 0 and %d", MaxOverlayScale)
+	}
+	if o.Opacity < 0 || o.Opacity > 1 {
+		return fmt.Errorf("imaging: overlay opacity must be between 0 and 1")
+	}
+	if abs(o.OffsetX) > MaxOverlayOffset || abs(o.OffsetY) > MaxOverlayOffset {
+		return fmt.Errorf("imaging: overlay offsets must be at most %d", MaxOverlayOffset)
+	}
+	if o.Spacing < 0 || o.Spacing > MaxOverlaySpacing {
+		return fmt.Errorf("imaging: overlay spacing must be between 0 and %d", MaxOverlaySpacing)
+	}
+
+	return nil
+}
+
+// Overlay composites overlay onto base.
+func (i *ImagingImpl) Overlay(base, overlay image.Image, opts OverlayOptions) (*image.NRGBA, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	dst := imaging.Clone(base)
+	src := imaging.Clone(overlay)
+
+	if opts.Scale > 0 {
+		width := max(1, int(math.Round(float64(dst.Rect.Dx())*opts.Scale)))
+		src = imaging.Resize(src, width, 0, imaging.Lanczos)
+	}
+	if src.Rect.Empty() {
+		return dst, nil
+	}
+
+	size := src.Rect.Size()
+	origin := opts.Anchor.Position(dst.Rect, size, opts.OffsetX, opts.OffsetY)
+
+	if opts.Tile == TileNone {
+		compositeAt(dst, src, origin, opts)
+		return dst, nil
+	}
+
+	stepX, stepY := size.X+opts.Spacing, size.Y+opts.Spacing
+	// start from the copy at or before the top-left corner that lines up
+	// with the anchored one
+	firstRow := floorDiv(dst.Rect.Min.Y-origin.Y, stepY)
+	for row := firstRow; origin.Y+row*stepY < dst.Rect.Max.Y; row++ {
+		y := origin.Y + row*stepY
+		shift := 0
+		if opts.Tile == TileStagger && row%2 != 0 {
+			shift = stepX / 2
+		}
+		firstCol := floorDiv(dst.Rect.Min.X-origin.X-shift, stepX)
+		for col := firstCol; origin.X+shift+col*stepX < dst.Rect.Max.X; col++ {
+			compositeAt(dst, src, image.Pt(origin.X+shift+col*stepX, y), opts)
+		}
+	}
+
+	return dst, nil
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+
+	return q
+}
+
+// compositeAt draws src over dst with its top-left corner at origin, using
+// the source-over operator with the colours mixed by the blend mode.
+func compositeAt(dst, src *image.NRGBA, origin image.Point, opts OverlayOptions) {
+	area := src.Rect.Add(origin.Sub(src.Rect.Min)).Intersect(dst.Rect)
+	if area.Empty() {
+		return
+	}
+
+	parallelRows(area.Dy(), func(start, end int) {
+		for y := area.Min.Y + start; y < area.Min.Y+end; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				s := src.PixOffset(x-origin.X+src.Rect.Min.X, y-origin.Y+src.Rect.Min.Y)
+				d := dst.PixOffset(x, y)
+
+				sa := float64(src.Pix[s+3]) / 255 * opts.Opacity
+				if sa == 0 {
+					continue
+				}
+				da := float64(dst.Pix[d+3]) / 255
+				outA := sa + da*(1-sa)
+
+				for ch := range 3 {
+					sc := float64(src.Pix[s+ch]) / 255
+					dc := float64(dst.Pix[d+ch]) / 255
+					// where the backdrop is transparent the source shows as is
+					mixed := (1-da)*sc + da*opts.Blend.blend(dc, sc)
+					out := (sa*mixed + da*(1-sa)*dc) / outA
+					dst.Pix[d+ch] = clampUint8(out * 255)
+				}
+				dst.Pix[d+3] = clampUint8(outA * 255)
+			}
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solidImage(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+
+	return img
+}
+
+func TestAnchorPosition(t *testing.T) {
+	outer := image.Rect(0, 0, 100, 50)
+	size := image.Pt(10, 10)
+
+	testcases := []struct{
+		anchor Anchor
+		want image.Point
+	}{
+		{anchor: AnchorTopLeft, want: image.Pt(5, 2)},
+		{anchor: AnchorCenter, want: image.Pt(50, 22)},
+		{anchor: AnchorBottomRight, want: image.Pt(85, 38)},
+		{anchor: AnchorBottom, want: image.Pt(50, 38)},
+	}
+
+	for _, tc := range testcases {
+		t.Run(AnchorName(tc.anchor), func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.anchor.Position(outer, size, 5, 2))
+		})
+	}
+}
+
+func TestOverlayBlendModes(t *testing.T) {
+	im := &ImagingImpl{}
+	base := solidImage(4, 4, color.NRGBA{200, 100, 50, 255})
+	top := solidImage(2, 2, color.NRGBA{100, 100, 100, 255})
+
+	testcases := []struct{
+		blend BlendMode
+		want color.NRGBA
+	}{
+		{blend: BlendNormal, want: color.NRGBA{100, 100, 100, 255}},
+		{blend: BlendMultiply, want: color.NRGBA{78, 39, 20, 255}},
+		{blend: BlendScreen, want: color.NRGBA{222, 161, 130, 255}},
+		{blend: BlendOverlay, want: color.NRGBA{188, 78, 39, 255}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(BlendModeName(tc.blend), func(t *testing.T) {
+			dst, err := im.Overlay(base, top, OverlayOptions{Anchor: AnchorTopLeft, Opacity: 1, Blend: tc.blend})
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, dst.NRGBAAt(0, 0))
+			// pixels outside the overlay are untouched
+			assert.Equal(t, base.NRGBAAt(3, 3), dst.NRGBAAt(3, 3))
+		})
+	}
+}
+
+func TestOverlayOpacityAndScale(t *testing.T) {
+	im := &ImagingImpl{}
+	base := solidImage(20, 10, color.NRGBA{0, 0, 0, 255})
+	top := solidImage(4, 2, color.NRGBA{255, 255, 255, 255})
+
+	dst, err := im.Overlay(base, top, OverlayOptions{Anchor: AnchorBottomRight, Opacity: 0.5, Scale: 0.5})
+	require.NoError(t, err)
+
+	// scaled to half the base width, 10x5, in the bottom-right corner
+	assert.InDelta(t, 128, float64(dst.NRGBAAt(19, 9).R), 1)
+	assert.InDelta(t, 128, float64(dst.NRGBAAt(10, 5).R), 1)
+	assert.Equal(t, uint8(0), dst.NRGBAAt(9, 9).R)
+	assert.Equal(t, uint8(0), dst.NRGBAAt(19, 4).R)
+
+	// a transparent backdrop takes on the overlay's alpha
+	clear := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	dst, err = im.Overlay(clear, top, OverlayOptions{Opacity: 0.5, Blend: BlendMultiply})
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{255, 255, 255, 128}, dst.NRGBAAt(0, 0))
+
+	_, err = im.Overlay(base, top, OverlayOptions{Opacity: 2})
+	assert.Error(t, err)
+}
+
+func TestOverlayTiling(t *testing.T) {
+	im := &ImagingImpl{}
+	base := solidImage(10, 6, color.NRGBA{0, 0, 0, 255})
+	top := solidImage(2, 2, color.NRGBA{255, 255, 255, 255})
+
+	covered := func(dst *image.NRGBA) string {
+		var row []byte
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 10; x++ {
+				if dst.NRGBAAt(x, y).R == 255 {
+					row = append(row, '#')
+				} else {
+					row = append(row, '.')
+				}
+			}
+			row = append(row, '|')
+		}
+		return string(row)
+	}
+
+	dst, err := im.Overlay(base, top, OverlayOptions{Anchor: AnchorTopLeft, Opacity: 1, Tile: TileRepeat, Spacing: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "##.##.##.#|##.##.##.#|..........|##.##.##.#|##.##.##.#|..........|", covered(dst))
+
+	dst, err = im.Overlay(base, top, OverlayOptions{Anchor: AnchorTopLeft, Opacity: 1, Tile: TileStagger, Spacing: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "##..##..##|##..##..##|..........|..........|..##..##..|..##..##..|", covered(dst))
+}
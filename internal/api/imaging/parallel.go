@@ -0,0 +1,30 @@
+package imaging
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parallelRows splits rows [0, height) into contiguous bands and runs fn on
+// each band concurrently, returning once all of them are done.
+func parallelRows(height int, fn func(start, end int)) {
+	workers := min(runtime.GOMAXPROCS(0), height)
+	if workers <= 1 {
+		fn(0, height)
+		return
+	}
+
+	band := (height + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for start := 0; start < height; start += band {
+		end := min(start+band, height)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(start, end)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,238 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// RedactMode selects how redacted regions are obscured.
+type RedactMode int
+
+const (
+	// RedactFill replaces the regions with a solid colour.
+	RedactFill RedactMode = iota
+	// RedactPixelate replaces the regions with the average colour of square
+	// blocks.
+	RedactPixelate
+	// RedactBlur replaces the regions with a heavily blurred copy.
+	RedactBlur
+)
+
+var redactModes = map[string]RedactMode{
+	"fill":     RedactFill,
+	"pixelate": RedactPixelate,
+	"blur":     RedactBlur,
+}
+
+// RedactModeFromName looks up a redaction mode by name.
+func RedactModeFromName(name string) (RedactMode, error) {
+	mode, ok := redactModes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown redaction mode %q", name)
+	}
+
+	return mode, nil
+}
+
+func RedactModeName(mode RedactMode) string {
+	for name, m := range redactModes {
+		if m == mode {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// Defaults and limits for redaction.
+const (
+	DefaultPixelateBlock = 16
+	DefaultRedactSigma   = 20
+	MaxRedactRegions     = 100
+	MaxPixelateBlock     = 512
+	MaxRedactSigma       = 100
+)
+
+// Region is an area of an image, given either as a rectangle or, when
+// Polygon is not empty, as the vertices of a polygon.
+type Region struct {
+	Rect    image.Rectangle
+	Polygon []Point
+}
+
+func (r Region) Validate() error {
+	if len(r.Polygon) == 0 {
+		if r.Rect.Empty() {
+			return fmt.Errorf("imaging: region is empty")
+		}
+		return nil
+	}
+
+	if len(r.Polygon) < 3 || len(r.Polygon) > MaxShapePoints {
+		return fmt.Errorf("imaging: region polygons have between 3 and %d points", MaxShapePoints)
+	}
+	for _, p := range r.Polygon {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("imaging: invalid point")
+		}
+	}
+
+	return nil
+}
+
+func (r Region) polygon() polygon {
+	if len(r.Polygon) == 0 {
+		min := Point{X: float64(r.Rect.Min.X), Y: float64(r.Rect.Min.Y)}
+		max := Point{X: float64(r.Rect.Max.X), Y: float64(r.Rect.Max.Y)}
+		return rectPolygon(min, max).oriented(true)
+	}
+
+	return polygon(r.Polygon).oriented(true)
+}
+
+type RedactOptions struct {
+	Regions []Region
+	Mode    RedactMode
+	// Color fills the regions in RedactFill mode.
+	Color color.NRGBA
+	// BlockSize is the side of the blocks in RedactPixelate mode, 16 when
+	// it is 0.
+	BlockSize int
+	// Sigma is the blur radius in RedactBlur mode, 20 when it is 0.
+	Sigma float64
+}
+
+func (o RedactOptions) Validate() error {
+	if len(o.Regions) == 0 || len(o.Regions) > MaxRedactRegions {
+		return fmt.Errorf("imaging: redact between 1 and %d regions", MaxRedactRegions)
+	}
+	for _, region := range o.Regions {
+		if err := region.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.BlockSize < 0 || o.BlockSize == 1 || o.BlockSize > MaxPixelateBlock {
+		return fmt.Errorf("imaging: block size must be between 2 and %d", MaxPixelateBlock)
+	}
+	if !(o.Sigma >= 0 && o.Sigma <= MaxRedactSigma) {
+		return fmt.Errorf("imaging: blur sigma must be between 0 and %d", MaxRedactSigma)
+	}
+
+	return nil
+}
+
+// Redact obscures the regions of img. Every pixel the regions touch, even
+// partly, is replaced outright so nothing of the original shows through
+// their edges, and pixels outside them are left exactly as they were.
+func (i *ImagingImpl) Redact(img image.Image, opts RedactOptions) (*image.NRGBA, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	dst := imaging.Clone(img)
+
+	polygons := make([]polygon, len(opts.Regions))
+	for idx, region := range opts.Regions {
+		polygons[idx] = region.polygon()
+	}
+	mask := polygonMask(polygons, dst.Rect)
+	if mask == nil {
+		return dst, nil
+	}
+
+	var source func(x, y int) []uint8
+	switch opts.Mode {
+	case RedactPixelate:
+		source = pixelated(dst, mask.Rect, opts.BlockSize)
+	case RedactBlur:
+		source = i.blurred(dst, mask.Rect, opts.Sigma)
+	default:
+		fill := []uint8{opts.Color.R, opts.Color.G, opts.Color.B, opts.Color.A}
+		source = func(x, y int) []uint8 { return fill }
+	}
+
+	bounds := mask.Rect
+	parallelRows(bounds.Dy(), func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				if mask.Pix[y*mask.Stride+x] == 0 {
+					continue
+				}
+				offset := dst.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				copy(dst.Pix[offset:offset+4], source(bounds.Min.X+x, bounds.Min.Y+y))
+			}
+		}
+	})
+
+	return dst, nil
+}
+
+// pixelated averages img over blocks aligned to the corner of bounds and
+// returns a lookup of the average covering a pixel. Colours are weighted by
+// alpha so transparent pixels do not darken their block.
+func pixelated(img *image.NRGBA, bounds image.Rectangle, block int) func(x, y int) []uint8 {
+	if block == 0 {
+		block = DefaultPixelateBlock
+	}
+
+	cols := (bounds.Dx() + block - 1) / block
+	rows := (bounds.Dy() + block - 1) / block
+	averages := make([]uint8, cols*rows*4)
+
+	parallelRows(rows, func(start, end int) {
+		for row := start; row < end; row++ {
+			for col := 0; col < cols; col++ {
+				area := image.Rect(col*block, row*block, (col+1)*block, (row+1)*block).Add(bounds.Min).Intersect(img.Rect)
+
+				var r, g, b, a float64
+				for y := area.Min.Y; y < area.Max.Y; y++ {
+					offset := img.PixOffset(area.Min.X, y)
+					for x := area.Min.X; x < area.Max.X; x++ {
+						alpha := float64(img.Pix[offset+3])
+						r += float64(img.Pix[offset]) * alpha
+						g += float64(img.Pix[offset+1]) * alpha
+						b += float64(img.Pix[offset+2]) * alpha
+						a += alpha
+						offset += 4
+					}
+				}
+
+				average := averages[(row*cols+col)*4:]
+				if a > 0 {
+					average[0] = clampUint8(r / a)
+					average[1] = clampUint8(g / a)
+					average[2] = clampUint8(b / a)
+					average[3] = clampUint8(a / float64(area.Dx()*area.Dy()))
+				}
+			}
+		}
+	})
+
+	return func(x, y int) []uint8 {
+		col, row := (x-bounds.Min.X)/block, (y-bounds.Min.Y)/block
+		offset := (row*cols + col) * 4
+		return averages[offset : offset+4]
+	}
+}
+
+// blurred blurs the part of img around bounds and returns a lookup of the
+// blurred pixels. The margin keeps the blur near the edges of the region as
+// strong as inside it.
+func (i *ImagingImpl) blurred(img *image.NRGBA, bounds image.Rectangle, sigma float64) func(x, y int) []uint8 {
+	if sigma == 0 {
+		sigma = DefaultRedactSigma
+	}
+
+	margin := int(math.Ceil(3 * sigma))
+	area := bounds.Inset(-margin).Intersect(img.Rect)
+	blurred := i.Blur(img.SubImage(area), sigma)
+
+	return func(x, y int) []uint8 {
+		offset := blurred.PixOffset(x-area.Min.X, y-area.Min.Y)
+		return blurred.Pix[offset : offset+4]
+	}
+}
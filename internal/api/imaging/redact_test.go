@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stripes has a vertical black and white stripe every pixel, which every
+// redaction mode should wipe out.
+func stripes(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8(255 * (x % 2))
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+
+	return img
+}
+
+func TestRedact(t *testing.T) {
+	im := &ImagingImpl{}
+	base := stripes(64, 64)
+	rect := image.Rect(16, 16, 48, 40)
+
+	testcases := []struct{
+		name string
+		opts RedactOptions
+		check func(t *testing.T, px color.NRGBA)
+	}{
+		{
+			name: "Fill",
+			opts: RedactOptions{Mode: RedactFill, Color: color.NRGBA{255, 0, 0, 255}},
+			check: func(t *testing.T, px color.NRGBA) {
+				assert.Equal(t, color.NRGBA{255, 0, 0, 255}, px)
+			},
+		},
+		{
+			name: "Pixelate",
+			opts: RedactOptions{Mode: RedactPixelate, BlockSize: 8},
+			check: func(t *testing.T, px color.NRGBA) {
+				assert.InDelta(t, 127, int(px.R), 1)
+			},
+		},
+		{
+			name: "Blur",
+			opts: RedactOptions{Mode: RedactBlur, Sigma: 10},
+			check: func(t *testing.T, px color.NRGBA) {
+				assert.InDelta(t, 127, int(px.R), 2)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Regions = []Region{{Rect: rect}}
+			dst, err := im.Redact(base, tc.opts)
+			require.NoError(t, err)
+
+			for y := 0; y < 64; y++ {
+				for x := 0; x < 64; x++ {
+					if image.Pt(x, y).In(rect) {
+						tc.check(t, dst.NRGBAAt(x, y))
+					} else {
+						require.Equal(t, base.NRGBAAt(x, y), dst.NRGBAAt(x, y), "pixel %d,%d outside the region changed", x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRedactPolygon(t *testing.T) {
+	im := &ImagingImpl{}
+	base := stripes(40, 40)
+
+	dst, err := im.Redact(base, RedactOptions{
+		Regions: []Region{{Polygon: []Point{{5, 5}, {35, 5}, {5, 35}}}},
+		Mode: RedactFill,
+		Color: color.NRGBA{A: 255},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, color.NRGBA{A: 255}, dst.NRGBAAt(11, 10))
+	// across the diagonal edge, and outside the bounding box
+	assert.Equal(t, base.NRGBAAt(31, 30), dst.NRGBAAt(31, 30))
+	assert.Equal(t, base.NRGBAAt(1, 1), dst.NRGBAAt(1, 1))
+}
+
+func TestRedactOptionsValidate(t *testing.T) {
+	testcases := []struct{
+		name string
+		opts RedactOptions
+	}{
+		{name: "No regions", opts: RedactOptions{}},
+		{name: "Empty rectangle", opts: RedactOptions{Regions: []Region{{Rect: image.Rect(10, 10, 10, 20)}}}},
+		{name: "Polygon with two points", opts: RedactOptions{Regions: []Region{{Polygon: []Point{{0, 0}, {1, 1}}}}}},
+		{name: "Single pixel blocks", opts: RedactOptions{Regions: []Region{{Rect: image.Rect(0, 0, 1, 1)}}, Mode: RedactPixelate, BlockSize: 1}},
+		{name: "Sigma too large", opts: RedactOptions{Regions: []Region{{Rect: image.Rect(0, 0, 1, 1)}}, Mode: RedactBlur, Sigma: 500}},
+		{name: "Sigma not a number", opts: RedactOptions{Regions: []Region{{Rect: image.Rect(0, 0, 1, 1)}}, Mode: RedactBlur, Sigma: math.NaN()}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, tc.opts.Validate())
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/vector"
+)
+
+// ShapeKind selects the primitive a Shape draws.
+type ShapeKind int
+
+const (
+	// ShapeRect and ShapeEllipse are given by two opposite corners of their
+	// bounding box.
+	ShapeRect ShapeKind = iota
+	ShapeEllipse
+	// ShapeLine and ShapeArrow are open paths through their points, the
+	// arrow having its head at the last one.
+	ShapeLine
+	ShapeArrow
+	// ShapePolygon is a closed path through its points.
+	ShapePolygon
+)
+
+var shapeKindNames = map[string]ShapeKind{
+	"rect":    ShapeRect,
+	"ellipse": ShapeEllipse,
+	"line":    ShapeLine,
+	"arrow":   ShapeArrow,
+	"polygon": ShapePolygon,
+}
+
+func ShapeKindFromName(name string) (ShapeKind, error) {
+	kind, ok := shapeKindNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown shape %q", name)
+	}
+
+	return kind, nil
+}
+
+func ShapeKindName(kind ShapeKind) string {
+	for name, k := range shapeKindNames {
+		if k == kind {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// DefaultShapeStroke is the outline width in pixels when none is given.
+const DefaultShapeStroke = 2
+
+// Limits for shapes.
+const (
+	MaxShapePoints      = 256
+	MaxShapeStrokeWidth = 200
+	MaxArrowSize        = 500
+)
+
+// Point is a position in image pixels.
+type Point struct {
+	X, Y float64
+}
+
+func (p Point) finite() bool {
+	return !math.IsNaN(p.X) && !math.IsNaN(p.Y) && !math.IsInf(p.X, 0) && !math.IsInf(p.Y, 0)
+}
+
+// Shape is a vector primitive. The outline is drawn when StrokeWidth is
+// positive and the interior of closed shapes is filled when Fill is not
+// transparent. ArrowSize is the length of an arrow head, derived from the
+// stroke width when zero.
+type Shape struct {
+	Kind        ShapeKind
+	Points      []Point
+	StrokeWidth float64
+	StrokeColor color.NRGBA
+	Fill        color.NRGBA
+	ArrowSize   float64
+	AntiAlias   bool
+}
+
+// Closed reports whether the shape has an interior that can be filled.
+func (s Shape) Closed() bool {
+	return s.Kind == ShapeRect || s.Kind == ShapeEllipse || s.Kind == ShapePolygon
+}
+
+func (s Shape) Validate() error {
+	switch {
+	case s.Kind == ShapePolygon && len(s.Points) < 3:
+		return fmt.Errorf("imaging: a polygon needs at least 3 points")
+	case (s.Kind == ShapeRect || s.Kind == ShapeEllipse) && len(s.Points) != 2:
+		return fmt.Errorf("imaging: a %s needs 2 corner points", ShapeKindName(s.Kind))
+	case len(s.Points) < 2:
+		return fmt.Errorf("imaging: a %s needs at least 2 points", ShapeKindName(s.Kind))
+	case len(s.Points) > MaxShapePoints:
+		return fmt.Errorf("imaging: shapes have at most %d points", MaxShapePoints)
+	case !(s.StrokeWidth >= 0 && s.StrokeWidth <= MaxShapeStrokeWidth):
+		return fmt.Errorf("imaging: stroke width must be between 0 and %d", MaxShapeStrokeWidth)
+	case !(s.ArrowSize >= 0 && s.ArrowSize <= MaxArrowSize):
+		return fmt.Errorf("imaging: arrow size must be between 0 and %d", MaxArrowSize)
+	case s.Kind != ShapeArrow && s.ArrowSize != 0:
+		return fmt.Errorf("imaging: only arrows have a head size")
+	case !s.Closed() && s.Fill.A > 0:
+		return fmt.Errorf("imaging: a %s cannot be filled", ShapeKindName(s.Kind))
+	case !s.Closed() && s.StrokeWidth == 0:
+		return fmt.Errorf("imaging: a %s needs a stroke width", ShapeKindName(s.Kind))
+	}
+
+	for _, p := range s.Points {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("imaging: invalid point")
+		}
+	}
+
+	return nil
+}
+
+// DrawShapes draws shapes onto img in order, each filled before it is
+// outlined.
+func (i *ImagingImpl) DrawShapes(img image.Image, shapes []Shape) (*image.NRGBA, error) {
+	for _, shape := range shapes {
+		if err := shape.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	dst := imaging.Clone(img)
+	for _, shape := range shapes {
+		if shape.Closed() && shape.Fill.A > 0 {
+			paintPolygons(dst, fillPolygons(shape), shape.Fill, shape.AntiAlias)
+		}
+		if shape.StrokeWidth > 0 && shape.StrokeColor.A > 0 {
+			paintPolygons(dst, strokePolygons(shape), shape.StrokeColor, shape.AntiAlias)
+		}
+	}
+
+	return dst, nil
+}
+
+// polygon is a closed path. Polygons painted together are combined by the
+// sign of their area: same-signed ones add up and opposite ones cut holes.
+type polygon []Point
+
+func (p polygon) area() float64 {
+	area := 0.0
+	for idx := range p {
+		next := p[(idx+1)%len(p)]
+		area += p[idx].X*next.Y - next.X*p[idx].Y
+	}
+
+	return area / 2
+}
+
+// oriented returns p wound so that its area has the given sign.
+func (p polygon) oriented(positive bool) polygon {
+	if (p.area() >= 0) == positive {
+		return p
+	}
+
+	reversed := make(polygon, len(p))
+	for idx, point := range p {
+		reversed[len(p)-1-idx] = point
+	}
+
+	return reversed
+}
+
+// polygonMask rasterizes the polygons into a coverage mask over their
+// bounding box, clipped to clip. The mask is nil when nothing is covered.
+func polygonMask(polygons []polygon, clip image.Rectangle) *image.Alpha {
+	bounds := image.Rectangle{}
+	for _, poly := range polygons {
+		for _, p := range poly {
+			bounds = bounds.Union(image.Rect(int(math.Floor(p.X)), int(math.Floor(p.Y)), int(math.Ceil(p.X))+1, int(math.Ceil(p.Y))+1))
+		}
+	}
+	bounds = bounds.Intersect(clip)
+	if bounds.Empty() {
+		return nil
+	}
+
+	rasterizer := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	rasterizer.DrawOp = draw.Src
+	origin := Point{X: float64(bounds.Min.X), Y: float64(bounds.Min.Y)}
+	for _, poly := range polygons {
+		if len(poly) < 3 {
+			continue
+		}
+		rasterizer.MoveTo(float32(poly[0].X-origin.X), float32(poly[0].Y-origin.Y))
+		for _, p := range poly[1:] {
+			rasterizer.LineTo(float32(p.X-origin.X), float32(p.Y-origin.Y))
+		}
+		rasterizer.ClosePath()
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	rasterizer.Draw(mask, mask.Rect, image.Opaque, image.Point{})
+	mask.Rect = bounds
+
+	return mask
+}
+
+// paintPolygons paints c through the coverage mask of the polygons.
+func paintPolygons(dst *image.NRGBA, polygons []polygon, c color.NRGBA, antiAlias bool) {
+	mask := polygonMask(polygons, dst.Rect)
+	if mask == nil {
+		return
+	}
+
+	bounds := mask.Rect
+	parallelRows(bounds.Dy(), func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < bounds.Dx(); x++ {
+				coverage := mask.Pix[y*mask.Stride+x]
+				if !antiAlias {
+					coverage = uint8(int(coverage) / 128 * 255)
+				}
+				if coverage == 0 {
+					continue
+				}
+				offset := dst.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				blendPixel(dst.Pix[offset:offset+4], c, float64(coverage)/255)
+			}
+		}
+	})
+}
+
+// box returns the corners of a rect or ellipse shape ordered as min and max.
+func (s Shape) box() (Point, Point) {
+	a, b := s.Points[0], s.Points[1]
+	return Point{X: math.Min(a.X, b.X), Y: math.Min(a.Y, b.Y)}, Point{X: math.Max(a.X, b.X), Y: math.Max(a.Y, b.Y)}
+}
+
+func rectPolygon(min, max Point) polygon {
+	return polygon{min, {X: max.X, Y: min.Y}, max, {X: min.X, Y: max.Y}}
+}
+
+func ellipsePolygon(center Point, rx, ry float64) polygon {
+	// enough segments that the chords stay within a fraction of a pixel
+	segments := int(math.Max(24, math.Min(1024, math.Pi*(rx+ry)/2)))
+	poly := make(polygon, segments)
+	for idx := range poly {
+		angle := 2 * math.Pi * float64(idx) / float64(segments)
+		poly[idx] = Point{X: center.X + rx*math.Cos(angle), Y: center.Y + ry*math.Sin(angle)}
+	}
+
+	return poly
+}
+
+func fillPolygons(s Shape) []polygon {
+	switch s.Kind {
+	case ShapeRect:
+		return []polygon{rectPolygon(s.box())}
+	case ShapeEllipse:
+		min, max := s.box()
+		center := Point{X: (min.X + max.X) / 2, Y: (min.Y + max.Y) / 2}
+		return []polygon{ellipsePolygon(center, (max.X-min.X)/2, (max.Y-min.Y)/2)}
+	default:
+		return []polygon{polygon(s.Points)}
+	}
+}
+
+// strokePolygons outlines the shape with polygons covering everything within
+// half the stroke width of its path.
+func strokePolygons(s Shape) []polygon {
+	half := s.StrokeWidth / 2
+
+	switch s.Kind {
+	case ShapeRect:
+		min, max := s.box()
+		outer := rectPolygon(Point{X: min.X - half, Y: min.Y - half}, Point{X: max.X + half, Y: max.Y + half})
+		polygons := []polygon{outer.oriented(true)}
+		if max.X-min.X > s.StrokeWidth && max.Y-min.Y > s.StrokeWidth {
+			inner := rectPolygon(Point{X: min.X + half, Y: min.Y + half}, Point{X: max.X - half, Y: max.Y - half})
+			polygons = append(polygons, inner.oriented(false))
+		}
+		return polygons
+	case ShapeEllipse:
+		min, max := s.box()
+		center := Point{X: (min.X + max.X) / 2, Y: (min.Y + max.Y) / 2}
+		rx, ry := (max.X-min.X)/2, (max.Y-min.Y)/2
+		polygons := []polygon{ellipsePolygon(center, rx+half, ry+half).oriented(true)}
+		if rx > half && ry > half {
+			polygons = append(polygons, ellipsePolygon(center, rx-half, ry-half).oriented(false))
+		}
+		return polygons
+	case ShapePolygon:
+		closed := append(append([]Point{}, s.Points...), s.Points[0])
+		return pathPolygons(closed, half)
+	case ShapeArrow:
+		path, head := arrowHead(s)
+		return append(pathPolygons(path, half), head...)
+	default:
+		return pathPolygons(s.Points, half)
+	}
+}
+
+// pathPolygons covers an open path with a quad per segment and a disc at
+// every point, giving round caps and joins.
+func pathPolygons(points []Point, half float64) []polygon {
+	polygons := make([]polygon, 0, 2*len(points))
+
+	for idx, p := range points {
+		polygons = append(polygons, ellipsePolygon(p, half, half).oriented(true))
+		if idx == 0 {
+			continue
+		}
+
+		prev := points[idx-1]
+		dx, dy := p.X-prev.X, p.Y-prev.Y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*half, dx/length*half
+		quad := polygon{
+			{X: prev.X + nx, Y: prev.Y + ny},
+			{X: p.X + nx, Y: p.Y + ny},
+			{X: p.X - nx, Y: p.Y - ny},
+			{X: prev.X - nx, Y: prev.Y - ny},
+		}
+		polygons = append(polygons, quad.oriented(true))
+	}
+
+	return polygons
+}
+
+// arrowHead returns the path of an arrow ending at the base of its head,
+// and the head itself.
+func arrowHead(s Shape) ([]Point, []polygon) {
+	tip := s.Points[len(s.Points)-1]
+
+	// the head points along the last segment of non-zero length
+	var from Point
+	found := false
+	for idx := len(s.Points) - 2; idx >= 0; idx-- {
+		if s.Points[idx] != tip {
+			from, found = s.Points[idx], true
+			break
+		}
+	}
+	if !found {
+		return s.Points, nil
+	}
+
+	size := s.ArrowSize
+	if size == 0 {
+		size = math.Max(10, 4*s.StrokeWidth)
+	}
+
+	dx, dy := tip.X-from.X, tip.Y-from.Y
+	length := math.Hypot(dx, dy)
+	ux, uy := dx/length, dy/length
+	base := Point{X: tip.X - ux*size, Y: tip.Y - uy*size}
+	head := polygon{
+		tip,
+		{X: base.X - uy*size/2, Y: base.Y + ux*size/2},
+		{X: base.X + uy*size/2, Y: base.Y - ux*size/2},
+	}
+
+	path := append([]Point{}, s.Points[:len(s.Points)-1]...)
+	return append(path, base), []polygon{head.oriented(true)}
+}
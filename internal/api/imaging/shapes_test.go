@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrawShapesRect(t *testing.T) {
+	im := &ImagingImpl{}
+	white := color.NRGBA{255, 255, 255, 255}
+	red := color.NRGBA{255, 0, 0, 255}
+	base := solidImage(40, 40, white)
+
+	dst, err := im.DrawShapes(base, []Shape{
+		{Kind: ShapeRect, Points: []Point{{30, 30}, {10, 10}}, StrokeWidth: 4, StrokeColor: red, AntiAlias: true},
+	})
+	require.NoError(t, err)
+
+	// the stroke is centred on the outline and leaves the inside alone
+	assert.Equal(t, red, dst.NRGBAAt(9, 20))
+	assert.Equal(t, red, dst.NRGBAAt(11, 11))
+	assert.Equal(t, white, dst.NRGBAAt(20, 20))
+	assert.Equal(t, white, dst.NRGBAAt(5, 5))
+	assert.Equal(t, white, dst.NRGBAAt(35, 20))
+
+	dst, err = im.DrawShapes(base, []Shape{
+		{Kind: ShapeRect, Points: []Point{{10, 10}, {30, 30}}, Fill: color.NRGBA{0, 0, 255, 128}, AntiAlias: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{127, 127, 255, 255}, dst.NRGBAAt(20, 20))
+	assert.Equal(t, white, dst.NRGBAAt(5, 5))
+}
+
+func TestDrawShapesAntiAlias(t *testing.T) {
+	im := &ImagingImpl{}
+	white := color.NRGBA{255, 255, 255, 255}
+	black := color.NRGBA{0, 0, 0, 255}
+	base := solidImage(40, 40, white)
+
+	count := func(antiAlias bool) (int, int) {
+		dst, err := im.DrawShapes(base, []Shape{
+			{Kind: ShapeEllipse, Points: []Point{{5, 5}, {35, 30}}, Fill: black, AntiAlias: antiAlias},
+		})
+		require.NoError(t, err)
+
+		var solid, partial int
+		for idx := 0; idx < len(dst.Pix); idx += 4 {
+			switch dst.Pix[idx] {
+			case 0:
+				solid++
+			case 255:
+			default:
+				partial++
+			}
+		}
+		return solid, partial
+	}
+
+	solid, partial := count(true)
+	assert.Greater(t, solid, 0)
+	assert.Greater(t, partial, 0)
+
+	solid, partial = count(false)
+	assert.Greater(t, solid, 0)
+	assert.Zero(t, partial)
+}
+
+func TestDrawShapesPaths(t *testing.T) {
+	im := &ImagingImpl{}
+	white := color.NRGBA{255, 255, 255, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	base := solidImage(60, 60, white)
+
+	dst, err := im.DrawShapes(base, []Shape{
+		{Kind: ShapeArrow, Points: []Point{{5, 30}, {50, 30}}, StrokeWidth: 2, StrokeColor: blue, AntiAlias: true},
+		{Kind: ShapePolygon, Points: []Point{{10, 5}, {50, 5}, {30, 20}}, StrokeWidth: 2, StrokeColor: blue, AntiAlias: true},
+	})
+	require.NoError(t, err)
+
+	// the shaft, and the head which is wider than the shaft
+	assert.Equal(t, blue, dst.NRGBAAt(20, 30))
+	assert.Equal(t, blue, dst.NRGBAAt(43, 28))
+	assert.Equal(t, white, dst.NRGBAAt(20, 28))
+	assert.Equal(t, white, dst.NRGBAAt(55, 30))
+
+	// the closing edge of the polygon is outlined, its inside is not filled
+	assert.Equal(t, blue, dst.NRGBAAt(20, 12))
+	assert.Equal(t, white, dst.NRGBAAt(30, 10))
+}
+
+func TestShapeValidate(t *testing.T) {
+	testcases := []struct{
+		name string
+		shape Shape
+	}{
+		{name: "Polygon with two points", shape: Shape{Kind: ShapePolygon, Points: []Point{{0, 0}, {1, 1}}, StrokeWidth: 1}},
+		{name: "Rect with three points", shape: Shape{Kind: ShapeRect, Points: []Point{{0, 0}, {1, 1}, {2, 2}}, StrokeWidth: 1}},
+		{name: "Filled line", shape: Shape{Kind: ShapeLine, Points: []Point{{0, 0}, {1, 1}}, StrokeWidth: 1, Fill: color.NRGBA{A: 255}}},
+		{name: "Line without stroke", shape: Shape{Kind: ShapeLine, Points: []Point{{0, 0}, {1, 1}}}},
+		{name: "Stroke too wide", shape: Shape{Kind: ShapeRect, Points: []Point{{0, 0}, {1, 1}}, StrokeWidth: 1000}},
+		{name: "Stroke width not a number", shape: Shape{Kind: ShapeRect, Points: []Point{{0, 0}, {1, 1}}, StrokeWidth: math.NaN()}},
+		{name: "Arrow head not a number", shape: Shape{Kind: ShapeArrow, Points: []Point{{0, 0}, {10, 10}}, StrokeWidth: 1, ArrowSize: math.NaN()}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Error(t, tc.shape.Validate())
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var ErrInvalidFont = errors.New("imaging: invalid font")
+
+// DefaultFontSize is the text size in pixels when none is given.
+const DefaultFontSize = 32
+
+// Limits for text rendering.
+const (
+	MaxTextLength   = 2000
+	MaxFontSize     = 1000
+	MaxTextStroke   = 50
+	MaxShadowBlur   = 50
+	MaxShadowOffset = 1000
+	MinLineSpacing  = 0.5
+	MaxLineSpacing  = 5
+)
+
+// ParseFont reads a TrueType or OpenType font.
+func ParseFont(data []byte) (*opentype.Font, error) {
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFont, err)
+	}
+
+	return f, nil
+}
+
+// DefaultFont is the bundled Go Regular font, used when no font is given.
+var DefaultFont = sync.OnceValue(func() *opentype.Font {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		panic("imaging: bundled font: " + err.Error())
+	}
+
+	return f
+})
+
+// TextAlign aligns the lines of a text block with each other.
+type TextAlign int
+
+const (
+	AlignLeft TextAlign = iota
+	AlignCenter
+	AlignRight
+)
+
+var textAlignNames = map[string]TextAlign{
+	"left":   AlignLeft,
+	"center": AlignCenter,
+	"right":  AlignRight,
+}
+
+func TextAlignFromName(name string) (TextAlign, error) {
+	align, ok := textAlignNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown alignment %q", name)
+	}
+
+	return align, nil
+}
+
+func TextAlignName(align TextAlign) string {
+	for name, a := range textAlignNames {
+		if a == align {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// TextOptions configure DrawText. Text is wrapped to the width of Box, or
+// of the image when Box is empty, and the block of lines is placed in it by
+// Anchor before being rotated by Rotation degrees counter-clockwise around
+// its centre. Size is in pixels and LineSpacing is a multiple of the font's
+// line height, 1 when zero. The stroke and shadow are drawn when their
+// width and colour are set.
+type TextOptions struct {
+	Text        string
+	Font        *opentype.Font
+	Size        float64
+	Color       color.NRGBA
+	Align       TextAlign
+	LineSpacing float64
+	Box         image.Rectangle
+	Anchor      Anchor
+	Rotation    float64
+
+	StrokeWidth int
+	StrokeColor color.NRGBA
+
+	ShadowOffset image.Point
+	ShadowBlur   float64
+	ShadowColor  color.NRGBA
+}
+
+// Validate checks the options, except for the font.
+func (o TextOptions) Validate() error {
+	length := utf8.RuneCountInString(o.Text)
+	switch {
+	case strings.TrimSpace(o.Text) == "":
+		return fmt.Errorf("imaging: text is empty")
+	case length > MaxTextLength:
+		return fmt.Errorf("imaging: text is longer than %d characters", MaxTextLength)
+	case !(o.Size > 0 && o.Size <= MaxFontSize):
+		return fmt.Errorf("imaging: font size must be greater than 0 and at most %d", MaxFontSize)
+	case o.LineSpacing != 0 && !(o.LineSpacing >= MinLineSpacing && o.LineSpacing <= MaxLineSpacing):
+		return fmt.Errorf("imaging: line spacing must be between %g and %d", MinLineSpacing, MaxLineSpacing)
+	case o.StrokeWidth < 0 || o.StrokeWidth > MaxTextStroke:
+		return fmt.Errorf("imaging: stroke width must be between 0 and %d", MaxTextStroke)
+	case !(o.ShadowBlur >= 0 && o.ShadowBlur <= MaxShadowBlur):
+		return fmt.Errorf("imaging: shadow blur must be between 0 and %d", MaxShadowBlur)
+	case abs(o.ShadowOffset.X) > MaxShadowOffset || abs(o.ShadowOffset.Y) > MaxShadowOffset:
+		return fmt.Errorf("imaging: shadow offsets must be at most %d", MaxShadowOffset)
+	case math.IsNaN(o.Rotation) || math.IsInf(o.Rotation, 0):
+		return fmt.Errorf("imaging: invalid rotation")
+	}
+
+	return nil
+}
+
+// DrawText renders text onto img.
+func (i *ImagingImpl) DrawText(img image.Image, opts TextOptions) (*image.NRGBA, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	f := opts.Font
+	if f == nil {
+		f = DefaultFont()
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: opts.Size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFont, err)
+	}
+	defer face.Close()
+
+	dst := imaging.Clone(img)
+	box := opts.Box
+	if box.Empty() {
+		box = dst.Rect
+	}
+
+	lines := wrapText(face, opts.Text, box.Dx()-2*opts.StrokeWidth)
+	mask, err := i.renderLines(face, lines, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	layer := textLayer(mask, opts)
+	origin := opts.Anchor.Position(box, layer.Rect.Size(), 0, 0)
+
+	if opts.Rotation != 0 {
+		rotated := imaging.Rotate(layer, opts.Rotation, color.Transparent)
+		// keep the centre of the block where it was placed
+		origin = origin.Add(layer.Rect.Size().Sub(rotated.Rect.Size()).Div(2))
+		layer = rotated
+	}
+
+	if opts.ShadowColor.A > 0 {
+		shadow, pad := shadowLayer(layer, opts.ShadowColor, opts.ShadowBlur)
+		compositeAt(dst, shadow, origin.Add(opts.ShadowOffset).Sub(image.Pt(pad, pad)), OverlayOptions{Opacity: 1})
+	}
+	compositeAt(dst, layer, origin, OverlayOptions{Opacity: 1})
+
+	return dst, nil
+}
+
+// renderLines draws lines into a coverage mask, leaving room around them for
+// the stroke.
+func (i *ImagingImpl) renderLines(face font.Face, lines []string, opts TextOptions) (*image.Alpha, error) {
+	metrics := face.Metrics()
+	spacing := opts.LineSpacing
+	if spacing == 0 {
+		spacing = 1
+	}
+	lineHeight := int(math.Ceil(float64(metrics.Height.Ceil()) * spacing))
+	ascent, descent := metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+	pad := opts.StrokeWidth
+
+	widths := make([]int, len(lines))
+	blockWidth := 0
+	for idx, line := range lines {
+		widths[idx] = font.MeasureString(face, line).Ceil()
+		blockWidth = max(blockWidth, widths[idx])
+	}
+
+	width := blockWidth + 2*pad
+	height := (len(lines)-1)*lineHeight + ascent + descent + 2*pad
+	if err := i.limits.Check(max(width, 1), max(height, 1)); err != nil {
+		return nil, err
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, max(width, 1), max(height, 1)))
+	drawer := font.Drawer{Dst: mask, Src: image.Opaque, Face: face}
+	for idx, line := range lines {
+		x := pad
+		switch opts.Align {
+		case AlignCenter:
+			x += (blockWidth - widths[idx]) / 2
+		case AlignRight:
+			x += blockWidth - widths[idx]
+		}
+		drawer.Dot = fixed.P(x, pad+ascent+idx*lineHeight)
+		drawer.DrawString(line)
+	}
+
+	return mask, nil
+}
+
+// wrapText breaks text into lines no wider than width, at spaces where
+// possible and inside words that do not fit on a line of their own.
+func wrapText(face font.Face, text string, width int) []string {
+	fits := func(s string) bool {
+		return width <= 0 || font.MeasureString(face, s).Ceil() <= width
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if fits(candidate) {
+				line = candidate
+				continue
+			}
+
+			if line != "" {
+				lines = append(lines, line)
+			}
+			for !fits(word) && utf8.RuneCountInString(word) > 1 {
+				cut := fittingPrefix(word, fits)
+				lines = append(lines, word[:cut])
+				word = word[cut:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// fittingPrefix returns the byte length of the longest prefix of word that
+// fits, and at least one rune.
+func fittingPrefix(word string, fits func(string) bool) int {
+	_, cut := utf8.DecodeRuneInString(word)
+	for next := cut; next < len(word); {
+		_, size := utf8.DecodeRuneInString(word[next:])
+		next += size
+		if !fits(word[:next]) {
+			break
+		}
+		cut = next
+	}
+
+	return cut
+}
+
+// textLayer colours the glyph mask, with the stroke beneath the fill.
+func textLayer(mask *image.Alpha, opts TextOptions) *image.NRGBA {
+	layer := image.NewNRGBA(mask.Rect)
+
+	var outline []float64
+	if opts.StrokeWidth > 0 && opts.StrokeColor.A > 0 {
+		outline = strokeCoverage(mask, float64(opts.StrokeWidth))
+	}
+
+	for idx, coverage := range mask.Pix {
+		px := layer.Pix[idx*4 : idx*4+4]
+		if outline != nil {
+			blendPixel(px, opts.StrokeColor, outline[idx])
+		}
+		blendPixel(px, opts.Color, float64(coverage)/255)
+	}
+
+	return layer
+}
+
+// strokeCoverage returns, for every pixel of mask, how much of it lies
+// within radius of the glyphs, anti-aliased over one pixel.
+func strokeCoverage(mask *image.Alpha, radius float64) []float64 {
+	width, height := mask.Rect.Dx(), mask.Rect.Dy()
+
+	dist := make([]float64, len(mask.Pix))
+	for idx, coverage := range mask.Pix {
+		if coverage < 128 {
+			dist[idx] = math.MaxFloat32
+		}
+	}
+	distanceTransform(dist, width, height)
+
+	coverage := make([]float64, len(dist))
+	for idx, squared := range dist {
+		coverage[idx] = math.Max(0, math.Min(1, radius+0.5-math.Sqrt(squared)))
+	}
+
+	return coverage
+}
+
+// distanceTransform replaces each value of grid, which is 0 inside shapes
+// and large elsewhere, with the squared Euclidean distance to the nearest
+// shape pixel, using the separable algorithm of Felzenszwalb and
+// Huttenlocher.
+func distanceTransform(grid []float64, width, height int) {
+	size := max(width, height)
+	f := make([]float64, size)
+	d := make([]float64, size)
+	v := make([]int, size)
+	z := make([]float64, size+1)
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			f[y] = grid[y*width+x]
+		}
+		distance1D(f[:height], d, v, z)
+		for y := 0; y < height; y++ {
+			grid[y*width+x] = d[y]
+		}
+	}
+	for y := 0; y < height; y++ {
+		row := grid[y*width : (y+1)*width]
+		copy(f, row)
+		distance1D(f[:width], d, v, z)
+		copy(row, d[:width])
+	}
+}
+
+func distance1D(f, d []float64, v []int, z []float64) {
+	k := 0
+	v[0] = 0
+	z[0] = math.Inf(-1)
+	z[1] = math.Inf(1)
+
+	for q := 1; q < len(f); q++ {
+		for {
+			p := v[k]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s > z[k] {
+				k++
+				v[k] = q
+				z[k] = s
+				z[k+1] = math.Inf(1)
+				break
+			}
+			k--
+		}
+	}
+
+	k = 0
+	for q := range f {
+		for z[k+1] < float64(q) {
+			k++
+		}
+		diff := float64(q - v[k])
+		d[q] = diff*diff + f[v[k]]
+	}
+}
+
+// shadowLayer returns a copy of the alpha of layer in colour c, blurred and
+// padded so the blur is not cut off, along with the padding.
+func shadowLayer(layer *image.NRGBA, c color.NRGBA, blur float64) (*image.NRGBA, int) {
+	pad := int(math.Ceil(blur * 3))
+	shadow := image.NewNRGBA(image.Rect(0, 0, layer.Rect.Dx()+2*pad, layer.Rect.Dy()+2*pad))
+
+	for y := 0; y < layer.Rect.Dy(); y++ {
+		for x := 0; x < layer.Rect.Dx(); x++ {
+			alpha := layer.Pix[layer.PixOffset(layer.Rect.Min.X+x, layer.Rect.Min.Y+y)+3]
+			if alpha == 0 {
+				continue
+			}
+			px := shadow.Pix[shadow.PixOffset(x+pad, y+pad):]
+			px[0], px[1], px[2] = c.R, c.G, c.B
+			px[3] = uint8(uint16(alpha) * uint16(c.A) / 255)
+		}
+	}
+
+	if blur > 0 {
+		shadow = imaging.Blur(shadow, blur)
+	}
+
+	return shadow, pad
+}
@@ -0,0 +1,181 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+func TestParseHexColor(t *testing.T) {
+	testcases := []struct{
+		input string
+		want color.NRGBA
+		wantErr bool
+	}{
+		{input: "#ff8000", want: color.NRGBA{255, 128, 0, 255}},
+		{input: "FF800080", want: color.NRGBA{255, 128, 0, 128}},
+		{input: "#f80", want: color.NRGBA{255, 136, 0, 255}},
+		{input: "f808", want: color.NRGBA{255, 136, 0, 136}},
+		{input: "#ff80", want: color.NRGBA{255, 255, 136, 0}},
+		{input: "red", wantErr: true},
+		{input: "#12345", wantErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseHexColor(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	assert.Equal(t, "ff8000", HexColor(color.NRGBA{255, 128, 0, 255}))
+	assert.Equal(t, "ff800080", HexColor(color.NRGBA{255, 128, 0, 128}))
+}
+
+func TestWrapText(t *testing.T) {
+	face, err := opentype.NewFace(DefaultFont(), &opentype.FaceOptions{Size: 20, DPI: 72})
+	require.NoError(t, err)
+	defer face.Close()
+
+	lines := wrapText(face, "the quick brown fox\njumps", 80)
+	require.Greater(t, len(lines), 2)
+	assert.Equal(t, "jumps", lines[len(lines)-1])
+	for _, line := range lines {
+		assert.LessOrEqual(t, font.MeasureString(face, line).Ceil(), 80, line)
+	}
+
+	// words wider than the box are broken
+	lines = wrapText(face, "supercalifragilistic", 50)
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		assert.LessOrEqual(t, font.MeasureString(face, line).Ceil(), 50, line)
+	}
+}
+
+func TestDistanceTransform(t *testing.T) {
+	grid := make([]float64, 5*3)
+	for idx := range grid {
+		grid[idx] = 1e20
+	}
+	grid[1*5+1] = 0
+
+	distanceTransform(grid, 5, 3)
+	assert.Equal(t, []float64{
+		2, 1, 2, 5, 10,
+		1, 0, 1, 4, 9,
+		2, 1, 2, 5, 10,
+	}, grid)
+}
+
+// inkBounds returns the bounds of the pixels of img that differ from bg.
+func inkBounds(img *image.NRGBA, matches func(color.NRGBA) bool) image.Rectangle {
+	var bounds image.Rectangle
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if matches(img.NRGBAAt(x, y)) {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return bounds
+}
+
+func TestDrawText(t *testing.T) {
+	im := &ImagingImpl{}
+	white := color.NRGBA{255, 255, 255, 255}
+	base := solidImage(200, 100, white)
+	dark := func(c color.NRGBA) bool { return c.R < 128 && c.G < 128 && c.B < 128 }
+
+	opts := TextOptions{Text: "Hello", Size: 24, Color: color.NRGBA{0, 0, 0, 255}, Anchor: AnchorTopLeft}
+	dst, err := im.DrawText(base, opts)
+	require.NoError(t, err)
+	ink := inkBounds(dst, dark)
+	require.False(t, ink.Empty())
+	assert.Less(t, ink.Max.X, 100)
+	assert.Less(t, ink.Max.Y, 50)
+
+	opts.Anchor = AnchorBottomRight
+	dst, err = im.DrawText(base, opts)
+	require.NoError(t, err)
+	ink = inkBounds(dst, dark)
+	assert.Greater(t, ink.Min.X, 100)
+	assert.Greater(t, ink.Min.Y, 50)
+
+	// the box limits where the text goes and how wide its lines are
+	opts.Anchor = AnchorTopLeft
+	opts.Text = "Hello Hello Hello"
+	opts.Box = image.Rect(20, 10, 100, 100)
+	dst, err = im.DrawText(base, opts)
+	require.NoError(t, err)
+	ink = inkBounds(dst, dark)
+	assert.GreaterOrEqual(t, ink.Min.X, 20)
+	assert.LessOrEqual(t, ink.Max.X, 100)
+	assert.Greater(t, ink.Dy(), 48, "three lines")
+
+	// rotating a quarter turn makes a line of text taller than it is wide
+	opts.Box = image.Rectangle{}
+	opts.Text = "Hello"
+	opts.Anchor = AnchorCenter
+	opts.Rotation = 90
+	dst, err = im.DrawText(base, opts)
+	require.NoError(t, err)
+	ink = inkBounds(dst, dark)
+	assert.Greater(t, ink.Dy(), ink.Dx())
+}
+
+func TestDrawTextStrokeAndShadow(t *testing.T) {
+	im := &ImagingImpl{}
+	base := solidImage(200, 100, color.NRGBA{255, 255, 255, 255})
+
+	dst, err := im.DrawText(base, TextOptions{
+		Text: "Hi",
+		Size: 40,
+		Color: color.NRGBA{0, 0, 0, 255},
+		StrokeWidth: 3,
+		StrokeColor: color.NRGBA{255, 0, 0, 255},
+		ShadowOffset: image.Pt(10, 10),
+		ShadowBlur: 1,
+		ShadowColor: color.NRGBA{0, 0, 255, 255},
+	})
+	require.NoError(t, err)
+
+	red := inkBounds(dst, func(c color.NRGBA) bool { return c.R > 200 && c.G < 50 && c.B < 50 })
+	blue := inkBounds(dst, func(c color.NRGBA) bool { return c.B > 200 && c.R < 50 && c.G < 50 })
+	require.False(t, red.Empty())
+	require.False(t, blue.Empty())
+	assert.Greater(t, blue.Max.X, red.Max.X)
+	assert.Greater(t, blue.Max.Y, red.Max.Y)
+}
+
+func TestDrawTextRejects(t *testing.T) {
+	im := &ImagingImpl{}
+	base := solidImage(10, 10, color.NRGBA{255, 255, 255, 255})
+
+	for _, opts := range []TextOptions{
+		{Text: " ", Size: 12},
+		{Text: "a", Size: 0},
+		{Text: "a", Size: 12, StrokeWidth: 100},
+		{Text: "a", Size: 12, LineSpacing: 10},
+		{Text: "a", Size: math.NaN()},
+		{Text: "a", Size: 12, LineSpacing: math.NaN()},
+		{Text: "a", Size: 12, ShadowBlur: math.Inf(1)},
+	} {
+		_, err := im.DrawText(base, opts)
+		assert.Error(t, err)
+	}
+
+	_, err := ParseFont([]byte("not a font"))
+	assert.ErrorIs(t, err, ErrInvalidFont)
+}
@@ -0,0 +1,271 @@
+package imaging
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Limits for the automatic tonal operations.
+const (
+	MaxLevelsClip     = 50
+	MinCLAHETile      = 8
+	MaxCLAHETile      = 1024
+	MaxCLAHELimit     = 100
+	defaultCLAHETile  = 64
+	defaultCLAHELimit = 2
+)
+
+type AutoLevelsOptions struct {
+	// ShadowClip and HighlightClip are the percentages of pixels allowed to
+	// turn black and white when the tonal range is stretched. Clipping a
+	// little keeps a few stray pixels from defeating the stretch.
+	ShadowClip    float64
+	HighlightClip float64
+	// PerChannel stretches red, green and blue independently, which also
+	// neutralises colour casts. Otherwise all channels are stretched by the
+	// luminance range so colours are kept.
+	PerChannel bool
+}
+
+// AutoLevels stretches the tonal range of img to cover 0-255.
+func (i *ImagingImpl) AutoLevels(img image.Image, opts AutoLevelsOptions) (*image.NRGBA, error) {
+	if !(opts.ShadowClip >= 0 && opts.HighlightClip >= 0 && opts.ShadowClip+opts.HighlightClip < 100) {
+		return nil, fmt.Errorf("imaging: invalid clip percentages %v and %v", opts.ShadowClip, opts.HighlightClip)
+	}
+
+	src := imaging.Clone(img)
+	hist, err := NewHistogram(src, MaxHistogramBins)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []*ChannelStats{&hist.Luminance, &hist.Luminance, &hist.Luminance}
+	if opts.PerChannel {
+		channels = []*ChannelStats{&hist.Red, &hist.Green, &hist.Blue}
+	}
+
+	var luts [3][256]uint8
+	for c, channel := range channels {
+		low, high := channel.clipRange(hist.Pixels, opts.ShadowClip, opts.HighlightClip)
+		luts[c] = stretchLUT(low, high)
+	}
+
+	return applyLUTs(src, luts), nil
+}
+
+// clipRange returns the values below and above which the given percentages
+// of pixels lie.
+func (c *ChannelStats) clipRange(pixels int, shadowClip, highlightClip float64) (uint8, uint8) {
+	lowLimit := float64(pixels) * shadowClip / 100
+	highLimit := float64(pixels) * highlightClip / 100
+
+	low, seen := 0, 0
+	for ; low < 255; low++ {
+		seen += c.counts[low]
+		if float64(seen) > lowLimit {
+			break
+		}
+	}
+
+	high, seen := 255, 0
+	for ; high > low; high-- {
+		seen += c.counts[high]
+		if float64(seen) > highLimit {
+			break
+		}
+	}
+
+	return uint8(low), uint8(high)
+}
+
+func stretchLUT(low, high uint8) [256]uint8 {
+	var lut [256]uint8
+	for value := range lut {
+		if high <= low {
+			lut[value] = uint8(value)
+			continue
+		}
+		lut[value] = clampUint8(float64(value-int(low)) * 255 / float64(high-low))
+	}
+
+	return lut
+}
+
+// applyLUTs maps the red, green and blue channels through their tables.
+func applyLUTs(src *image.NRGBA, luts [3][256]uint8) *image.NRGBA {
+	dst := image.NewNRGBA(src.Rect)
+
+	parallelRows(src.Rect.Dy(), func(start, end int) {
+		for idx := start * src.Stride; idx < end*src.Stride; idx += 4 {
+			dst.Pix[idx] = luts[0][src.Pix[idx]]
+			dst.Pix[idx+1] = luts[1][src.Pix[idx+1]]
+			dst.Pix[idx+2] = luts[2][src.Pix[idx+2]]
+			dst.Pix[idx+3] = src.Pix[idx+3]
+		}
+	})
+
+	return dst
+}
+
+// Equalize spreads the luminance of img evenly over 0-255, keeping the
+// chroma of every pixel.
+func (i *ImagingImpl) Equalize(img image.Image) *image.NRGBA {
+	src := imaging.Clone(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+
+	var counts [256]int
+	for idx := 0; idx < len(src.Pix); idx += 4 {
+		y, _, _ := color.RGBToYCbCr(src.Pix[idx], src.Pix[idx+1], src.Pix[idx+2])
+		counts[y]++
+	}
+	lut := equalizeLUT(counts[:], width*height)
+
+	return mapLuma(src, func(x, y int, luma uint8) uint8 {
+		return lut[luma]
+	})
+}
+
+// equalizeLUT maps values through the cumulative distribution of counts,
+// scaled so the darkest occupied value becomes 0.
+func equalizeLUT(counts []int, total int) [256]uint8 {
+	var lut [256]uint8
+
+	cdfMin, cumulative := 0, 0
+	for value, count := range counts {
+		cumulative += count
+		if cdfMin == 0 {
+			cdfMin = cumulative
+		}
+
+		if total == cdfMin {
+			lut[value] = uint8(value)
+			continue
+		}
+		lut[value] = clampUint8(float64(cumulative-cdfMin) * 255 / float64(total-cdfMin))
+	}
+
+	return lut
+}
+
+type CLAHEOptions struct {
+	// TileSize is the width and height in pixels of the regions equalized
+	// separately (default 64).
+	TileSize int
+	// ClipLimit caps each histogram bin at this multiple of the average bin
+	// count before equalizing, which limits how much noise is amplified in
+	// flat regions (default 2).
+	ClipLimit float64
+}
+
+// CLAHE applies contrast-limited adaptive histogram equalization to the
+// luminance of img. Every tile gets its own mapping, blended bilinearly
+// between neighbouring tiles so no seams show.
+func (i *ImagingImpl) CLAHE(img image.Image, opts CLAHEOptions) (*image.NRGBA, error) {
+	if opts.TileSize == 0 {
+		opts.TileSize = defaultCLAHETile
+	}
+	if opts.ClipLimit == 0 {
+		opts.ClipLimit = defaultCLAHELimit
+	}
+	if opts.TileSize < MinCLAHETile || opts.TileSize > MaxCLAHETile || opts.ClipLimit < 1 || opts.ClipLimit > MaxCLAHELimit {
+		return nil, fmt.Errorf("imaging: invalid CLAHE tile size %d or clip limit %v", opts.TileSize, opts.ClipLimit)
+	}
+
+	src := imaging.Clone(img)
+	width, height := src.Rect.Dx(), src.Rect.Dy()
+	tilesX := max(1, (width+opts.TileSize-1)/opts.TileSize)
+	tilesY := max(1, (height+opts.TileSize-1)/opts.TileSize)
+
+	luts := make([][256]uint8, tilesX*tilesY)
+	parallelRows(tilesY, func(start, end int) {
+		for ty := start; ty < end; ty++ {
+			for tx := 0; tx < tilesX; tx++ {
+				luts[ty*tilesX+tx] = claheTileLUT(src, tx*opts.TileSize, ty*opts.TileSize, opts.TileSize, opts.ClipLimit)
+			}
+		}
+	})
+
+	// tile mappings are anchored at the tile centres
+	tileCoord := func(pos, tiles int) (int, int, float64) {
+		f := (float64(pos)+0.5)/float64(opts.TileSize) - 0.5
+		t0 := clampInt(int(math.Floor(f)), 0, tiles-1)
+		t1 := clampInt(t0+1, 0, tiles-1)
+		return t0, t1, math.Max(0, math.Min(1, f-float64(t0)))
+	}
+
+	return mapLuma(src, func(x, y int, luma uint8) uint8 {
+		x0, x1, fx := tileCoord(x, tilesX)
+		y0, y1, fy := tileCoord(y, tilesY)
+
+		top := (1-fx)*float64(luts[y0*tilesX+x0][luma]) + fx*float64(luts[y0*tilesX+x1][luma])
+		bottom := (1-fx)*float64(luts[y1*tilesX+x0][luma]) + fx*float64(luts[y1*tilesX+x1][luma])
+		return clampUint8((1-fy)*top + fy*bottom)
+	}), nil
+}
+
+func claheTileLUT(src *image.NRGBA, x0, y0, size int, clipLimit float64) [256]uint8 {
+	x1 := min(x0+size, src.Rect.Dx())
+	y1 := min(y0+size, src.Rect.Dy())
+
+	var counts [256]int
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			px := src.Pix[y*src.Stride+x*4:]
+			luma, _, _ := color.RGBToYCbCr(px[0], px[1], px[2])
+			counts[luma]++
+		}
+	}
+	total := (x1 - x0) * (y1 - y0)
+
+	// clip the histogram and hand the excess out evenly
+	limit := max(1, int(clipLimit*float64(total)/256))
+	excess := 0
+	for value, count := range counts {
+		if count > limit {
+			excess += count - limit
+			counts[value] = limit
+		}
+	}
+	for value := range counts {
+		counts[value] += excess / 256
+		if value < excess%256 {
+			counts[value]++
+		}
+	}
+
+	// a plain cumulative mapping; unlike global equalization the darkest
+	// value is not pinned to black, which would exaggerate dark tiles
+	var lut [256]uint8
+	cumulative := 0
+	for value, count := range counts {
+		cumulative += count
+		lut[value] = clampUint8(float64(cumulative) * 255 / float64(total))
+	}
+
+	return lut
+}
+
+// mapLuma replaces the luminance of every pixel with fn's result, keeping
+// the chroma and alpha.
+func mapLuma(src *image.NRGBA, fn func(x, y int, luma uint8) uint8) *image.NRGBA {
+	width := src.Rect.Dx()
+	dst := image.NewNRGBA(src.Rect)
+
+	parallelRows(src.Rect.Dy(), func(start, end int) {
+		for y := start; y < end; y++ {
+			for x := 0; x < width; x++ {
+				idx := y*src.Stride + x*4
+				luma, cb, cr := color.RGBToYCbCr(src.Pix[idx], src.Pix[idx+1], src.Pix[idx+2])
+				r, g, b := color.YCbCrToRGB(fn(x, y, luma), cb, cr)
+
+				dst.Pix[idx], dst.Pix[idx+1], dst.Pix[idx+2], dst.Pix[idx+3] = r, g, b, src.Pix[idx+3]
+			}
+		}
+	})
+
+	return dst
+}
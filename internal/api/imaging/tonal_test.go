@@ -0,0 +1,81 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dimImage is a low-contrast horizontal grey ramp from 100 to 131.
+func dimImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(100 + x)
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestAutoLevels(t *testing.T) {
+	im := &ImagingImpl{}
+
+	out, err := im.AutoLevels(dimImage(), AutoLevelsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0), out.NRGBAAt(0, 0).R)
+	assert.Equal(t, uint8(255), out.NRGBAAt(31, 0).R)
+
+	// clipping a tenth on both ends pushes the outer columns to the limits
+	out, err = im.AutoLevels(dimImage(), AutoLevelsOptions{ShadowClip: 10, HighlightClip: 10})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(0), out.NRGBAAt(2, 0).R)
+	assert.Equal(t, uint8(255), out.NRGBAAt(29, 0).R)
+
+	_, err = im.AutoLevels(dimImage(), AutoLevelsOptions{ShadowClip: 60, HighlightClip: 50})
+	assert.Error(t, err)
+}
+
+func TestAutoLevelsPerChannel(t *testing.T) {
+	im := &ImagingImpl{}
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 50, G: 20, B: 20, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{R: 200, G: 100, B: 60, A: 255})
+
+	out, err := im.AutoLevels(src, AutoLevelsOptions{PerChannel: true})
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0, G: 0, B: 0, A: 255}, out.NRGBAAt(0, 0))
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, out.NRGBAAt(1, 0))
+}
+
+func TestEqualize(t *testing.T) {
+	out := (&ImagingImpl{}).Equalize(dimImage())
+
+	assert.Equal(t, uint8(0), out.NRGBAAt(0, 0).R)
+	assert.Equal(t, uint8(255), out.NRGBAAt(31, 0).R)
+	// an even ramp stays even, roughly 8 levels apart
+	assert.InDelta(t, 8*16, int(out.NRGBAAt(16, 0).R), 4)
+}
+
+func TestCLAHE(t *testing.T) {
+	im := &ImagingImpl{}
+	src := dimImage()
+
+	out, err := im.CLAHE(src, CLAHEOptions{TileSize: 16, ClipLimit: 4})
+	require.NoError(t, err)
+
+	spread := func(img *image.NRGBA) int {
+		return int(img.NRGBAAt(31, 16).R) - int(img.NRGBAAt(0, 16).R)
+	}
+	assert.Greater(t, spread(out), spread(src))
+	// no seams: neighbouring columns stay close across tile boundaries
+	for x := 1; x < 32; x++ {
+		assert.InDelta(t, out.NRGBAAt(x-1, 16).R, out.NRGBAAt(x, 16).R, 20, "column %d", x)
+	}
+
+	_, err = im.CLAHE(src, CLAHEOptions{TileSize: 4})
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package imaging
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+type UnsharpMaskOptions struct {
+	// Radius is the sigma of the Gaussian blur the mask is built from.
+	Radius float64
+	// Amount scales the detail added back, 1 adds it unchanged.
+	Amount float64
+	// Threshold is the smallest difference from the blurred image, from 0 to
+	// 255, that gets sharpened. Raising it leaves flat areas and noise alone.
+	Threshold float64
+	// LuminanceOnly sharpens brightness only, which avoids colour fringes
+	// along high-contrast edges.
+	LuminanceOnly bool
+}
+
+func (i *ImagingImpl) UnsharpMask(img image.Image, opts UnsharpMaskOptions) *image.NRGBA {
+	src := imaging.Clone(img)
+	blurred := imaging.Blur(src, opts.Radius)
+	dst := image.NewNRGBA(src.Bounds())
+
+	parallelRows(src.Rect.Dy(), func(start, end int) {
+		for idx := start * src.Stride; idx < end*src.Stride; idx += 4 {
+			px, blurPx, out := src.Pix[idx:idx+4], blurred.Pix[idx:idx+4], dst.Pix[idx:idx+4]
+			out[3] = px[3]
+
+			if opts.LuminanceOnly {
+				// shift every channel by the same amount so hue and saturation
+				// are kept
+				diff := luminance(px) - luminance(blurPx)
+				if math.Abs(diff) < opts.Threshold {
+					copy(out[:3], px[:3])
+					continue
+				}
+				for c := 0; c < 3; c++ {
+					out[c] = clampUint8(float64(px[c]) + opts.Amount*diff)
+				}
+				continue
+			}
+
+			for c := 0; c < 3; c++ {
+				diff := float64(px[c]) - float64(blurPx[c])
+				if math.Abs(diff) < opts.Threshold {
+					out[c] = px[c]
+					continue
+				}
+				out[c] = clampUint8(float64(px[c]) + opts.Amount*diff)
+			}
+		}
+	})
+
+	return dst
+}
+
+// luminance returns the Rec. 601 luma of an NRGBA pixel.
+func luminance(px []uint8) float64 {
+	return 0.299*float64(px[0]) + 0.587*float64(px[1]) + 0.114*float64(px[2])
+}
+
+func clampUint8(value float64) uint8 {
+	switch {
+	case value <= 0:
+		return 0
+	case value >= 255:
+		return 255
+	default:
+		return uint8(value + 0.5)
+	}
+}
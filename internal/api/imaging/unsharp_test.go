@@ -0,0 +1,70 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// edgeImage is dark on the left half and light on the right.
+func edgeImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			c := color.NRGBA{R: 80, G: 100, B: 120, A: 255}
+			if x >= 4 {
+				c = color.NRGBA{R: 160, G: 180, B: 200, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestUnsharpMask(t *testing.T) {
+	im := &ImagingImpl{}
+	src := edgeImage()
+
+	testcases := []struct{
+		name string
+		opts UnsharpMaskOptions
+		check func(t *testing.T, out *image.NRGBA)
+	}{
+		{
+			name: "Increases contrast across an edge",
+			opts: UnsharpMaskOptions{Radius: 1, Amount: 1},
+			check: func(t *testing.T, out *image.NRGBA) {
+				assert.Less(t, out.NRGBAAt(3, 4).R, src.NRGBAAt(3, 4).R)
+				assert.Greater(t, out.NRGBAAt(4, 4).R, src.NRGBAAt(4, 4).R)
+				// flat areas away from the edge are untouched
+				assert.Equal(t, src.NRGBAAt(0, 4), out.NRGBAAt(0, 4))
+			},
+		},
+		{
+			name: "Threshold above the contrast leaves the image unchanged",
+			opts: UnsharpMaskOptions{Radius: 1, Amount: 1, Threshold: 255},
+			check: func(t *testing.T, out *image.NRGBA) {
+				assert.Equal(t, src.Pix, out.Pix)
+			},
+		},
+		{
+			name: "Luminance only shifts channels equally",
+			opts: UnsharpMaskOptions{Radius: 1, Amount: 1, LuminanceOnly: true},
+			check: func(t *testing.T, out *image.NRGBA) {
+				before, after := src.NRGBAAt(4, 4), out.NRGBAAt(4, 4)
+				shift := int(after.R) - int(before.R)
+				assert.Greater(t, shift, 0)
+				assert.InDelta(t, shift, int(after.G)-int(before.G), 1)
+				assert.InDelta(t, shift, int(after.B)-int(before.B), 1)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.check(t, im.UnsharpMask(src, tc.opts))
+		})
+	}
+}
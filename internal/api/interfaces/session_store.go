@@ -4,10 +4,39 @@ import (
 	"time"
 )
 
+type SessionImage struct {
+	OriginalFilename string `json:"originalFilename"`
+	TempPath         string `json:"tempPath"`
+}
+
+// UploadState tracks a resumable upload that has not received all of its
+// bytes yet.
+type UploadState struct {
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	PartPath  string    `json:"partPath"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 type SessionData struct {
 	OriginalFilename string    `json:"originalFilename"`
     TempPath         string    `json:"tempPath"`
     UploadTime       time.Time `json:"uploadTime"`
+	// ContentHash is the hex SHA-256 of the file at TempPath, and
+	// ModifiedTime is when that file was produced.
+	ContentHash      string    `json:"contentHash,omitempty"`
+	ModifiedTime     time.Time `json:"modifiedTime,omitempty"`
+	// Images lists every image of a multi-image session. Operations apply to
+	// the image at TempPath, which is the first one.
+	Images           []SessionImage `json:"images,omitempty"`
+	// Upload is set while a resumable upload is still in progress.
+	Upload           *UploadState `json:"upload,omitempty"`
+}
+
+// Ready reports whether the session holds a complete image that operations
+// can be applied to.
+func (s SessionData) Ready() bool {
+	return s.Upload == nil && s.TempPath != ""
 }
 
 type SessionStore interface {
@@ -0,0 +1,13 @@
+package metrics
+
+import (
+	"expvar"
+)
+
+// Counters are published by expvar and served at /debug/vars on the debug
+// listener.
+var (
+	// OutputFormats counts images served per format chosen by content
+	// negotiation.
+	OutputFormats = expvar.NewMap("negotiated_output_formats")
+)
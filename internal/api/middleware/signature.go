@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
+	"go.uber.org/zap"
+)
+
+// RequireSignature rejects requests whose URL does not carry a valid,
+// unexpired signature. With a nil signer, URL signing is disabled and every
+// request is let through.
+func RequireSignature(signer *signing.Signer, next http.Handler) http.Handler {
+	if signer == nil {
+		return next
+	}
+
+	resp := response.NewResponse()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := signer.Verify(r.URL.EscapedPath(), r.URL.Query()); err != nil {
+			logger.LoggerFromContext(r.Context()).Warn("Rejected unsigned request", zap.Error(err))
+			resp.WriteError(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
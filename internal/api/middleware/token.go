@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/logger"
+	"github.com/dylan0804/image-processing-tool/internal/api/response"
+)
+
+// RequireToken rejects requests that do not carry token as a bearer token.
+// An empty token rejects every request, so a missing setting never leaves
+// the routes open.
+func RequireToken(token string, next http.Handler) http.Handler {
+	resp := response.NewResponse()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			logger.LoggerFromContext(r.Context()).Warn("Rejected request without a valid token")
+			resp.WriteError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
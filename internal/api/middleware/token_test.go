@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	testcases := []struct{
+		name string
+		token string
+		header string
+		wantStatus int
+	}{
+		{name: "Valid token", token: "secret", header: "Bearer secret", wantStatus: http.StatusNoContent},
+		{name: "Wrong token", token: "secret", header: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "Missing header", token: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "Not a bearer token", token: "secret", header: "secret", wantStatus: http.StatusUnauthorized},
+		{name: "No token configured", header: "Bearer ", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+
+			RequireToken(tc.token, ok).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+		})
+	}
+}
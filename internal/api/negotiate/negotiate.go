@@ -0,0 +1,146 @@
+package negotiate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+)
+
+// Source names the format an image is already stored in.
+const Source = "source"
+
+// Policy chooses an output format from what a client accepts. Among the
+// formats the client rates highest, the one earliest in the preference list
+// wins. Supported formats missing from the list rank after every listed one,
+// so newly added encoders are still offered.
+type Policy struct {
+	preference []string
+}
+
+// DefaultPolicy keeps the stored format whenever the client accepts it.
+func DefaultPolicy() Policy {
+	return Policy{preference: []string{Source, "png", "jpeg", "gif", "tiff", "bmp"}}
+}
+
+// NewPolicy builds a policy from format names, where "source" stands for the
+// format the image is already in.
+func NewPolicy(names []string) (Policy, error) {
+	policy := Policy{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != Source {
+			format, err := imaging.FormatFromName(name)
+			if err != nil {
+				return Policy{}, fmt.Errorf("negotiate: unsupported format %q", name)
+			}
+			name = imaging.FormatName(format)
+		}
+		policy.preference = append(policy.preference, name)
+	}
+
+	return policy, nil
+}
+
+// candidates lists every supported format in preference order.
+func (p Policy) candidates(source imaging.Format) []imaging.Format {
+	var ordered []imaging.Format
+	seen := make(map[imaging.Format]bool)
+
+	add := func(format imaging.Format) {
+		if !seen[format] {
+			seen[format] = true
+			ordered = append(ordered, format)
+		}
+	}
+
+	for _, name := range p.preference {
+		if name == Source {
+			add(source)
+			continue
+		}
+		format, _ := imaging.FormatFromName(name)
+		add(format)
+	}
+	for _, format := range imaging.SupportedFormats() {
+		add(format)
+	}
+
+	return ordered
+}
+
+// Negotiate returns the format to serve for the given Accept header, or false
+// if the client accepts none of the supported formats. Without an Accept
+// header the source format is kept.
+func (p Policy) Negotiate(accept string, source imaging.Format) (imaging.Format, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return source, true
+	}
+
+	ranges := parseAccept(accept)
+
+	best, bestQ := source, 0.0
+	for _, format := range p.candidates(source) {
+		if q := quality(ranges, imaging.ContentType(format)); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q >= 0 && q <= 1 {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+
+	return ranges
+}
+
+// quality is the q-value of the most specific range matching contentType.
+func quality(ranges []mediaRange, contentType string) float64 {
+	typ, subtype, _ := strings.Cut(contentType, "/")
+
+	q, specificity := 0.0, -1
+	for _, mr := range ranges {
+		var s int
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			s = 2
+		case mr.typ == typ && mr.subtype == "*":
+			s = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = mr.q, s
+		}
+	}
+
+	return q
+}
@@ -0,0 +1,91 @@
+package negotiate
+
+import (
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate(t *testing.T) {
+	testcases := []struct{
+		name string
+		preference []string
+		accept string
+		source imaging.Format
+		want imaging.Format
+		wantOK bool
+	}{
+		{
+			name: "No Accept header keeps the source format",
+			source: imaging.TIFF,
+			want: imaging.TIFF,
+			wantOK: true,
+		},
+		{
+			name: "Wildcard keeps the source format",
+			accept: "image/*,*/*;q=0.8",
+			source: imaging.BMP,
+			want: imaging.BMP,
+			wantOK: true,
+		},
+		{
+			name: "Source format not accepted",
+			accept: "image/png,image/jpeg;q=0.9",
+			source: imaging.TIFF,
+			want: imaging.PNG,
+			wantOK: true,
+		},
+		{
+			name: "Highest quality wins over preference",
+			accept: "image/png;q=0.5,image/jpeg",
+			source: imaging.TIFF,
+			want: imaging.JPEG,
+			wantOK: true,
+		},
+		{
+			name: "Server preference breaks ties",
+			preference: []string{"jpeg", "png"},
+			accept: "image/*",
+			source: imaging.PNG,
+			want: imaging.JPEG,
+			wantOK: true,
+		},
+		{
+			name: "Explicit exclusion",
+			accept: "image/*,image/png;q=0",
+			source: imaging.PNG,
+			want: imaging.JPEG,
+			wantOK: true,
+		},
+		{
+			name: "Nothing acceptable",
+			accept: "text/html",
+			source: imaging.PNG,
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := DefaultPolicy()
+			if tc.preference != nil {
+				var err error
+				policy, err = NewPolicy(tc.preference)
+				require.NoError(t, err)
+			}
+
+			got, ok := policy.Negotiate(tc.accept, tc.source)
+			assert.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPolicyRejectsUnknownFormat(t *testing.T) {
+	_, err := NewPolicy([]string{"png", "webp"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,241 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidURL         = errors.New("remote: invalid URL")
+	ErrBlockedDestination = errors.New("remote: destination address is not allowed")
+	ErrTooManyRedirects   = errors.New("remote: too many redirects")
+	ErrTooLarge           = errors.New("remote: response body exceeds size limit")
+	ErrContentType        = errors.New("remote: response is not an image")
+	ErrUpstream           = errors.New("remote: upstream request failed")
+)
+
+// blockedPrefixes are the ranges a fetch may never reach unless explicitly
+// allow-listed: loopback, private, link-local (including cloud metadata
+// endpoints), carrier-grade NAT and other non-public space, as well as the
+// NAT64 and 6to4 ranges that embed IPv4 addresses, which gateways translate
+// back into any of the above.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+type Options struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	// AllowedHosts lists hostnames and CIDR ranges that may be fetched even
+	// though they resolve to blocked addresses.
+	AllowedHosts []string
+}
+
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+	hosts    map[string]bool
+	prefixes []netip.Prefix
+}
+
+type Result struct {
+	Data        []byte
+	Filename    string
+	ContentType string
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	f := &Fetcher{
+		maxBytes: opts.MaxBytes,
+		hosts:    make(map[string]bool),
+	}
+
+	for _, entry := range opts.AllowedHosts {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			f.prefixes = append(f.prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(entry); err == nil {
+			f.prefixes = append(f.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			f.hosts[entry] = true
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+
+	transport := &http.Transport{
+		// never route through an environment proxy, the address checks
+		// below must see the real destination
+		Proxy:                 nil,
+		DialContext:           f.dialContext(dialer),
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+			return checkScheme(req.URL)
+		},
+	}
+
+	return f
+}
+
+// dialContext resolves the host itself and connects to a vetted IP, so a
+// DNS answer cannot change between the check and the connection.
+func (f *Fetcher) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+
+		hostAllowed := f.hosts[strings.ToLower(host)]
+
+		var lastErr error = fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+		for _, ip := range ips {
+			ip = ip.Unmap()
+			if !hostAllowed && !f.addrAllowed(ip) {
+				continue
+			}
+
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+
+		return nil, lastErr
+	}
+}
+
+func (f *Fetcher) addrAllowed(ip netip.Addr) bool {
+	for _, prefix := range f.prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidURL)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+
+	return nil
+}
+
+// Fetch downloads the image at rawURL, enforcing the configured limits.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Result, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		for _, known := range []error{ErrBlockedDestination, ErrTooManyRedirects, ErrInvalidURL} {
+			if errors.Is(err, known) {
+				return nil, err
+			}
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrUpstream, resp.StatusCode)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") && contentType != "application/octet-stream" {
+		return nil, fmt.Errorf("%w: got %q", ErrContentType, contentType)
+	}
+
+	if resp.ContentLength > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpstream, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	filename := path.Base(resp.Request.URL.Path)
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = params["filename"]
+	}
+
+	return &Result{
+		Data:        data,
+		Filename:    filename,
+		ContentType: contentType,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddrAllowed(t *testing.T) {
+	f := NewFetcher(Options{AllowedHosts: []string{"10.1.0.0/16", "192.168.1.7"}})
+
+	testcases := []struct{
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "10.0.0.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "100.64.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "fd00::1", want: false},
+		{addr: "fe80::1", want: false},
+		// NAT64 and 6to4 addresses embedding the metadata endpoint
+		{addr: "64:ff9b::a9fe:a9fe", want: false},
+		{addr: "64:ff9b:1::a9fe:a9fe", want: false},
+		{addr: "2002:a9fe:a9fe::", want: false},
+		// allow-listed ranges and addresses
+		{addr: "10.1.2.3", want: true},
+		{addr: "192.168.1.7", want: true},
+		{addr: "192.168.1.8", want: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.addr, func(t *testing.T) {
+			assert.Equal(t, tc.want, f.addrAllowed(netip.MustParseAddr(tc.addr)))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	var private string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/photo.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("jpeg"))
+		case "/redirect":
+			http.Redirect(w, r, "/photo.jpg", http.StatusFound)
+		case "/private":
+			// the same server under its address, which is not allow-listed
+			http.Redirect(w, r, private+"/photo.jpg", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	private = server.URL
+	byName := "http://localhost:" + serverURL.Port()
+	mapped := "http://[::ffff:127.0.0.1]:" + serverURL.Port()
+
+	testcases := []struct{
+		name string
+		url string
+		opts Options
+		wantErr error
+	}{
+		{
+			name: "Allow-listed address",
+			url: server.URL + "/photo.jpg",
+			opts: Options{AllowedHosts: []string{"127.0.0.1"}},
+		},
+		{
+			name: "Allow-listed host name",
+			url: byName + "/photo.jpg",
+			opts: Options{AllowedHosts: []string{"localhost"}},
+		},
+		{
+			name: "Loopback is blocked",
+			url: server.URL + "/photo.jpg",
+			wantErr: ErrBlockedDestination,
+		},
+		{
+			name: "IPv4-mapped loopback is blocked",
+			url: mapped + "/photo.jpg",
+			wantErr: ErrBlockedDestination,
+		},
+		{
+			name: "IPv4-mapped address is checked as IPv4",
+			url: mapped + "/photo.jpg",
+			opts: Options{AllowedHosts: []string{"127.0.0.1"}},
+		},
+		{
+			name: "Redirect to a private address",
+			url: byName + "/private",
+			opts: Options{AllowedHosts: []string{"localhost"}, MaxRedirects: 3},
+			wantErr: ErrBlockedDestination,
+		},
+		{
+			name: "Redirect within the limit",
+			url: server.URL + "/redirect",
+			opts: Options{AllowedHosts: []string{"127.0.0.1"}, MaxRedirects: 1},
+		},
+		{
+			name: "Redirects disabled",
+			url: server.URL + "/redirect",
+			opts: Options{AllowedHosts: []string{"127.0.0.1"}},
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name: "Unsupported scheme",
+			url: "file:///etc/passwd",
+			wantErr: ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Timeout = 5 * time.Second
+			tc.opts.MaxBytes = 1 << 20
+
+			result, err := NewFetcher(tc.opts).Fetch(context.Background(), tc.url)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []byte("jpeg"), result.Data)
+			assert.Equal(t, "photo.jpg", result.Filename)
+		})
+	}
+}
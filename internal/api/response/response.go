@@ -35,4 +35,20 @@ func (r *Response) WriteError(w http.ResponseWriter, errMsg string, code int) {
 	}
 
 	json.NewEncoder(w).Encode(&resp)
-}
\ No newline at end of file
+}
+
+// WriteValidationError reports invalid request parameters. err is encoded
+// as the error field, so it should be a type that marshals to something
+// useful such as validation.Errors.
+func (r *Response) WriteValidationError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	resp := &BaseResponse{
+		Success: false,
+		Data: "Invalid request parameters",
+		Err: err,
+	}
+
+	json.NewEncoder(w).Encode(&resp)
+}
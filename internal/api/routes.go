@@ -1,23 +1,30 @@
 package api
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/dylan0804/image-processing-tool/internal/api/config"
 	"github.com/dylan0804/image-processing-tool/internal/api/handlers"
 	"github.com/dylan0804/image-processing-tool/internal/api/middleware"
+	"github.com/dylan0804/image-processing-tool/internal/api/signing"
 	"go.uber.org/zap"
 )
 
 type Route struct {
 	mux  *http.ServeMux
 	imageHandler *handlers.ImageHandler
+	signer *signing.Signer
+	config config.Config
 	logger *zap.Logger
 }
 
-func NewRoutes(mux *http.ServeMux, i *handlers.ImageHandler, logger *zap.Logger) *Route {
+func NewRoutes(mux *http.ServeMux, i *handlers.ImageHandler, signer *signing.Signer, config config.Config, logger *zap.Logger) *Route {
 	return &Route{
 		mux: mux,
 		imageHandler: i,
+		signer: signer,
+		config: config,
 		logger: logger,
 	}
 }
@@ -30,12 +37,91 @@ func (r *Route) InitRoutes() {
 	})
 
 	r.mux.HandleFunc("POST /api/v1/image/upload", r.imageHandler.UploadImage)
-	r.mux.HandleFunc("POST /api/v1/image/resize", r.imageHandler.BlurImage)
-	r.mux.HandleFunc("POST /api/v1/image/sharpen", r.imageHandler.SharpenImage)
+	r.mux.HandleFunc("POST /api/v1/image/import", r.imageHandler.ImportImage)
+	r.mux.Handle("POST /api/v1/image/resize", r.session(r.imageHandler.BlurImage))
+	r.mux.Handle("POST /api/v1/image/sharpen", r.session(r.imageHandler.SharpenImage))
+	r.mux.Handle("POST /api/v1/image/unsharp", r.session(r.imageHandler.UnsharpMaskImage))
+	r.mux.Handle("POST /api/v1/image/convolve", r.session(r.imageHandler.ConvolveImage))
+	r.mux.HandleFunc("GET /api/v1/kernels", r.imageHandler.ListKernels)
+	r.mux.Handle("POST /api/v1/image/edges", r.session(r.imageHandler.DetectEdges))
+	r.mux.Handle("POST /api/v1/image/denoise", r.session(r.imageHandler.DenoiseImage))
+	r.mux.Handle("POST /api/v1/image/autotone", r.session(r.imageHandler.AutoToneImage))
+	r.mux.Handle("POST /api/v1/image/levels", r.session(r.imageHandler.LevelsImage))
+	r.mux.Handle("POST /api/v1/image/curves", r.session(r.imageHandler.CurvesImage))
+	r.mux.Handle("POST /api/v1/image/lut", r.session(r.imageHandler.ApplyLUT))
+	r.mux.HandleFunc("GET /api/v1/luts", r.imageHandler.ListLUTs)
+	r.mux.Handle("PUT /api/v1/luts/{name}", r.assets(r.imageHandler.UploadLUT))
+	r.mux.Handle("DELETE /api/v1/luts/{name}", r.assets(r.imageHandler.DeleteLUT))
+	r.mux.Handle("POST /api/v1/image/overlay", r.session(r.imageHandler.OverlayImage))
+	r.mux.HandleFunc("GET /api/v1/watermarks", r.imageHandler.ListWatermarks)
+	r.mux.Handle("PUT /api/v1/watermarks/{name}", r.assets(r.imageHandler.UploadWatermark))
+	r.mux.Handle("DELETE /api/v1/watermarks/{name}", r.assets(r.imageHandler.DeleteWatermark))
+	r.mux.Handle("POST /api/v1/image/text", r.session(r.imageHandler.DrawText))
+	r.mux.Handle("POST /api/v1/image/draw", r.session(r.imageHandler.DrawShapes))
+	r.mux.Handle("POST /api/v1/image/redact", r.session(r.imageHandler.Redact))
+	r.mux.Handle("POST /api/v1/image/mask", r.session(r.imageHandler.MaskImage))
+	r.mux.Handle("POST /api/v1/image/pad", r.session(r.imageHandler.PadImage))
+	r.mux.Handle("POST /api/v1/image/border", r.session(r.imageHandler.AddBorder))
+	r.mux.HandleFunc("GET /api/v1/masks", r.imageHandler.ListMasks)
+	r.mux.Handle("PUT /api/v1/masks/{name}", r.assets(r.imageHandler.UploadMask))
+	r.mux.Handle("DELETE /api/v1/masks/{name}", r.assets(r.imageHandler.DeleteMask))
+	r.mux.HandleFunc("GET /api/v1/fonts", r.imageHandler.ListFonts)
+	r.mux.Handle("PUT /api/v1/fonts/{name}", r.assets(r.imageHandler.UploadFont))
+	r.mux.Handle("DELETE /api/v1/fonts/{name}", r.assets(r.imageHandler.DeleteFont))
+
+	// on-the-fly transformations, e.g. /img/{sessionId}/w_400,h_300,fit/blur_2
+	r.mux.Handle("GET /img/{sessionId}/{ops...}", r.signed(r.imageHandler.TransformImage))
+
+	r.mux.Handle("GET /api/v1/sessions/{sessionId}/download", r.signed(r.imageHandler.DownloadImage))
+	r.mux.HandleFunc("POST /api/v1/sessions/{sessionId}/signed-urls", r.imageHandler.CreateSignedURL)
+	r.mux.Handle("GET /api/v1/sessions/{sessionId}/histogram", r.session(r.imageHandler.SessionHistogram))
+
+	// resumable uploads (tus protocol)
+	r.mux.HandleFunc("OPTIONS /api/v1/tus", r.imageHandler.TusOptions)
+	r.mux.HandleFunc("OPTIONS /api/v1/tus/{id}", r.imageHandler.TusOptions)
+	r.mux.HandleFunc("POST /api/v1/tus", r.imageHandler.TusCreateUpload)
+	r.mux.HandleFunc("HEAD /api/v1/tus/{id}", r.imageHandler.TusUploadStatus)
+	r.mux.HandleFunc("PATCH /api/v1/tus/{id}", r.imageHandler.TusPatchUpload)
+	r.mux.HandleFunc("DELETE /api/v1/tus/{id}", r.imageHandler.TusDeleteUpload)
 
 	handler := middleware.LoggingMiddleware(r.logger, r.mux)
 
 	r.logger.Info("app running on port :8080")
 
 	http.ListenAndServe(":8080", handler)
+}
+
+// ServeDebug serves the expvar metrics on addr. They include the command
+// line and memory statistics, so they are kept off the public listener.
+func ServeDebug(addr string, logger *zap.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	logger.Info("debug endpoints running on " + addr)
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("Debug listener stopped", zap.Error(err))
+	}
+}
+
+// session guards a route that works on an existing session. With URL
+// signing configured it requires the signing token, otherwise knowing a
+// session ID is enough to use the session.
+func (r *Route) session(handler http.HandlerFunc) http.Handler {
+	if r.signer == nil {
+		return handler
+	}
+
+	return middleware.RequireToken(r.config.URLSigningToken, handler)
+}
+
+// assets guards a route that changes the shared assets. They are used by
+// every session, so changing them always takes the assets token.
+func (r *Route) assets(handler http.HandlerFunc) http.Handler {
+	return middleware.RequireToken(r.config.AssetsToken, handler)
+}
+
+// signed requires a valid URL signature when URL signing is configured.
+func (r *Route) signed(handler http.HandlerFunc) http.Handler {
+	return middleware.RequireSignature(r.signer, handler)
 }
\ No newline at end of file
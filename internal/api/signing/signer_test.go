@@ -0,0 +1,52 @@
+package signing
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	oldKey := Key{ID: "k1", Secret: []byte("first-secret")}
+	newKey := Key{ID: "k2", Secret: []byte("second-secret")}
+
+	signer, err := NewSigner([]Key{oldKey}, "")
+	require.NoError(t, err)
+
+	path := "/img/abc/blur_2"
+	expires := time.Now().Add(time.Hour)
+	query := signer.Sign(path, expires)
+
+	assert.NoError(t, signer.Verify(path, query))
+	assert.ErrorIs(t, signer.Verify("/img/abc/blur_20", query), ErrInvalidSignature)
+	assert.ErrorIs(t, signer.Verify(path, url.Values{}), ErrMissingSignature)
+
+	tampered := url.Values{}
+	for k, v := range query {
+		tampered[k] = v
+	}
+	tampered.Set(ParamExpires, "9999999999")
+	assert.ErrorIs(t, signer.Verify(path, tampered), ErrInvalidSignature)
+
+	// rotation: old URLs keep working while the new key signs
+	rotated, err := NewSigner([]Key{oldKey, newKey}, "k2")
+	require.NoError(t, err)
+	assert.NoError(t, rotated.Verify(path, query))
+	assert.Equal(t, "k2", rotated.Sign(path, expires).Get(ParamKeyID))
+
+	// once the old key is retired its URLs stop working
+	retired, err := NewSigner([]Key{newKey}, "k2")
+	require.NoError(t, err)
+	assert.ErrorIs(t, retired.Verify(path, query), ErrUnknownKey)
+
+	signer.now = func() time.Time { return expires.Add(time.Second) }
+	assert.ErrorIs(t, signer.Verify(path, query), ErrExpired)
+}
+
+func TestNewSignerRejectsUnknownActiveKey(t *testing.T) {
+	_, err := NewSigner([]Key{{ID: "k1", Secret: []byte("s")}}, "k9")
+	assert.Error(t, err)
+}
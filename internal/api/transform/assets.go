@@ -0,0 +1,114 @@
+package transform
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// MaxParsedAssetBytes bounds the estimated memory held by parsed assets.
+const MaxParsedAssetBytes = 64 << 20
+
+// parsedAssets keeps parsed LUTs, watermarks, masks and fonts keyed by
+// their version, so an asset is parsed once rather than on every request
+// that uses it. Parsed assets are shared between chains and must not be
+// modified.
+var parsedAssets = newAssetCache(MaxParsedAssetBytes)
+
+// loadAsset returns the parsed asset of kind called name. parse turns the
+// asset's content into its parsed form and estimates how much memory that
+// takes. Assets that are already parsed are neither read nor parsed again.
+func loadAsset(loader AssetLoader, kind, name string, parse func(data []byte) (any, int64, error)) (any, string, error) {
+	if loader == nil {
+		return nil, "", fmt.Errorf("%w: %s %q: assets are not available", ErrInvalidChain, kind, name)
+	}
+
+	version, err := loader.Version(kind, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if value, ok := parsedAssets.get(kind + "/" + version); ok {
+		return value, version, nil
+	}
+
+	data, version, err := loader.Load(kind, name)
+	if err != nil {
+		return nil, "", err
+	}
+	value, size, err := parse(data)
+	if err != nil {
+		return nil, "", err
+	}
+	parsedAssets.add(kind+"/"+version, value, size)
+
+	return value, version, nil
+}
+
+// decodeImageAsset decodes an image asset such as a watermark, estimating
+// its size as four bytes a pixel.
+func decodeImageAsset(data []byte, kind, name string) (any, int64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s %q: %w", kind, name, err)
+	}
+	size := img.Bounds().Size()
+
+	return img, int64(size.X) * int64(size.Y) * 4, nil
+}
+
+// assetCache is a least recently used cache bounded by the estimated size
+// of its values.
+type assetCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type assetEntry struct {
+	key   string
+	value any
+	size  int64
+}
+
+func newAssetCache(maxBytes int64) *assetCache {
+	return &assetCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *assetCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*assetEntry).value, true
+}
+
+func (c *assetCache) add(key string, value any, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok || size > c.maxBytes {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&assetEntry{key: key, value: value, size: size})
+	c.size += size
+
+	for c.size > c.maxBytes {
+		oldest := c.order.Remove(c.order.Back()).(*assetEntry)
+		delete(c.entries, oldest.key)
+		c.size -= oldest.size
+	}
+}
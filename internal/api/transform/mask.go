@@ -0,0 +1,195 @@
+package transform
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+)
+
+// masked applies its operations only where its mask is set, blending their
+// result with the original image by the strength of the mask.
+type masked struct {
+	// name of the mask image asset, for image masks
+	name string
+	opts imaging.MaskOptions
+	ops  []Operation
+}
+
+// Mask limits ops to the area described by opts, whose kind must not be
+// imaging.MaskImage.
+func Mask(opts imaging.MaskOptions, ops ...Operation) Operation {
+	return &masked{opts: opts, ops: ops}
+}
+
+// ImageMask limits ops to the area set in the mask image asset called
+// name. The chain must be resolved before it is applied.
+func ImageMask(name string, invert bool, ops ...Operation) Operation {
+	return &masked{name: name, opts: imaging.MaskOptions{Kind: imaging.MaskImage, Invert: invert}, ops: ops}
+}
+
+// newMask parses "mask_<kind>" with the invert flag and the arguments of
+// the kind:
+//
+//	rect and ellipse: the bounding rectangle (r_x:y:w:h) and feather width
+//	radial: the centre (at_x:y) and radius
+//	linear: the points the mask fades in from (from_x:y) and to (to_x:y)
+//	image: the name of the mask image asset (src)
+//
+// The operations it applies to are added by Parse.
+func newMask(args Args) (*masked, error) {
+	kind, err := imaging.MaskKindFromName(args.String("mask", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case imaging.MaskRadial:
+		err = args.Check("mask", "at", "radius", "invert")
+	case imaging.MaskLinear:
+		err = args.Check("mask", "from", "to", "invert")
+	case imaging.MaskImage:
+		err = args.Check("mask", "src", "invert")
+	default:
+		err = args.Check("mask", "r", "feather", "invert")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	required := map[imaging.MaskKind][]string{
+		imaging.MaskRect:    {"r"},
+		imaging.MaskEllipse: {"r"},
+		imaging.MaskRadial:  {"at", "radius"},
+		imaging.MaskLinear:  {"from", "to"},
+		imaging.MaskImage:   {"src"},
+	}
+	for _, key := range required[kind] {
+		if !args.Has(key) {
+			return nil, fmt.Errorf("%s is required", key)
+		}
+	}
+
+	m := &masked{opts: imaging.MaskOptions{Kind: kind, Invert: args.Has("invert")}}
+	if m.opts.Rect, err = rectArg(args, "r"); err != nil {
+		return nil, err
+	}
+	if m.opts.Feather, err = args.Float("feather", 0); err != nil {
+		return nil, err
+	}
+	if m.opts.Center, err = pointArg(args, "at"); err != nil {
+		return nil, err
+	}
+	if m.opts.Radius, err = args.Float("radius", 0); err != nil {
+		return nil, err
+	}
+	if m.opts.From, err = pointArg(args, "from"); err != nil {
+		return nil, err
+	}
+	if m.opts.To, err = pointArg(args, "to"); err != nil {
+		return nil, err
+	}
+
+	if kind == imaging.MaskImage {
+		if m.name = args.String("src", ""); m.name == "" {
+			return nil, fmt.Errorf("src must name a mask image")
+		}
+		return m, nil
+	}
+
+	if err := m.opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func pointArg(args Args, key string) (imaging.Point, error) {
+	if !args.Has(key) {
+		return imaging.Point{}, nil
+	}
+
+	points, err := pointsArg(args, key)
+	if err != nil {
+		return imaging.Point{}, err
+	}
+	if len(points) != 1 {
+		return imaging.Point{}, fmt.Errorf("%s must be x:y", key)
+	}
+
+	return points[0], nil
+}
+
+// resolve loads the mask image and the assets of the masked operations.
+func (m *masked) resolve(loader AssetLoader) (string, error) {
+	var versions []string
+
+	if m.name != "" {
+		img, version, err := loadAsset(loader, MaskAssets, m.name, func(data []byte) (any, int64, error) {
+			return decodeImageAsset(data, "mask", m.name)
+		})
+		if err != nil {
+			return "", err
+		}
+		m.opts.Image = img.(image.Image)
+		versions = append(versions, version)
+	}
+
+	opVersions, err := resolveOperations(m.ops, loader)
+	if err != nil {
+		return "", err
+	}
+	versions = append(versions, opVersions...)
+
+	return strings.Join(versions, ","), nil
+}
+
+func (m *masked) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	if m.opts.Kind == imaging.MaskImage && m.opts.Image == nil {
+		return nil, fmt.Errorf("transform: mask %q was not resolved", m.name)
+	}
+
+	filtered, err := applyOperations(im, img, m.ops)
+	if err != nil {
+		return nil, err
+	}
+	if filtered.Bounds().Size() != img.Bounds().Size() {
+		return nil, fmt.Errorf("%w: masked operations must keep the image size", ErrInvalidChain)
+	}
+
+	return im.BlendMask(img, filtered, m.opts)
+}
+
+func (m *masked) transparent() bool {
+	return transparent(m.ops)
+}
+
+func (m *masked) String() string {
+	parts := []string{"mask_" + imaging.MaskKindName(m.opts.Kind)}
+
+	switch m.opts.Kind {
+	case imaging.MaskRadial:
+		parts = append(parts, "at_"+formatPoints([]imaging.Point{m.opts.Center}), "radius_"+formatFloat(m.opts.Radius))
+	case imaging.MaskLinear:
+		parts = append(parts, "from_"+formatPoints([]imaging.Point{m.opts.From}), "to_"+formatPoints([]imaging.Point{m.opts.To}))
+	case imaging.MaskImage:
+		parts = append(parts, "src_"+m.name)
+	default:
+		rect := m.opts.Rect
+		parts = append(parts, fmt.Sprintf("r_%d:%d:%d:%d", rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()))
+		if m.opts.Feather != 0 {
+			parts = append(parts, "feather_"+formatFloat(m.opts.Feather))
+		}
+	}
+	if m.opts.Invert {
+		parts = append(parts, "invert")
+	}
+
+	segments := []string{strings.Join(parts, ",")}
+	for _, op := range m.ops {
+		segments = append(segments, op.String())
+	}
+
+	return strings.Join(append(segments, "unmask"), "/")
+}
@@ -0,0 +1,1653 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"golang.org/x/image/font/opentype"
+)
+
+func init() {
+	Register("resize", newResize)
+	Register("blur", newBlur)
+	Register("sharpen", newSharpen)
+	Register("unsharp", newUnsharpMask)
+	Register("convolve", newConvolve)
+	Register("edges", newEdges)
+	Register("denoise", newDenoise)
+	Register("autolevels", newAutoLevels)
+	Register("equalize", newEqualize)
+	Register("clahe", newCLAHE)
+	Register("levels", newLevels)
+	Register("curves", newCurves)
+	Register("lut", newCubeLUT)
+	Register("overlay", newOverlay)
+	Register("text", newText)
+	Register("draw", newDraw)
+	Register("redact", newRedact)
+	Register("pad", newPad)
+	Register("border", newBorder)
+}
+
+// Asset kinds that operations refer to by name.
+const (
+	LUTAssets       = "luts"
+	WatermarkAssets = "watermarks"
+	FontAssets      = "fonts"
+	MaskAssets      = "masks"
+)
+
+// maxSigma keeps blur and sharpen radii within what completes in reasonable
+// time on large images.
+const maxSigma = 100
+
+const (
+	maxUnsharpAmount    = 10
+	maxUnsharpThreshold = 255
+	maxConvolveBias     = 255
+	maxEdgeSigma        = 20
+	maxEdgeThreshold    = 255
+	maxDenoiseStrength  = 100
+	maxDenoiseRange     = 255
+	// maxCanvasSize is the largest side JPEG can encode; the image limits
+	// usually stop canvases well before it.
+	maxCanvasSize = 65535
+)
+
+type resize struct {
+	width  int
+	height int
+	mode   imaging.ResizeMode
+}
+
+var resizeModes = map[string]imaging.ResizeMode{
+	"fit":     imaging.ResizeFit,
+	"fill":    imaging.ResizeFill,
+	"stretch": imaging.ResizeStretch,
+}
+
+func newResize(args Args) (Operation, error) {
+	if err := args.Check("w", "h", "fit", "fill", "stretch"); err != nil {
+		return nil, err
+	}
+
+	width, err := args.Int("w", 0)
+	if err != nil {
+		return nil, err
+	}
+	height, err := args.Int("h", 0)
+	if err != nil {
+		return nil, err
+	}
+	if width < 0 || height < 0 || (width == 0 && height == 0) {
+		return nil, fmt.Errorf("w and h must be positive and at least one is required")
+	}
+
+	op := &resize{width: width, height: height, mode: imaging.ResizeFit}
+
+	modes := 0
+	for name, mode := range resizeModes {
+		if args.Has(name) {
+			op.mode = mode
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, fmt.Errorf("only one of fit, fill or stretch may be given")
+	}
+
+	return op, nil
+}
+
+func (r *resize) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Resize(img, r.width, r.height, r.mode)
+}
+
+func (r *resize) String() string {
+	parts := make([]string, 0, 3)
+	if r.width > 0 {
+		parts = append(parts, fmt.Sprintf("w_%d", r.width))
+	}
+	if r.height > 0 {
+		parts = append(parts, fmt.Sprintf("h_%d", r.height))
+	}
+	for name, mode := range resizeModes {
+		if mode == r.mode {
+			parts = append(parts, name)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type blur struct {
+	sigma float64
+}
+
+func Blur(sigma float64) Operation {
+	return &blur{sigma: sigma}
+}
+
+func newBlur(args Args) (Operation, error) {
+	sigma, err := sigmaArg(args, "blur")
+	if err != nil {
+		return nil, err
+	}
+
+	return &blur{sigma: sigma}, nil
+}
+
+func (b *blur) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Blur(img, b.sigma), nil
+}
+
+func (b *blur) String() string {
+	return "blur_" + formatFloat(b.sigma)
+}
+
+type sharpen struct {
+	sigma float64
+}
+
+func Sharpen(sigma float64) Operation {
+	return &sharpen{sigma: sigma}
+}
+
+func newSharpen(args Args) (Operation, error) {
+	sigma, err := sigmaArg(args, "sharpen")
+	if err != nil {
+		return nil, err
+	}
+
+	return &sharpen{sigma: sigma}, nil
+}
+
+func (s *sharpen) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Sharpen(img, s.sigma), nil
+}
+
+func (s *sharpen) String() string {
+	return "sharpen_" + formatFloat(s.sigma)
+}
+
+type unsharpMask struct {
+	opts imaging.UnsharpMaskOptions
+}
+
+func UnsharpMask(opts imaging.UnsharpMaskOptions) Operation {
+	return &unsharpMask{opts: opts}
+}
+
+// newUnsharpMask parses "unsharp_<radius>" with optional amount (a),
+// threshold (t) and luma flag, e.g. "unsharp_2,a_1.5,t_4,luma".
+func newUnsharpMask(args Args) (Operation, error) {
+	if err := args.Check("unsharp", "a", "t", "luma"); err != nil {
+		return nil, err
+	}
+
+	radius, err := args.Float("unsharp", 0)
+	if err != nil {
+		return nil, err
+	}
+	if radius <= 0 || radius > maxSigma {
+		return nil, fmt.Errorf("radius must be greater than 0 and at most %d", maxSigma)
+	}
+
+	amount, err := args.Float("a", 1)
+	if err != nil {
+		return nil, err
+	}
+	if amount <= 0 || amount > maxUnsharpAmount {
+		return nil, fmt.Errorf("a must be greater than 0 and at most %d", maxUnsharpAmount)
+	}
+
+	threshold, err := args.Float("t", 0)
+	if err != nil {
+		return nil, err
+	}
+	if threshold < 0 || threshold > maxUnsharpThreshold {
+		return nil, fmt.Errorf("t must be between 0 and %d", maxUnsharpThreshold)
+	}
+
+	return &unsharpMask{opts: imaging.UnsharpMaskOptions{
+		Radius:        radius,
+		Amount:        amount,
+		Threshold:     threshold,
+		LuminanceOnly: args.Has("luma"),
+	}}, nil
+}
+
+func (u *unsharpMask) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.UnsharpMask(img, u.opts), nil
+}
+
+func (u *unsharpMask) String() string {
+	parts := []string{"unsharp_" + formatFloat(u.opts.Radius)}
+	if u.opts.Amount != 1 {
+		parts = append(parts, "a_"+formatFloat(u.opts.Amount))
+	}
+	if u.opts.Threshold != 0 {
+		parts = append(parts, "t_"+formatFloat(u.opts.Threshold))
+	}
+	if u.opts.LuminanceOnly {
+		parts = append(parts, "luma")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type convolve struct {
+	// name is set for built-in kernels so the canonical form stays short.
+	name   string
+	kernel imaging.Kernel
+	opts   imaging.ConvolveOptions
+}
+
+// Convolve applies a custom kernel. NamedConvolve should be used for the
+// built-in ones.
+func Convolve(kernel imaging.Kernel, opts imaging.ConvolveOptions) Operation {
+	return &convolve{kernel: kernel, opts: opts}
+}
+
+func NamedConvolve(name string, opts imaging.ConvolveOptions) (Operation, error) {
+	kernel, ok := imaging.NamedKernel(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown kernel %q", name)
+	}
+
+	return &convolve{name: name, kernel: kernel, opts: opts}, nil
+}
+
+// newConvolve parses either a built-in kernel, "convolve_emboss", or custom
+// values listed row by row, "convolve,k_0:-1:0:-1:5:-1:0:-1:0". Both accept
+// the norm flag and a bias.
+func newConvolve(args Args) (Operation, error) {
+	if err := args.Check("convolve", "k", "norm", "bias"); err != nil {
+		return nil, err
+	}
+
+	bias, err := args.Float("bias", 0)
+	if err != nil {
+		return nil, err
+	}
+	if math.Abs(bias) > maxConvolveBias {
+		return nil, fmt.Errorf("bias must be between -%d and %d", maxConvolveBias, maxConvolveBias)
+	}
+	opts := imaging.ConvolveOptions{Normalize: args.Has("norm"), Bias: bias}
+
+	name, values := args.String("convolve", ""), args.String("k", "")
+	switch {
+	case name != "" && values != "":
+		return nil, fmt.Errorf("give either a kernel name or k, not both")
+	case name != "":
+		return NamedConvolve(name, opts)
+	case values == "":
+		return nil, fmt.Errorf("a kernel name or k is required")
+	}
+
+	parsed, err := floatListArg(args, "k")
+	if err != nil {
+		return nil, err
+	}
+
+	kernel, err := imaging.NewKernel(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return Convolve(kernel, opts), nil
+}
+
+func (c *convolve) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Convolve(img, c.kernel, c.opts)
+}
+
+func (c *convolve) String() string {
+	var parts []string
+	if c.name != "" {
+		parts = append(parts, "convolve_"+c.name)
+	} else {
+		values := make([]string, len(c.kernel.Values))
+		for idx, value := range c.kernel.Values {
+			values[idx] = formatFloat(value)
+		}
+		parts = append(parts, "convolve", "k_"+strings.Join(values, ":"))
+	}
+	if c.opts.Normalize {
+		parts = append(parts, "norm")
+	}
+	if c.opts.Bias != 0 {
+		parts = append(parts, "bias_"+formatFloat(c.opts.Bias))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type edges struct {
+	opts imaging.EdgeOptions
+}
+
+func Edges(opts imaging.EdgeOptions) Operation {
+	return &edges{opts: opts}
+}
+
+// newEdges parses "edges_<method>" with an optional smoothing sigma (s).
+// Sobel, Prewitt and Laplacian output is made binary by giving a threshold
+// (t); Canny takes its hysteresis thresholds as lo and hi.
+func newEdges(args Args) (Operation, error) {
+	method, err := imaging.EdgeMethodFromName(args.String("edges", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	if method == imaging.EdgeCanny {
+		err = args.Check("edges", "s", "lo", "hi")
+	} else {
+		err = args.Check("edges", "s", "t")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	opts := imaging.EdgeOptions{Method: method, Binary: args.Has("t")}
+
+	if opts.Sigma, err = args.Float("s", 0); err != nil {
+		return nil, err
+	}
+	if opts.Sigma < 0 || opts.Sigma > maxEdgeSigma {
+		return nil, fmt.Errorf("s must be between 0 and %d", maxEdgeSigma)
+	}
+
+	if opts.Threshold, err = edgeThresholdArg(args, "t"); err != nil {
+		return nil, err
+	}
+	if opts.Low, err = edgeThresholdArg(args, "lo"); err != nil {
+		return nil, err
+	}
+	if opts.High, err = edgeThresholdArg(args, "hi"); err != nil {
+		return nil, err
+	}
+	if args.Has("lo") != args.Has("hi") {
+		return nil, fmt.Errorf("lo and hi must be given together")
+	}
+	if opts.Low > opts.High {
+		return nil, fmt.Errorf("lo must not be above hi")
+	}
+
+	return &edges{opts: opts}, nil
+}
+
+func edgeThresholdArg(args Args, key string) (float64, error) {
+	value, err := args.Float(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > maxEdgeThreshold {
+		return 0, fmt.Errorf("%s must be between 0 and %d", key, maxEdgeThreshold)
+	}
+
+	return value, nil
+}
+
+func (e *edges) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.DetectEdges(img, e.opts)
+}
+
+func (e *edges) String() string {
+	parts := []string{"edges_" + imaging.EdgeMethodName(e.opts.Method)}
+	if e.opts.Sigma != 0 {
+		parts = append(parts, "s_"+formatFloat(e.opts.Sigma))
+	}
+	if e.opts.Binary && e.opts.Method != imaging.EdgeCanny {
+		parts = append(parts, "t_"+formatFloat(e.opts.Threshold))
+	}
+	if e.opts.High != 0 && e.opts.Method == imaging.EdgeCanny {
+		parts = append(parts, "lo_"+formatFloat(e.opts.Low), "hi_"+formatFloat(e.opts.High))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type denoise struct {
+	opts imaging.DenoiseOptions
+}
+
+func Denoise(opts imaging.DenoiseOptions) Operation {
+	return &denoise{opts: opts}
+}
+
+// newDenoise parses "denoise_<method>" with the method's parameters, all
+// optional: "denoise_median,r_2", "denoise_bilateral,ss_3,sr_30" or
+// "denoise_nlmeans,h_10,r_5,p_1".
+func newDenoise(args Args) (Operation, error) {
+	method, err := imaging.DenoiseMethodFromName(args.String("denoise", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := imaging.DenoiseOptions{Method: method}
+
+	switch method {
+	case imaging.DenoiseMedian:
+		if err := args.Check("denoise", "r"); err != nil {
+			return nil, err
+		}
+		if opts.Radius, err = intRangeArg(args, "r", 1, imaging.MaxMedianRadius); err != nil {
+			return nil, err
+		}
+	case imaging.DenoiseBilateral:
+		if err := args.Check("denoise", "ss", "sr"); err != nil {
+			return nil, err
+		}
+		if opts.SpatialSigma, err = floatRangeArg(args, "ss", imaging.MaxBilateralSigma); err != nil {
+			return nil, err
+		}
+		if opts.RangeSigma, err = floatRangeArg(args, "sr", maxDenoiseRange); err != nil {
+			return nil, err
+		}
+	case imaging.DenoiseNLMeans:
+		if err := args.Check("denoise", "h", "r", "p"); err != nil {
+			return nil, err
+		}
+		if opts.Strength, err = floatRangeArg(args, "h", maxDenoiseStrength); err != nil {
+			return nil, err
+		}
+		if opts.Radius, err = intRangeArg(args, "r", 1, imaging.MaxNLMeansRadius); err != nil {
+			return nil, err
+		}
+		if opts.PatchRadius, err = intRangeArg(args, "p", 1, imaging.MaxNLMeansPatchRadius); err != nil {
+			return nil, err
+		}
+	}
+
+	return &denoise{opts: opts}, nil
+}
+
+// floatRangeArg parses an optional value in (0, high], returning 0 when it
+// is absent.
+func floatRangeArg(args Args, key string, high float64) (float64, error) {
+	value, err := args.Float(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	if args.Has(key) && (value <= 0 || value > high) {
+		return 0, fmt.Errorf("%s must be greater than 0 and at most %s", key, formatFloat(high))
+	}
+
+	return value, nil
+}
+
+// intRangeArg parses an optional value in [low, high], returning 0 when it
+// is absent.
+func intRangeArg(args Args, key string, low, high int) (int, error) {
+	value, err := args.Int(key, 0)
+	if err != nil {
+		return 0, err
+	}
+	if args.Has(key) && (value < low || value > high) {
+		return 0, fmt.Errorf("%s must be between %d and %d", key, low, high)
+	}
+
+	return value, nil
+}
+
+func (d *denoise) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Denoise(img, d.opts)
+}
+
+func (d *denoise) String() string {
+	parts := []string{"denoise_" + imaging.DenoiseMethodName(d.opts.Method)}
+	add := func(key string, value float64) {
+		if value != 0 {
+			parts = append(parts, key+"_"+formatFloat(value))
+		}
+	}
+
+	add("h", d.opts.Strength)
+	add("r", float64(d.opts.Radius))
+	add("p", float64(d.opts.PatchRadius))
+	add("ss", d.opts.SpatialSigma)
+	add("sr", d.opts.RangeSigma)
+
+	return strings.Join(parts, ",")
+}
+
+type autoLevels struct {
+	opts imaging.AutoLevelsOptions
+}
+
+func AutoLevels(opts imaging.AutoLevelsOptions) Operation {
+	return &autoLevels{opts: opts}
+}
+
+// newAutoLevels parses "autolevels" with optional shadow (lo) and highlight
+// (hi) clip percentages and the rgb flag for per-channel stretching.
+func newAutoLevels(args Args) (Operation, error) {
+	if err := args.Check("autolevels", "lo", "hi", "rgb"); err != nil {
+		return nil, err
+	}
+
+	opts := imaging.AutoLevelsOptions{PerChannel: args.Has("rgb")}
+
+	var err error
+	for key, target := range map[string]*float64{"lo": &opts.ShadowClip, "hi": &opts.HighlightClip} {
+		if *target, err = args.Float(key, 0); err != nil {
+			return nil, err
+		}
+		if *target < 0 || *target > imaging.MaxLevelsClip {
+			return nil, fmt.Errorf("%s must be between 0 and %d", key, imaging.MaxLevelsClip)
+		}
+	}
+	if opts.ShadowClip+opts.HighlightClip >= 100 {
+		return nil, fmt.Errorf("lo and hi must add up to less than 100")
+	}
+
+	return &autoLevels{opts: opts}, nil
+}
+
+func (a *autoLevels) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.AutoLevels(img, a.opts)
+}
+
+func (a *autoLevels) String() string {
+	parts := []string{"autolevels"}
+	if a.opts.ShadowClip != 0 {
+		parts = append(parts, "lo_"+formatFloat(a.opts.ShadowClip))
+	}
+	if a.opts.HighlightClip != 0 {
+		parts = append(parts, "hi_"+formatFloat(a.opts.HighlightClip))
+	}
+	if a.opts.PerChannel {
+		parts = append(parts, "rgb")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type equalize struct{}
+
+func Equalize() Operation {
+	return equalize{}
+}
+
+func newEqualize(args Args) (Operation, error) {
+	if err := args.Check("equalize"); err != nil {
+		return nil, err
+	}
+
+	return equalize{}, nil
+}
+
+func (equalize) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Equalize(img), nil
+}
+
+func (equalize) String() string {
+	return "equalize"
+}
+
+type clahe struct {
+	opts imaging.CLAHEOptions
+}
+
+func CLAHE(opts imaging.CLAHEOptions) Operation {
+	return &clahe{opts: opts}
+}
+
+// newCLAHE parses "clahe" with optional tile size in pixels and clip limit,
+// e.g. "clahe,tile_64,clip_2".
+func newCLAHE(args Args) (Operation, error) {
+	if err := args.Check("clahe", "tile", "clip"); err != nil {
+		return nil, err
+	}
+
+	tile, err := intRangeArg(args, "tile", imaging.MinCLAHETile, imaging.MaxCLAHETile)
+	if err != nil {
+		return nil, err
+	}
+
+	clip, err := args.Float("clip", 0)
+	if err != nil {
+		return nil, err
+	}
+	if args.Has("clip") && (clip < 1 || clip > imaging.MaxCLAHELimit) {
+		return nil, fmt.Errorf("clip must be between 1 and %d", imaging.MaxCLAHELimit)
+	}
+
+	return &clahe{opts: imaging.CLAHEOptions{TileSize: tile, ClipLimit: clip}}, nil
+}
+
+func (c *clahe) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.CLAHE(img, c.opts)
+}
+
+func (c *clahe) String() string {
+	parts := []string{"clahe"}
+	if c.opts.TileSize != 0 {
+		parts = append(parts, "tile_"+strconv.Itoa(c.opts.TileSize))
+	}
+	if c.opts.ClipLimit != 0 {
+		parts = append(parts, "clip_"+formatFloat(c.opts.ClipLimit))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type levels struct {
+	channel imaging.Channel
+	levels  imaging.Levels
+}
+
+func Levels(channel imaging.Channel, l imaging.Levels) Operation {
+	return &levels{channel: channel, levels: l}
+}
+
+// newLevels parses "levels_<channel>" with optional input range (in), gamma
+// (g) and output range (out), e.g. "levels_rgb,in_10:245,g_1.2,out_0:255".
+// The channel is one of rgb (the default), r, g or b.
+func newLevels(args Args) (Operation, error) {
+	if err := args.Check("levels", "in", "g", "out"); err != nil {
+		return nil, err
+	}
+
+	channel, err := imaging.ChannelFromName(args.String("levels", "rgb"))
+	if err != nil {
+		return nil, err
+	}
+
+	l := imaging.DefaultLevels()
+	if l.InputBlack, l.InputWhite, err = rangeArg(args, "in", l.InputBlack, l.InputWhite); err != nil {
+		return nil, err
+	}
+	if l.OutputBlack, l.OutputWhite, err = rangeArg(args, "out", l.OutputBlack, l.OutputWhite); err != nil {
+		return nil, err
+	}
+	if l.Gamma, err = args.Float("g", l.Gamma); err != nil {
+		return nil, err
+	}
+
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &levels{channel: channel, levels: l}, nil
+}
+
+// rangeArg parses a "low:high" pair.
+func rangeArg(args Args, key string, low, high float64) (float64, float64, error) {
+	values, err := floatListArg(args, key)
+	if err != nil || values == nil {
+		return low, high, err
+	}
+	if len(values) != 2 {
+		return 0, 0, fmt.Errorf("%s must be two numbers separated by a colon", key)
+	}
+
+	return values[0], values[1], nil
+}
+
+// floatListArg parses colon-separated numbers, returning nil when key is
+// absent.
+func floatListArg(args Args, key string) ([]float64, error) {
+	raw := args.String(key, "")
+	if raw == "" {
+		return nil, nil
+	}
+
+	fields := strings.Split(raw, ":")
+	values := make([]float64, len(fields))
+	for idx, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%s must be numbers separated by colons", key)
+		}
+		values[idx] = value
+	}
+
+	return values, nil
+}
+
+func (l *levels) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Levels(img, l.channel, l.levels)
+}
+
+func (l *levels) channelLUT() (imaging.Channel, [256]uint8, error) {
+	if err := l.levels.Validate(); err != nil {
+		return 0, [256]uint8{}, fmt.Errorf("imaging: %w", err)
+	}
+
+	return l.channel, l.levels.LUT(), nil
+}
+
+func (l *levels) String() string {
+	defaults := imaging.DefaultLevels()
+	parts := []string{"levels_" + imaging.ChannelName(l.channel)}
+
+	if l.levels.InputBlack != defaults.InputBlack || l.levels.InputWhite != defaults.InputWhite {
+		parts = append(parts, "in_"+formatFloat(l.levels.InputBlack)+":"+formatFloat(l.levels.InputWhite))
+	}
+	if l.levels.Gamma != defaults.Gamma {
+		parts = append(parts, "g_"+formatFloat(l.levels.Gamma))
+	}
+	if l.levels.OutputBlack != defaults.OutputBlack || l.levels.OutputWhite != defaults.OutputWhite {
+		parts = append(parts, "out_"+formatFloat(l.levels.OutputBlack)+":"+formatFloat(l.levels.OutputWhite))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type curves struct {
+	channel imaging.Channel
+	curve   imaging.Curve
+}
+
+func Curves(channel imaging.Channel, curve imaging.Curve) Operation {
+	return &curves{channel: channel, curve: curve}
+}
+
+// newCurves parses "curves_<channel>" with control points given as
+// colon-separated input and output pairs, e.g.
+// "curves_rgb,p_0:0:64:50:192:210:255:255".
+func newCurves(args Args) (Operation, error) {
+	if err := args.Check("curves", "p"); err != nil {
+		return nil, err
+	}
+
+	channel, err := imaging.ChannelFromName(args.String("curves", "rgb"))
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := floatListArg(args, "p")
+	if err != nil {
+		return nil, err
+	}
+	if values == nil || len(values)%2 != 0 {
+		return nil, fmt.Errorf("p must list input and output pairs")
+	}
+
+	curve := make(imaging.Curve, 0, len(values)/2)
+	for idx := 0; idx < len(values); idx += 2 {
+		curve = append(curve, imaging.CurvePoint{In: values[idx], Out: values[idx+1]})
+	}
+	if err := curve.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &curves{channel: channel, curve: curve}, nil
+}
+
+func (c *curves) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Curves(img, c.channel, c.curve)
+}
+
+func (c *curves) channelLUT() (imaging.Channel, [256]uint8, error) {
+	if err := c.curve.Validate(); err != nil {
+		return 0, [256]uint8{}, fmt.Errorf("imaging: %w", err)
+	}
+
+	return c.channel, c.curve.LUT(), nil
+}
+
+func (c *curves) String() string {
+	points := make([]string, 0, len(c.curve)*2)
+	for _, point := range c.curve {
+		points = append(points, formatFloat(point.In), formatFloat(point.Out))
+	}
+
+	return "curves_" + imaging.ChannelName(c.channel) + ",p_" + strings.Join(points, ":")
+}
+
+type cubeLUT struct {
+	name string
+	opts imaging.CubeLUTOptions
+	lut  *imaging.CubeLUT
+}
+
+// CubeLUT grades with the uploaded .cube file called name. The chain must be
+// resolved before it is applied.
+func CubeLUT(name string, opts imaging.CubeLUTOptions) Operation {
+	return &cubeLUT{name: name, opts: opts}
+}
+
+// newCubeLUT parses "lut_<name>" with optional intensity (i) and
+// interpolation flag, e.g. "lut_teal-orange,i_0.8,tetrahedral".
+func newCubeLUT(args Args) (Operation, error) {
+	if err := args.Check("lut", "i", "trilinear", "tetrahedral"); err != nil {
+		return nil, err
+	}
+
+	name := args.String("lut", "")
+	if name == "" {
+		return nil, fmt.Errorf("lut needs the name of an uploaded LUT")
+	}
+
+	if args.Has("trilinear") && args.Has("tetrahedral") {
+		return nil, fmt.Errorf("only one interpolation may be given")
+	}
+	opts := imaging.CubeLUTOptions{Interpolation: imaging.LUTTrilinear, Intensity: 1}
+	if args.Has("tetrahedral") {
+		opts.Interpolation = imaging.LUTTetrahedral
+	}
+
+	intensity, err := floatRangeArg(args, "i", 1)
+	if err != nil {
+		return nil, err
+	}
+	if args.Has("i") {
+		opts.Intensity = intensity
+	}
+
+	return &cubeLUT{name: name, opts: opts}, nil
+}
+
+func (c *cubeLUT) resolve(loader AssetLoader) (string, error) {
+	lut, version, err := loadAsset(loader, LUTAssets, c.name, func(data []byte) (any, int64, error) {
+		lut, err := imaging.ParseCube(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, fmt.Errorf("lut %q: %w", c.name, err)
+		}
+
+		return lut, int64(len(lut.Table)) * 24, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	c.lut = lut.(*imaging.CubeLUT)
+
+	return version, nil
+}
+
+func (c *cubeLUT) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	if c.lut == nil {
+		return nil, fmt.Errorf("transform: lut %q was not resolved", c.name)
+	}
+
+	return im.ApplyCubeLUT(img, c.lut, c.opts)
+}
+
+func (c *cubeLUT) String() string {
+	parts := []string{"lut_" + c.name}
+	if c.opts.Intensity != 1 {
+		parts = append(parts, "i_"+formatFloat(c.opts.Intensity))
+	}
+	if c.opts.Interpolation == imaging.LUTTetrahedral {
+		parts = append(parts, "tetrahedral")
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type overlay struct {
+	// name of the watermark asset, empty for images given directly
+	name    string
+	opts    imaging.OverlayOptions
+	img     image.Image
+	version string
+}
+
+// Overlay composites the watermark asset called name. The chain must be
+// resolved before it is applied.
+func Overlay(name string, opts imaging.OverlayOptions) Operation {
+	return &overlay{name: name, opts: opts}
+}
+
+// OverlayImage composites img, whose content is identified by version so
+// that results using different images are cached apart.
+func OverlayImage(img image.Image, version string, opts imaging.OverlayOptions) Operation {
+	return &overlay{opts: opts, img: img, version: version}
+}
+
+// newOverlay parses "overlay_<watermark>" with optional anchor (a), offsets
+// (x, y), scale relative to the base width (s), opacity (o), tiling mode
+// (tile), gap between tiles (gap) and blend mode (blend), e.g.
+// "overlay_logo,a_bottom-right,x_20,y_20,s_0.2,o_0.6".
+func newOverlay(args Args) (Operation, error) {
+	if err := args.Check("overlay", "a", "x", "y", "s", "o", "tile", "gap", "blend"); err != nil {
+		return nil, err
+	}
+
+	name := args.String("overlay", "")
+	if name == "" {
+		return nil, fmt.Errorf("overlay needs the name of a watermark")
+	}
+
+	opts := imaging.OverlayOptions{Opacity: 1}
+	var err error
+	if opts.Anchor, err = imaging.AnchorFromName(args.String("a", "center")); err != nil {
+		return nil, err
+	}
+	if opts.Tile, err = imaging.TileModeFromName(args.String("tile", "none")); err != nil {
+		return nil, err
+	}
+	if opts.Blend, err = imaging.BlendModeFromName(args.String("blend", "normal")); err != nil {
+		return nil, err
+	}
+	if opts.OffsetX, err = intRangeArg(args, "x", -imaging.MaxOverlayOffset, imaging.MaxOverlayOffset); err != nil {
+		return nil, err
+	}
+	if opts.OffsetY, err = intRangeArg(args, "y", -imaging.MaxOverlayOffset, imaging.MaxOverlayOffset); err != nil {
+		return nil, err
+	}
+	if opts.Spacing, err = intRangeArg(args, "gap", 0, imaging.MaxOverlaySpacing); err != nil {
+		return nil, err
+	}
+	if opts.Scale, err = floatRangeArg(args, "s", imaging.MaxOverlayScale); err != nil {
+		return nil, err
+	}
+	if args.Has("o") {
+		if opts.Opacity, err = floatRangeArg(args, "o", 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return &overlay{name: name, opts: opts}, nil
+}
+
+func (o *overlay) resolve(loader AssetLoader) (string, error) {
+	if o.name == "" {
+		return o.version, nil
+	}
+
+	img, version, err := loadAsset(loader, WatermarkAssets, o.name, func(data []byte) (any, int64, error) {
+		return decodeImageAsset(data, "watermark", o.name)
+	})
+	if err != nil {
+		return "", err
+	}
+	o.img = img.(image.Image)
+
+	return version, nil
+}
+
+func (o *overlay) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	if o.img == nil {
+		return nil, fmt.Errorf("transform: watermark %q was not resolved", o.name)
+	}
+
+	return im.Overlay(img, o.img, o.opts)
+}
+
+func (o *overlay) String() string {
+	parts := []string{"overlay"}
+	if o.name != "" {
+		parts[0] += "_" + o.name
+	}
+
+	if o.opts.Anchor != imaging.AnchorCenter {
+		parts = append(parts, "a_"+imaging.AnchorName(o.opts.Anchor))
+	}
+	if o.opts.OffsetX != 0 {
+		parts = append(parts, "x_"+strconv.Itoa(o.opts.OffsetX))
+	}
+	if o.opts.OffsetY != 0 {
+		parts = append(parts, "y_"+strconv.Itoa(o.opts.OffsetY))
+	}
+	if o.opts.Scale != 0 {
+		parts = append(parts, "s_"+formatFloat(o.opts.Scale))
+	}
+	if o.opts.Opacity != 1 {
+		parts = append(parts, "o_"+formatFloat(o.opts.Opacity))
+	}
+	if o.opts.Tile != imaging.TileNone {
+		parts = append(parts, "tile_"+imaging.TileModeName(o.opts.Tile))
+	}
+	if o.opts.Spacing != 0 {
+		parts = append(parts, "gap_"+strconv.Itoa(o.opts.Spacing))
+	}
+	if o.opts.Blend != imaging.BlendNormal {
+		parts = append(parts, "blend_"+imaging.BlendModeName(o.opts.Blend))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type text struct {
+	// name of the font asset, empty for the bundled font
+	font string
+	opts imaging.TextOptions
+}
+
+var (
+	black        = color.NRGBA{A: 255}
+	white        = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	shadowColour = color.NRGBA{A: 128}
+)
+
+// Text draws text in the font asset called font, or the bundled font when it
+// is empty. The chain must be resolved before it is applied.
+func Text(font string, opts imaging.TextOptions) Operation {
+	return &text{font: font, opts: opts}
+}
+
+// newText parses "text_<string>", where the string is base64url encoded
+// without padding, with optional font asset (font), size in pixels (size),
+// colour (color), alignment (align), line spacing (lh), wrapping box
+// (box_x:y:w:h), anchor within the box (a), rotation in degrees (rot),
+// stroke width (stroke) and colour (sc), and shadow offset (shadow_x:y),
+// blur (sb) and colour (shc), e.g. "text_SGVsbG8,size_48,color_fff,a_bottom".
+// Colours are hex without the leading "#".
+func newText(args Args) (Operation, error) {
+	if err := args.Check("text", "font", "size", "color", "align", "lh", "box", "a", "rot", "stroke", "sc", "shadow", "sb", "shc"); err != nil {
+		return nil, err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(args.String("text", ""))
+	if err != nil || len(decoded) == 0 || !utf8.Valid(decoded) {
+		return nil, fmt.Errorf("text must be non-empty UTF-8 encoded as unpadded base64url")
+	}
+
+	opts := imaging.TextOptions{Text: string(decoded), Size: imaging.DefaultFontSize}
+	if opts.Size, err = args.Float("size", opts.Size); err != nil {
+		return nil, err
+	}
+	if opts.Color, err = colorArg(args, "color", black); err != nil {
+		return nil, err
+	}
+	if opts.Align, err = imaging.TextAlignFromName(args.String("align", "left")); err != nil {
+		return nil, err
+	}
+	if opts.LineSpacing, err = args.Float("lh", 0); err != nil {
+		return nil, err
+	}
+	if opts.Anchor, err = imaging.AnchorFromName(args.String("a", "center")); err != nil {
+		return nil, err
+	}
+	if opts.Box, err = rectArg(args, "box"); err != nil {
+		return nil, err
+	}
+	if opts.Rotation, err = args.Float("rot", 0); err != nil {
+		return nil, err
+	}
+	if opts.Rotation < -360 || opts.Rotation > 360 {
+		return nil, fmt.Errorf("rot must be between -360 and 360")
+	}
+
+	if opts.StrokeWidth, err = intRangeArg(args, "stroke", 1, imaging.MaxTextStroke); err != nil {
+		return nil, err
+	}
+	if opts.StrokeColor, err = colorArg(args, "sc", black); err != nil {
+		return nil, err
+	}
+	if args.Has("sc") && opts.StrokeWidth == 0 {
+		return nil, fmt.Errorf("sc needs a stroke width")
+	}
+
+	if args.Has("shadow") || args.Has("sb") || args.Has("shc") {
+		offset, err := floatListArg(args, "shadow")
+		if err != nil {
+			return nil, err
+		}
+		if offset != nil {
+			if len(offset) != 2 {
+				return nil, fmt.Errorf("shadow must be two numbers separated by a colon")
+			}
+			opts.ShadowOffset = image.Pt(int(offset[0]), int(offset[1]))
+		}
+		if opts.ShadowBlur, err = args.Float("sb", 0); err != nil {
+			return nil, err
+		}
+		if opts.ShadowColor, err = colorArg(args, "shc", shadowColour); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &text{font: args.String("font", ""), opts: opts}, nil
+}
+
+// colorArg parses a hex colour, falling back when key is absent.
+func colorArg(args Args, key string, fallback color.NRGBA) (color.NRGBA, error) {
+	if !args.Has(key) {
+		return fallback, nil
+	}
+
+	c, err := imaging.ParseHexColor(args.String(key, ""))
+	if err != nil {
+		return c, fmt.Errorf("%s: %v", key, err)
+	}
+
+	return c, nil
+}
+
+// rectArg parses "x:y:w:h", returning an empty rectangle when key is absent.
+func rectArg(args Args, key string) (image.Rectangle, error) {
+	values, err := floatListArg(args, key)
+	if err != nil || values == nil {
+		return image.Rectangle{}, err
+	}
+	if len(values) != 4 || values[2] <= 0 || values[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("%s must be x:y:width:height with a positive size", key)
+	}
+
+	x, y := int(values[0]), int(values[1])
+	return image.Rect(x, y, x+int(values[2]), y+int(values[3])), nil
+}
+
+func (t *text) resolve(loader AssetLoader) (string, error) {
+	if t.font == "" {
+		t.opts.Font = nil
+		return "", nil
+	}
+
+	f, version, err := loadAsset(loader, FontAssets, t.font, func(data []byte) (any, int64, error) {
+		f, err := imaging.ParseFont(data)
+		if err != nil {
+			return nil, 0, fmt.Errorf("font %q: %w", t.font, err)
+		}
+
+		// the parsed font keeps the file's content
+		return f, int64(len(data)), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	t.opts.Font = f.(*opentype.Font)
+
+	return version, nil
+}
+
+func (t *text) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	if t.font != "" && t.opts.Font == nil {
+		return nil, fmt.Errorf("transform: font %q was not resolved", t.font)
+	}
+
+	return im.DrawText(img, t.opts)
+}
+
+func (t *text) String() string {
+	opts := t.opts
+	parts := []string{"text_" + base64.RawURLEncoding.EncodeToString([]byte(opts.Text))}
+
+	if t.font != "" {
+		parts = append(parts, "font_"+t.font)
+	}
+	if opts.Size != imaging.DefaultFontSize {
+		parts = append(parts, "size_"+formatFloat(opts.Size))
+	}
+	if opts.Color != black {
+		parts = append(parts, "color_"+imaging.HexColor(opts.Color))
+	}
+	if opts.Align != imaging.AlignLeft {
+		parts = append(parts, "align_"+imaging.TextAlignName(opts.Align))
+	}
+	if opts.LineSpacing != 0 {
+		parts = append(parts, "lh_"+formatFloat(opts.LineSpacing))
+	}
+	if !opts.Box.Empty() {
+		parts = append(parts, fmt.Sprintf("box_%d:%d:%d:%d", opts.Box.Min.X, opts.Box.Min.Y, opts.Box.Dx(), opts.Box.Dy()))
+	}
+	if opts.Anchor != imaging.AnchorCenter {
+		parts = append(parts, "a_"+imaging.AnchorName(opts.Anchor))
+	}
+	if opts.Rotation != 0 {
+		parts = append(parts, "rot_"+formatFloat(opts.Rotation))
+	}
+	if opts.StrokeWidth != 0 {
+		parts = append(parts, "stroke_"+strconv.Itoa(opts.StrokeWidth))
+		if opts.StrokeColor != black {
+			parts = append(parts, "sc_"+imaging.HexColor(opts.StrokeColor))
+		}
+	}
+	if opts.ShadowColor.A != 0 {
+		parts = append(parts, fmt.Sprintf("shadow_%d:%d", opts.ShadowOffset.X, opts.ShadowOffset.Y))
+		if opts.ShadowBlur != 0 {
+			parts = append(parts, "sb_"+formatFloat(opts.ShadowBlur))
+		}
+		if opts.ShadowColor != shadowColour {
+			parts = append(parts, "shc_"+imaging.HexColor(opts.ShadowColor))
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+type drawShapes struct {
+	shapes []imaging.Shape
+}
+
+// Draw draws shapes in order. Its canonical form has a segment per shape.
+func Draw(shapes ...imaging.Shape) Operation {
+	return &drawShapes{shapes: shapes}
+}
+
+// newDraw parses "draw_<shape>" with points (p_x:y:x:y...), stroke width
+// (w), stroke colour (c), fill colour (f), arrow head size (head) and the
+// aliased flag, e.g. "draw_rect,p_10:10:200:80,w_4,c_ff0000,f_ff000040".
+// Shapes are rect, ellipse, line, arrow or polygon.
+func newDraw(args Args) (Operation, error) {
+	if err := args.Check("draw", "p", "w", "c", "f", "head", "aliased"); err != nil {
+		return nil, err
+	}
+
+	kind, err := imaging.ShapeKindFromName(args.String("draw", ""))
+	if err != nil {
+		return nil, err
+	}
+	shape := imaging.Shape{Kind: kind, AntiAlias: !args.Has("aliased")}
+
+	if shape.Points, err = pointsArg(args, "p"); err != nil {
+		return nil, err
+	}
+	if shape.StrokeWidth, err = args.Float("w", imaging.DefaultShapeStroke); err != nil {
+		return nil, err
+	}
+	if shape.StrokeColor, err = colorArg(args, "c", black); err != nil {
+		return nil, err
+	}
+	if shape.Fill, err = colorArg(args, "f", color.NRGBA{}); err != nil {
+		return nil, err
+	}
+	if shape.ArrowSize, err = args.Float("head", 0); err != nil {
+		return nil, err
+	}
+
+	if err := shape.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &drawShapes{shapes: []imaging.Shape{shape}}, nil
+}
+
+func (d *drawShapes) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.DrawShapes(img, d.shapes)
+}
+
+func (d *drawShapes) String() string {
+	segments := make([]string, 0, len(d.shapes))
+	for _, shape := range d.shapes {
+		parts := []string{"draw_" + imaging.ShapeKindName(shape.Kind), "p_" + formatPoints(shape.Points)}
+		if shape.StrokeWidth != imaging.DefaultShapeStroke {
+			parts = append(parts, "w_"+formatFloat(shape.StrokeWidth))
+		}
+		if shape.StrokeWidth != 0 && shape.StrokeColor != black {
+			parts = append(parts, "c_"+imaging.HexColor(shape.StrokeColor))
+		}
+		if shape.Fill.A != 0 {
+			parts = append(parts, "f_"+imaging.HexColor(shape.Fill))
+		}
+		if shape.ArrowSize != 0 {
+			parts = append(parts, "head_"+formatFloat(shape.ArrowSize))
+		}
+		if !shape.AntiAlias {
+			parts = append(parts, "aliased")
+		}
+		segments = append(segments, strings.Join(parts, ","))
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func sigmaArg(args Args, name string) (float64, error) {
+	if err := args.Check(name); err != nil {
+		return 0, err
+	}
+
+	sigma, err := args.Float(name, 0)
+	if err != nil {
+		return 0, err
+	}
+	if sigma <= 0 || sigma > maxSigma {
+		return 0, fmt.Errorf("%s must be greater than 0 and at most %d", name, maxSigma)
+	}
+
+	return sigma, nil
+}
+
+// pointsArg parses a list of points given as x:y:x:y...
+func pointsArg(args Args, key string) ([]imaging.Point, error) {
+	values, err := floatListArg(args, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)%2 != 0 {
+		return nil, fmt.Errorf("%s must list x and y pairs", key)
+	}
+
+	points := make([]imaging.Point, 0, len(values)/2)
+	for idx := 0; idx < len(values); idx += 2 {
+		points = append(points, imaging.Point{X: values[idx], Y: values[idx+1]})
+	}
+
+	return points, nil
+}
+
+func formatPoints(points []imaging.Point) string {
+	values := make([]string, 0, len(points)*2)
+	for _, p := range points {
+		values = append(values, formatFloat(p.X), formatFloat(p.Y))
+	}
+
+	return strings.Join(values, ":")
+}
+
+type redact struct {
+	opts imaging.RedactOptions
+}
+
+// Redact obscures regions of the image. Its canonical form has a segment
+// per region.
+func Redact(opts imaging.RedactOptions) Operation {
+	return &redact{opts: opts}
+}
+
+// newRedact parses "redact_<mode>" with a rectangle (r_x:y:w:h) or polygon
+// (p_x:y:x:y...) region, e.g. "redact_pixelate,r_10:10:200:40,block_12".
+// Modes are fill with a colour (c, default black), pixelate with a block
+// size (block) and blur with a sigma (s).
+func newRedact(args Args) (Operation, error) {
+	mode, err := imaging.RedactModeFromName(args.String("redact", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case imaging.RedactPixelate:
+		err = args.Check("redact", "r", "p", "block")
+	case imaging.RedactBlur:
+		err = args.Check("redact", "r", "p", "s")
+	default:
+		err = args.Check("redact", "r", "p", "c")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var region imaging.Region
+	if args.Has("r") == args.Has("p") {
+		return nil, fmt.Errorf("exactly one of r and p must be given")
+	}
+	if region.Rect, err = rectArg(args, "r"); err != nil {
+		return nil, err
+	}
+	if region.Polygon, err = pointsArg(args, "p"); err != nil {
+		return nil, err
+	}
+
+	opts := imaging.RedactOptions{Regions: []imaging.Region{region}, Mode: mode}
+	if opts.Color, err = colorArg(args, "c", black); err != nil {
+		return nil, err
+	}
+	if opts.BlockSize, err = args.Int("block", imaging.DefaultPixelateBlock); err != nil {
+		return nil, err
+	}
+	if opts.Sigma, err = args.Float("s", imaging.DefaultRedactSigma); err != nil {
+		return nil, err
+	}
+	if opts.BlockSize == 0 || opts.Sigma == 0 {
+		return nil, fmt.Errorf("block and s must be positive")
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &redact{opts: opts}, nil
+}
+
+func (r *redact) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.Redact(img, r.opts)
+}
+
+func (r *redact) transparent() bool {
+	return r.opts.Mode == imaging.RedactFill && r.opts.Color.A < 255
+}
+
+func (r *redact) String() string {
+	mode := "redact_" + imaging.RedactModeName(r.opts.Mode)
+
+	var options []string
+	switch r.opts.Mode {
+	case imaging.RedactPixelate:
+		if r.opts.BlockSize != 0 && r.opts.BlockSize != imaging.DefaultPixelateBlock {
+			options = append(options, "block_"+strconv.Itoa(r.opts.BlockSize))
+		}
+	case imaging.RedactBlur:
+		if r.opts.Sigma != 0 && r.opts.Sigma != imaging.DefaultRedactSigma {
+			options = append(options, "s_"+formatFloat(r.opts.Sigma))
+		}
+	default:
+		if r.opts.Color != black {
+			options = append(options, "c_"+imaging.HexColor(r.opts.Color))
+		}
+	}
+
+	segments := make([]string, 0, len(r.opts.Regions))
+	for _, region := range r.opts.Regions {
+		parts := []string{mode}
+		if len(region.Polygon) > 0 {
+			parts = append(parts, "p_"+formatPoints(region.Polygon))
+		} else {
+			rect := region.Rect
+			parts = append(parts, fmt.Sprintf("r_%d:%d:%d:%d", rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()))
+		}
+		segments = append(segments, strings.Join(append(parts, options...), ","))
+	}
+
+	return strings.Join(segments, "/")
+}
+
+type pad struct {
+	opts imaging.PadOptions
+	fill imaging.CanvasFill
+}
+
+// Pad grows the canvas to the size or aspect ratio in opts without
+// cropping, filling the new area.
+func Pad(opts imaging.PadOptions, fill imaging.CanvasFill) Operation {
+	opts.AspectWidth, opts.AspectHeight = reduceRatio(opts.AspectWidth, opts.AspectHeight)
+	return &pad{opts: opts, fill: fill}
+}
+
+// newPad parses "pad" with a canvas size (w, h) or aspect ratio
+// (ar_width:height), anchor (a) and fill, e.g. "pad,ar_1:1,fill_blur" or
+// "pad,w_800,h_800,a_bottom,c_f0f0f0".
+func newPad(args Args) (Operation, error) {
+	if err := args.Check(append([]string{"pad", "w", "h", "ar", "a"}, fillKeys...)...); err != nil {
+		return nil, err
+	}
+
+	p := &pad{}
+	var err error
+	if p.opts.Width, err = intRangeArg(args, "w", 1, maxCanvasSize); err != nil {
+		return nil, err
+	}
+	if p.opts.Height, err = intRangeArg(args, "h", 1, maxCanvasSize); err != nil {
+		return nil, err
+	}
+	if p.opts.Anchor, err = imaging.AnchorFromName(args.String("a", "center")); err != nil {
+		return nil, err
+	}
+	if p.fill, err = fillArg(args); err != nil {
+		return nil, err
+	}
+
+	ratio, err := floatListArg(args, "ar")
+	if err != nil {
+		return nil, err
+	}
+	if ratio != nil {
+		if len(ratio) != 2 {
+			return nil, fmt.Errorf("ar must be width:height")
+		}
+		p.opts.AspectWidth, p.opts.AspectHeight = reduceRatio(ratio[0], ratio[1])
+	}
+
+	if err := p.opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// reduceRatio divides whole-number ratios by their greatest common divisor
+// so that 32:18 and 16:9 encode the same way.
+func reduceRatio(a, b float64) (float64, float64) {
+	if a <= 0 || b <= 0 || a != math.Trunc(a) || b != math.Trunc(b) || a > math.MaxInt32 || b > math.MaxInt32 {
+		return a, b
+	}
+
+	x, y := int64(a), int64(b)
+	for y != 0 {
+		x, y = y, x%y
+	}
+
+	return a / float64(x), b / float64(x)
+}
+
+func (p *pad) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	insets, err := imaging.PadInsets(img.Bounds().Size(), p.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return im.ExtendCanvas(img, insets, p.fill)
+}
+
+func (p *pad) transparent() bool {
+	return p.fill.Transparent()
+}
+
+func (p *pad) String() string {
+	parts := []string{"pad"}
+	if p.opts.Width > 0 {
+		parts = append(parts, "w_"+strconv.Itoa(p.opts.Width))
+	}
+	if p.opts.Height > 0 {
+		parts = append(parts, "h_"+strconv.Itoa(p.opts.Height))
+	}
+	if p.opts.AspectWidth > 0 {
+		parts = append(parts, "ar_"+formatFloat(p.opts.AspectWidth)+":"+formatFloat(p.opts.AspectHeight))
+	}
+	if p.opts.Anchor != imaging.AnchorCenter {
+		parts = append(parts, "a_"+imaging.AnchorName(p.opts.Anchor))
+	}
+
+	return strings.Join(append(parts, fillString(p.fill)...), ",")
+}
+
+type border struct {
+	insets imaging.Insets
+	fill   imaging.CanvasFill
+}
+
+// Border adds insets around the image, filling them.
+func Border(insets imaging.Insets, fill imaging.CanvasFill) Operation {
+	return &border{insets: insets, fill: fill}
+}
+
+// newBorder parses "border_<width>" for the same width on every side, with
+// per-side widths (t, r, b, l) overriding it, and a fill, e.g.
+// "border_20,c_000" or "border,t_10,b_40".
+func newBorder(args Args) (Operation, error) {
+	if err := args.Check(append([]string{"border", "t", "r", "b", "l"}, fillKeys...)...); err != nil {
+		return nil, err
+	}
+
+	width, err := intRangeArg(args, "border", 0, imaging.MaxCanvasInset)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &border{insets: imaging.UniformInsets(width)}
+	for key, side := range map[string]*int{"t": &b.insets.Top, "r": &b.insets.Right, "b": &b.insets.Bottom, "l": &b.insets.Left} {
+		if !args.Has(key) {
+			continue
+		}
+		if *side, err = intRangeArg(args, key, 0, imaging.MaxCanvasInset); err != nil {
+			return nil, err
+		}
+	}
+	if b.insets == (imaging.Insets{}) {
+		return nil, fmt.Errorf("border needs a positive width")
+	}
+
+	if b.fill, err = fillArg(args); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *border) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return im.ExtendCanvas(img, b.insets, b.fill)
+}
+
+func (b *border) transparent() bool {
+	return b.fill.Transparent()
+}
+
+func (b *border) String() string {
+	parts := []string{"border"}
+	if b.insets == imaging.UniformInsets(b.insets.Top) {
+		parts[0] += "_" + strconv.Itoa(b.insets.Top)
+	} else {
+		sides := []struct {
+			key   string
+			value int
+		}{{"t", b.insets.Top}, {"r", b.insets.Right}, {"b", b.insets.Bottom}, {"l", b.insets.Left}}
+		for _, side := range sides {
+			if side.value != 0 {
+				parts = append(parts, side.key+"_"+strconv.Itoa(side.value))
+			}
+		}
+	}
+
+	return strings.Join(append(parts, fillString(b.fill)...), ",")
+}
+
+// fillKeys are the arguments of canvas fills: the mode (fill), its colour
+// (c, default white) and its blur radius (s).
+var fillKeys = []string{"fill", "c", "s"}
+
+func fillArg(args Args) (imaging.CanvasFill, error) {
+	mode, err := imaging.FillModeFromName(args.String("fill", "color"))
+	if err != nil {
+		return imaging.CanvasFill{}, err
+	}
+
+	fill := imaging.CanvasFill{Mode: mode}
+	if args.Has("c") && mode != imaging.FillColor {
+		return fill, fmt.Errorf("c only applies to the color fill")
+	}
+	if args.Has("s") && mode != imaging.FillBlur {
+		return fill, fmt.Errorf("s only applies to the blur fill")
+	}
+
+	if fill.Color, err = colorArg(args, "c", white); err != nil {
+		return fill, err
+	}
+	if fill.Sigma, err = floatRangeArg(args, "s", imaging.MaxCanvasBlur); err != nil {
+		return fill, err
+	}
+
+	return fill, nil
+}
+
+func fillString(fill imaging.CanvasFill) []string {
+	switch fill.Mode {
+	case imaging.FillBlur:
+		if fill.Sigma != 0 && fill.Sigma != imaging.DefaultCanvasBlur {
+			return []string{"fill_blur", "s_" + formatFloat(fill.Sigma)}
+		}
+		return []string{"fill_blur"}
+	case imaging.FillTransparent:
+		return []string{"fill_transparent"}
+	default:
+		if fill.Color != white {
+			return []string{"c_" + imaging.HexColor(fill.Color)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,448 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+)
+
+// A chain is written as path segments, one operation per segment, each made
+// of comma-separated "key_value" arguments or bare flags:
+//
+//	w_400,h_300,fit/blur_2/sharpen_1/format_png
+//
+// The key of the first argument names the operation, except for resizing
+// where any of w or h may come first.
+//
+// A "mask" segment limits the operations after it, up to the matching
+// "unmask" segment or the end of the chain, to the area of the mask:
+//
+//	mask_ellipse,r_100:50:200:200,feather_40/blur_8/unmask/sharpen_1
+
+var ErrInvalidChain = errors.New("transform: invalid operation chain")
+
+// MaxOperations bounds how many operations one chain may contain.
+const MaxOperations = 16
+
+type Operation interface {
+	Apply(im imaging.Imaging, img image.Image) (image.Image, error)
+	// String returns the canonical encoding of the operation, so equivalent
+	// chains always encode the same way.
+	String() string
+}
+
+type Factory func(args Args) (Operation, error)
+
+// AssetLoader provides the named assets, such as colour LUTs, that some
+// operations refer to. The version it returns changes whenever the content
+// does, and Version should be cheap since parsed assets are looked up by it.
+type AssetLoader interface {
+	Load(kind, name string) (data []byte, version string, err error)
+	Version(kind, name string) (string, error)
+}
+
+// assetOperation is implemented by operations that refer to assets, which
+// are loaded when the chain is resolved.
+type assetOperation interface {
+	resolve(loader AssetLoader) (version string, err error)
+}
+
+// toneOperation is implemented by operations that map each colour channel
+// through a lookup table. Consecutive ones are applied together in a single
+// pass.
+type toneOperation interface {
+	channelLUT() (imaging.Channel, [256]uint8, error)
+}
+
+// transparentOperation is implemented by operations that may add
+// transparency to an image.
+type transparentOperation interface {
+	transparent() bool
+}
+
+var registry = map[string]Factory{}
+
+// aliases map argument keys that may lead a segment to the operation they
+// belong to.
+var aliases = map[string]string{
+	"w": "resize",
+	"h": "resize",
+}
+
+// Register makes an operation available to chains under name.
+func Register(name string, factory Factory) {
+	registry[name] = factory
+}
+
+// Operations returns the names of all registered operations.
+func Operations() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+type Chain struct {
+	Operations []Operation
+	// Format is the requested output format, or nil to keep the source one.
+	Format *imaging.Format
+
+	// versions of the assets loaded by Resolve, in operation order
+	versions []string
+}
+
+func NewChain(ops ...Operation) *Chain {
+	return &Chain{Operations: ops}
+}
+
+// Parse decodes a path-encoded operation chain.
+func Parse(spec string) (*Chain, error) {
+	chain := &Chain{}
+	// open mask groups, innermost last, which operations are added to
+	// instead of the chain. Masks count towards MaxOperations as each
+	// costs a pass over the image.
+	var groups []*masked
+	count := 0
+	add := func(op Operation) error {
+		if count++; count > MaxOperations {
+			return fmt.Errorf("%w: more than %d operations", ErrInvalidChain, MaxOperations)
+		}
+
+		if len(groups) > 0 {
+			group := groups[len(groups)-1]
+			group.ops = append(group.ops, op)
+		} else {
+			chain.Operations = append(chain.Operations, op)
+		}
+
+		return nil
+	}
+
+	for _, segment := range strings.Split(strings.Trim(spec, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+
+		args, err := parseArgs(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		if args.name == "format" {
+			if chain.Format != nil {
+				return nil, fmt.Errorf("%w: format given more than once", ErrInvalidChain)
+			}
+			format, err := imaging.FormatFromName(args.values["format"])
+			if err != nil {
+				return nil, fmt.Errorf("%w: unsupported output format %q", ErrInvalidChain, args.values["format"])
+			}
+			chain.Format = &format
+			continue
+		}
+
+		switch args.name {
+		case "mask":
+			group, err := newMask(args)
+			if err != nil {
+				return nil, fmt.Errorf("%w: mask: %v", ErrInvalidChain, err)
+			}
+			if err := add(group); err != nil {
+				return nil, err
+			}
+			groups = append(groups, group)
+			continue
+		case "unmask":
+			if len(groups) == 0 || len(args.values) > 1 {
+				return nil, fmt.Errorf("%w: unmask must follow a mask and takes no arguments", ErrInvalidChain)
+			}
+			if len(groups[len(groups)-1].ops) == 0 {
+				return nil, fmt.Errorf("%w: mask without operations", ErrInvalidChain)
+			}
+			groups = groups[:len(groups)-1]
+			continue
+		}
+
+		op, err := newOperation(args)
+		if err != nil {
+			return nil, err
+		}
+		if changesSize(op) && len(groups) > 0 {
+			return nil, fmt.Errorf("%w: masked operations must keep the image size", ErrInvalidChain)
+		}
+		if err := add(op); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, group := range groups {
+		if len(group.ops) == 0 {
+			return nil, fmt.Errorf("%w: mask without operations", ErrInvalidChain)
+		}
+	}
+
+	if len(chain.Operations) == 0 && chain.Format == nil {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalidChain)
+	}
+
+	return chain, nil
+}
+
+// ParseOperation decodes a single segment such as "blur_2".
+func ParseOperation(segment string) (Operation, error) {
+	args, err := parseArgs(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOperation(args)
+}
+
+func newOperation(args Args) (Operation, error) {
+	factory, ok := registry[args.name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidChain, args.name)
+	}
+
+	op, err := factory(args)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidChain, args.name, err)
+	}
+
+	return op, nil
+}
+
+// changesSize reports whether op may produce an image of another size.
+func changesSize(op Operation) bool {
+	switch op.(type) {
+	case *resize, *pad, *border:
+		return true
+	default:
+		return false
+	}
+}
+
+// Resolve loads the assets the operations of the chain refer to. Chains
+// containing such operations must be resolved before Apply or Key.
+func (c *Chain) Resolve(loader AssetLoader) error {
+	versions, err := resolveOperations(c.Operations, loader)
+	if err != nil {
+		return err
+	}
+	c.versions = versions
+
+	return nil
+}
+
+// resolveOperations resolves the operations that refer to assets and
+// returns the versions they loaded.
+func resolveOperations(ops []Operation, loader AssetLoader) ([]string, error) {
+	var versions []string
+
+	for _, op := range ops {
+		assetOp, ok := op.(assetOperation)
+		if !ok {
+			continue
+		}
+		version, err := assetOp.resolve(loader)
+		if err != nil {
+			return nil, err
+		}
+		if version != "" {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+// Key identifies what the chain produces: its String, plus the versions of
+// the assets it was resolved with so that results are not reused after an
+// asset is replaced.
+func (c *Chain) Key() string {
+	if len(c.versions) == 0 {
+		return c.String()
+	}
+
+	return c.String() + "@" + strings.Join(c.versions, ",")
+}
+
+// Apply runs every operation of the chain in order.
+func (c *Chain) Apply(im imaging.Imaging, img image.Image) (image.Image, error) {
+	return applyOperations(im, img, c.Operations)
+}
+
+// applyOperations applies ops to img in order, composing each run of tone
+// operations into one set of lookup tables.
+func applyOperations(im imaging.Imaging, img image.Image, ops []Operation) (image.Image, error) {
+	for idx := 0; idx < len(ops); {
+		if _, ok := ops[idx].(toneOperation); !ok {
+			var err error
+			img, err = ops[idx].Apply(im, img)
+			if err != nil {
+				return nil, err
+			}
+			idx++
+			continue
+		}
+
+		luts := imaging.IdentityLUTs()
+		for ; idx < len(ops); idx++ {
+			tone, ok := ops[idx].(toneOperation)
+			if !ok {
+				break
+			}
+			channel, lut, err := tone.channelLUT()
+			if err != nil {
+				return nil, err
+			}
+			luts = luts.Then(channel, lut)
+		}
+		img = im.ApplyChannelLUTs(img, luts)
+	}
+
+	return img, nil
+}
+
+// OutputFormat returns the format the result of the chain is encoded in
+// for a source image in source: the requested format when there is one,
+// otherwise the source format, or PNG when that cannot store transparency
+// the chain adds.
+func (c *Chain) OutputFormat(source imaging.Format) imaging.Format {
+	if c.Format != nil {
+		return *c.Format
+	}
+	if transparent(c.Operations) && !imaging.SupportsAlpha(source) {
+		return imaging.PNG
+	}
+
+	return source
+}
+
+func transparent(ops []Operation) bool {
+	for _, op := range ops {
+		if t, ok := op.(transparentOperation); ok && t.transparent() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Chain) String() string {
+	segments := make([]string, 0, len(c.Operations)+1)
+	for _, op := range c.Operations {
+		segments = append(segments, op.String())
+	}
+	if c.Format != nil {
+		segments = append(segments, "format_"+imaging.FormatName(*c.Format))
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Args holds the arguments of one segment. Flags are stored with an empty
+// value.
+type Args struct {
+	name   string
+	values map[string]string
+}
+
+func parseArgs(segment string) (Args, error) {
+	args := Args{values: make(map[string]string)}
+
+	for idx, token := range strings.Split(segment, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(token), "_")
+		key = strings.ToLower(key)
+		if key == "" {
+			return args, fmt.Errorf("%w: empty argument in %q", ErrInvalidChain, segment)
+		}
+		if _, dup := args.values[key]; dup {
+			return args, fmt.Errorf("%w: %q given twice in %q", ErrInvalidChain, key, segment)
+		}
+		args.values[key] = value
+
+		if idx == 0 {
+			args.name = key
+			if alias, ok := aliases[key]; ok {
+				args.name = alias
+			}
+		}
+	}
+
+	return args, nil
+}
+
+func (a Args) Has(key string) bool {
+	_, ok := a.values[key]
+	return ok
+}
+
+func (a Args) String(key, fallback string) string {
+	if value, ok := a.values[key]; ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// Float parses the value of key, falling back when it is absent.
+func (a Args) Float(key string, fallback float64) (float64, error) {
+	value, ok := a.values[key]
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return 0, fmt.Errorf("%s must be a number", key)
+	}
+
+	return parsed, nil
+}
+
+// Int parses the value of key, falling back when it is absent.
+func (a Args) Int(key string, fallback int) (int, error) {
+	value, ok := a.values[key]
+	if !ok || value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+
+	return parsed, nil
+}
+
+// Check rejects any argument not in allowed, catching typos that would
+// otherwise be silently ignored.
+func (a Args) Check(allowed ...string) error {
+	for key := range a.values {
+		found := false
+		for _, name := range allowed {
+			if key == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unexpected argument %q", key)
+		}
+	}
+
+	return nil
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,501 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/dylan0804/image-processing-tool/internal/api/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testcases := []struct{
+		name string
+		spec string
+		wantCanonical string
+		wantErr bool
+	}{
+		{
+			name: "Full chain",
+			spec: "w_400,h_300,fit/blur_2/sharpen_1/format_png",
+			wantCanonical: "w_400,h_300,fit/blur_2/sharpen_1/format_png",
+		},
+		{
+			name: "Equivalent spellings share a canonical form",
+			spec: "/h_300,w_400/blur_2.0/format_jpg/",
+			wantCanonical: "w_400,h_300,fit/blur_2/format_jpeg",
+		},
+		{
+			name: "Unsharp mask drops default arguments",
+			spec: "unsharp_2,luma,a_1,t_4",
+			wantCanonical: "unsharp_2,t_4,luma",
+		},
+		{
+			name: "Unsharp mask amount out of range",
+			spec: "unsharp_2,a_20",
+			wantErr: true,
+		},
+		{
+			name: "Named convolution kernel",
+			spec: "convolve_emboss,bias_128",
+			wantCanonical: "convolve_emboss,bias_128",
+		},
+		{
+			name: "Custom convolution kernel",
+			spec: "convolve,norm,k_1:2:1:2:4.0:2:1:2:1",
+			wantCanonical: "convolve,k_1:2:1:2:4:2:1:2:1,norm",
+		},
+		{
+			name: "Convolution kernel that is not square",
+			spec: "convolve,k_1:2:1:2",
+			wantErr: true,
+		},
+		{
+			name: "Canny edges",
+			spec: "edges_canny,hi_60,lo_20",
+			wantCanonical: "edges_canny,lo_20,hi_60",
+		},
+		{
+			name: "Binary Sobel edges",
+			spec: "edges_sobel,t_64,s_1",
+			wantCanonical: "edges_sobel,s_1,t_64",
+		},
+		{
+			name: "Hysteresis thresholds only apply to canny",
+			spec: "edges_sobel,lo_20,hi_60",
+			wantErr: true,
+		},
+		{
+			name: "Non-local means denoise",
+			spec: "denoise_nlmeans,r_3,h_12",
+			wantCanonical: "denoise_nlmeans,h_12,r_3",
+		},
+		{
+			name: "Denoise parameter of another method",
+			spec: "denoise_median,h_12",
+			wantErr: true,
+		},
+		{
+			name: "Automatic tone operations",
+			spec: "autolevels,rgb,lo_0.5/equalize/clahe,clip_3,tile_32",
+			wantCanonical: "autolevels,lo_0.5,rgb/equalize/clahe,tile_32,clip_3",
+		},
+		{
+			name: "CLAHE tile too small",
+			spec: "clahe,tile_2",
+			wantErr: true,
+		},
+		{
+			name: "Levels and curves",
+			spec: "levels,in_10:245,out_0:255,g_1.0/curves_r,p_0:0:128:150:255:255",
+			wantCanonical: "levels_rgb,in_10:245/curves_r,p_0:0:128:150:255:255",
+		},
+		{
+			name: "Curve points out of order",
+			spec: "curves,p_0:0:200:150:100:255",
+			wantErr: true,
+		},
+		{
+			name: "LUT",
+			spec: "lut_teal-orange,tetrahedral,i_1/lut_warm,i_0.5,trilinear",
+			wantCanonical: "lut_teal-orange,tetrahedral/lut_warm,i_0.5",
+		},
+		{
+			name: "LUT without a name",
+			spec: "lut,i_0.5",
+			wantErr: true,
+		},
+		{
+			name: "LUT intensity out of range",
+			spec: "lut_warm,i_1.5",
+			wantErr: true,
+		},
+		{
+			name: "Overlay",
+			spec: "overlay_logo,blend_screen,a_bottom-right,o_0.6,y_20,x_-20,tile_stagger",
+			wantCanonical: "overlay_logo,a_bottom-right,x_-20,y_20,o_0.6,tile_stagger,blend_screen",
+		},
+		{
+			name: "Overlay with defaults omitted",
+			spec: "overlay_logo,blend_multiply,a_center,o_1,tile_none,gap_40,x_-20,s_0.25",
+			wantCanonical: "overlay_logo,x_-20,s_0.25,gap_40,blend_multiply",
+		},
+		{
+			name: "Overlay with unknown anchor",
+			spec: "overlay_logo,a_middle",
+			wantErr: true,
+		},
+		{
+			name: "Overlay scaled beyond the base",
+			spec: "overlay_logo,s_2",
+			wantErr: true,
+		},
+		{
+			name: "Text",
+			spec: "text_SGVsbG8sIHdvcmxkL-KCrA,size_32,color_FFF,align_center,a_bottom,box_10:20:300:100,stroke_2,sc_000000,shadow_3:3,shc_00000080",
+			wantCanonical: "text_SGVsbG8sIHdvcmxkL-KCrA,color_ffffff,align_center,box_10:20:300:100,a_bottom,stroke_2,shadow_3:3",
+		},
+		{
+			name: "Text with a font asset",
+			spec: "text_SGk,font_serif,size_64,rot_-15,sb_2",
+			wantCanonical: "text_SGk,font_serif,size_64,rot_-15,shadow_0:0,sb_2",
+		},
+		{
+			name: "Text that is not base64",
+			spec: "text_Hello!",
+			wantErr: true,
+		},
+		{
+			name: "Text stroke colour without width",
+			spec: "text_SGk,sc_ff0000",
+			wantErr: true,
+		},
+		{
+			name: "Text with invalid colour",
+			spec: "text_SGk,color_red",
+			wantErr: true,
+		},
+		{
+			name: "Shapes",
+			spec: "draw_rect,p_10:10:200:80,w_4,c_FF0000,f_ff000040/draw_arrow,p_0:0:50:50,head_12,c_000/draw_polygon,p_0:0:10:0:5:8,w_0,f_00ff00,aliased",
+			wantCanonical: "draw_rect,p_10:10:200:80,w_4,c_ff0000,f_ff000040/draw_arrow,p_0:0:50:50,head_12/draw_polygon,p_0:0:10:0:5:8,w_0,f_00ff00,aliased",
+		},
+		{
+			name: "Filled line",
+			spec: "draw_line,p_0:0:10:10,f_ff0000",
+			wantErr: true,
+		},
+		{
+			name: "Unknown shape",
+			spec: "draw_star,p_0:0:10:10",
+			wantErr: true,
+		},
+		{
+			name: "Odd number of coordinates",
+			spec: "draw_line,p_0:0:10",
+			wantErr: true,
+		},
+		{
+			name: "Redaction",
+			spec: "redact_fill,r_10:10:200:40/redact_pixelate,block_16,p_0:0:40:0:20:30/redact_blur,r_0:0:5:5,s_40",
+			wantCanonical: "redact_fill,r_10:10:200:40/redact_pixelate,p_0:0:40:0:20:30/redact_blur,r_0:0:5:5,s_40",
+		},
+		{
+			name: "Redaction argument of another mode",
+			spec: "redact_fill,r_0:0:5:5,block_8",
+			wantErr: true,
+		},
+		{
+			name: "Redaction without a region",
+			spec: "redact_blur",
+			wantErr: true,
+		},
+		{
+			name: "Redaction with two regions",
+			spec: "redact_fill,r_0:0:5:5,p_0:0:5:0:5:5",
+			wantErr: true,
+		},
+		{
+			name: "Masked operations",
+			spec: "mask_ellipse,feather_40,r_100:50:200:200/blur_8/sharpen_1/unmask/mask_linear,to_0:100,from_0:0,invert/levels,g_1.2/format_png",
+			wantCanonical: "mask_ellipse,r_100:50:200:200,feather_40/blur_8/sharpen_1/unmask/mask_linear,from_0:0,to_0:100,invert/levels_rgb,g_1.2/unmask/format_png",
+		},
+		{
+			name: "Mask image",
+			spec: "mask_image,src_face/blur_20/unmask",
+			wantCanonical: "mask_image,src_face/blur_20/unmask",
+		},
+		{
+			name: "Radial mask without a radius",
+			spec: "mask_radial,at_50:50/blur_2",
+			wantErr: true,
+		},
+		{
+			name: "Nested masks",
+			spec: "mask_rect,r_0:0:5:5/blur_1/mask_radial,at_2:2,radius_2/blur_2",
+			wantCanonical: "mask_rect,r_0:0:5:5/blur_1/mask_radial,at_2:2,radius_2/blur_2/unmask/unmask",
+		},
+		{
+			name: "Mask without operations",
+			spec: "mask_rect,r_0:0:5:5/unmask/blur_2",
+			wantErr: true,
+		},
+		{
+			name: "Unmask without a mask",
+			spec: "blur_2/unmask",
+			wantErr: true,
+		},
+		{
+			name: "Masked resize",
+			spec: "mask_rect,r_0:0:5:5/w_100",
+			wantErr: true,
+		},
+		{
+			name: "Canvas padding and borders",
+			spec: "pad,ar_32:18,a_center,c_FFF/pad,h_800,w_800,a_bottom,fill_blur,s_20/border_20,fill_transparent/border,t_10,b_40,c_000",
+			wantCanonical: "pad,ar_16:9/pad,w_800,h_800,a_bottom,fill_blur/border_20,fill_transparent/border,t_10,b_40,c_000000",
+		},
+		{
+			name: "Uniform border with every side overridden alike",
+			spec: "border_5,t_8,r_8,b_8,l_8",
+			wantCanonical: "border_8",
+		},
+		{
+			name: "Padding to a size and a ratio",
+			spec: "pad,w_800,ar_1:1",
+			wantErr: true,
+		},
+		{
+			name: "Padding without a size",
+			spec: "pad,fill_blur",
+			wantErr: true,
+		},
+		{
+			name: "Fill colour with a blurred fill",
+			spec: "border_10,fill_blur,c_fff",
+			wantErr: true,
+		},
+		{
+			name: "Empty border",
+			spec: "border_0",
+			wantErr: true,
+		},
+		{
+			name: "Masked padding",
+			spec: "mask_rect,r_0:0:5:5/pad,ar_1:1",
+			wantErr: true,
+		},
+		{
+			name: "Unsupported output format",
+			spec: "blur_2/format_webp",
+			wantErr: true,
+		},
+		{
+			name: "Conflicting resize modes",
+			spec: "w_10,fit,fill",
+			wantErr: true,
+		},
+		{
+			name: "Unexpected argument",
+			spec: "blur_2,radius_4",
+			wantErr: true,
+		},
+		{
+			name: "Auto levels clipping every pixel",
+			spec: "autolevels,lo_50,hi_50",
+			wantErr: true,
+		},
+		{
+			name: "Empty chain",
+			spec: "/",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := Parse(tc.spec)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidChain)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCanonical, chain.String())
+		})
+	}
+}
+
+func TestParseNonFinite(t *testing.T) {
+	specs := []string{
+		"blur_NaN",
+		"sharpen_NaN",
+		"blur_Inf",
+		"unsharp_2,a_-Inf",
+		"convolve,k_1:NaN:1:1:1:1:1:1:1",
+		"draw_arrow,p_0:0:10:10,head_NaN",
+		"draw_line,p_0:Inf:10:10",
+		"redact_blur,r_0:0:4:4,s_NaN",
+		"text_SGk,size_NaN",
+		"pad,ar_1:1,fill_blur,s_NaN",
+		"border_2,fill_blur,s_NaN",
+		"mask_radial,at_0:0,radius_Inf/blur_2/unmask",
+	}
+
+	for _, spec := range specs {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			assert.ErrorIs(t, err, ErrInvalidChain)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	chain, err := Parse("format_tif")
+	require.NoError(t, err)
+	require.NotNil(t, chain.Format)
+	assert.Equal(t, imaging.TIFF, *chain.Format)
+	assert.Empty(t, chain.Operations)
+}
+
+type mapLoader map[string]string
+
+func (m mapLoader) Load(kind, name string) ([]byte, string, error) {
+	data, ok := m[kind+"/"+name]
+	if !ok {
+		return nil, "", errors.New("not found")
+	}
+
+	return []byte(data), "v1", nil
+}
+
+func (m mapLoader) Version(kind, name string) (string, error) {
+	_, version, err := m.Load(kind, name)
+	return version, err
+}
+
+func TestChainResolve(t *testing.T) {
+	chain, err := Parse("blur_2/lut_invert,i_0.5")
+	require.NoError(t, err)
+
+	// applying before the assets are loaded fails instead of guessing
+	_, err = chain.Apply(imaging.NewImaging(imaging.Limits{}), image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	assert.Error(t, err)
+
+	assert.Error(t, chain.Resolve(mapLoader{}))
+	assert.ErrorIs(t, chain.Resolve(nil), ErrInvalidChain)
+
+	require.NoError(t, chain.Resolve(mapLoader{"luts/invert": "LUT_1D_SIZE 2\n1 1 1\n0 0 0\n"}))
+	assert.Equal(t, "blur_2/lut_invert,i_0.5@v1", chain.Key())
+
+	_, err = chain.Apply(imaging.NewImaging(imaging.Limits{}), image.NewNRGBA(image.Rect(0, 0, 2, 2)))
+	assert.NoError(t, err)
+
+	// chains without assets are keyed by their canonical form alone
+	plain, err := Parse("blur_2")
+	require.NoError(t, err)
+	require.NoError(t, plain.Resolve(nil))
+	assert.Equal(t, "blur_2", plain.Key())
+}
+
+// countingLoader serves a single LUT under version and counts its loads.
+type countingLoader struct {
+	version string
+	loads int
+}
+
+func (c *countingLoader) Load(kind, name string) ([]byte, string, error) {
+	c.loads++
+	return []byte("LUT_1D_SIZE 2\n1 1 1\n0 0 0\n"), c.version, nil
+}
+
+func (c *countingLoader) Version(kind, name string) (string, error) {
+	return c.version, nil
+}
+
+func TestChainResolveReusesParsedAssets(t *testing.T) {
+	loader := &countingLoader{version: "reuse-1"}
+
+	for range 3 {
+		chain, err := Parse("lut_invert")
+		require.NoError(t, err)
+		require.NoError(t, chain.Resolve(loader))
+	}
+	assert.Equal(t, 1, loader.loads)
+
+	// a new version is loaded and parsed again
+	loader.version = "reuse-2"
+	chain, err := Parse("lut_invert")
+	require.NoError(t, err)
+	require.NoError(t, chain.Resolve(loader))
+	assert.Equal(t, 2, loader.loads)
+	assert.Equal(t, "lut_invert@reuse-2", chain.Key())
+}
+
+func TestChainComposesToneOperations(t *testing.T) {
+	im := imaging.NewImaging(imaging.Limits{})
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for idx := range img.Pix {
+		img.Pix[idx] = uint8(idx * 20)
+	}
+
+	chain, err := Parse("curves_r,p_0:255:255:0/levels_g,g_2/curves,p_0:0:128:160:255:255/levels_rgb,in_10:245")
+	require.NoError(t, err)
+	composed, err := chain.Apply(im, img)
+	require.NoError(t, err)
+
+	// applying the operations one at a time gives the same result
+	var separate image.Image = img
+	for _, op := range chain.Operations {
+		separate, err = op.Apply(im, separate)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, separate, composed)
+
+	// invalid adjustments built directly are still rejected
+	_, err = NewChain(Curves(imaging.ChannelRGB, imaging.Curve{{In: 0, Out: 0}})).Apply(im, img)
+	assert.Error(t, err)
+}
+
+func TestMaskedChain(t *testing.T) {
+	im := imaging.NewImaging(imaging.Limits{})
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+
+	chain, err := Parse("mask_image,src_half/lut_invert/unmask")
+	require.NoError(t, err)
+
+	// the mask image and the LUT inside the group are both resolved
+	assert.ErrorIs(t, chain.Resolve(nil), ErrInvalidChain)
+	mask := &bytes.Buffer{}
+	require.NoError(t, png.Encode(mask, image.NewGray(image.Rect(0, 0, 2, 2))))
+	require.NoError(t, chain.Resolve(mapLoader{
+		"masks/half": mask.String(),
+		"luts/invert": "LUT_1D_SIZE 2\n1 1 1\n0 0 0\n",
+	}))
+	assert.Equal(t, "mask_image,src_half/lut_invert/unmask@v1,v1", chain.Key())
+
+	_, err = chain.Apply(im, img)
+	assert.NoError(t, err)
+
+	// operations built directly can still change the size, which is caught
+	// when the chain is applied
+	resized := NewChain(Mask(imaging.MaskOptions{Kind: imaging.MaskRadial, Radius: 2}, &resize{width: 2, height: 2, mode: imaging.ResizeStretch}))
+	_, err = resized.Apply(im, img)
+	assert.ErrorIs(t, err, ErrInvalidChain)
+}
+
+func TestChainOutputFormat(t *testing.T) {
+	testcases := []struct{
+		name string
+		spec string
+		source imaging.Format
+		want imaging.Format
+	}{
+		{name: "Opaque fill keeps the format", spec: "pad,ar_1:1", source: imaging.JPEG, want: imaging.JPEG},
+		{name: "Transparent fill of a JPEG", spec: "pad,ar_1:1,fill_transparent", source: imaging.JPEG, want: imaging.PNG},
+		{name: "Translucent border of a BMP", spec: "border_4,c_00000080", source: imaging.BMP, want: imaging.PNG},
+		{name: "Transparent fill of a format with alpha", spec: "border_4,fill_transparent", source: imaging.TIFF, want: imaging.TIFF},
+		{name: "Requested format wins", spec: "border_4,fill_transparent/format_jpg", source: imaging.PNG, want: imaging.JPEG},
+		{name: "Transparency inside a mask", spec: "mask_radial,at_5:5,radius_5/redact_fill,r_0:0:4:4,c_0000/unmask", source: imaging.JPEG, want: imaging.PNG},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := Parse(tc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, chain.OutputFormat(tc.source))
+		})
+	}
+}
+
+func TestOverlayImageKey(t *testing.T) {
+	chain := NewChain(OverlayImage(image.NewNRGBA(image.Rect(0, 0, 1, 1)), "overlay-hash", imaging.OverlayOptions{Opacity: 0.5}))
+	require.NoError(t, chain.Resolve(nil))
+
+	assert.Equal(t, "overlay,o_0.5", chain.String())
+	assert.Equal(t, "overlay,o_0.5@overlay-hash", chain.Key())
+}
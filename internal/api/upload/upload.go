@@ -0,0 +1,181 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/disintegration/imaging"
+	imgproc "github.com/dylan0804/image-processing-tool/internal/api/imaging"
+)
+
+var (
+	ErrEmptyFile         = errors.New("upload: file is empty")
+	ErrUnsupportedFormat = errors.New("upload: unsupported image format")
+	ErrInvalidImage      = errors.New("upload: file could not be decoded as an image")
+)
+
+type signature struct {
+	format imaging.Format
+	magic  [][]byte
+}
+
+// signatures is the allow-list of formats accepted for upload. The leading
+// bytes of the file decide its format; the client-supplied name and
+// Content-Type are never trusted.
+var signatures = []signature{
+	{imaging.JPEG, [][]byte{{0xFF, 0xD8, 0xFF}}},
+	{imaging.PNG, [][]byte{[]byte("\x89PNG\r\n\x1a\n")}},
+	{imaging.GIF, [][]byte{[]byte("GIF87a"), []byte("GIF89a")}},
+	{imaging.TIFF, [][]byte{[]byte("II*\x00"), []byte("MM\x00*")}},
+	{imaging.BMP, [][]byte{[]byte("BM")}},
+}
+
+const maxFilenameLength = 255
+
+// sniffLength is the length of the longest signature.
+const sniffLength = 8
+
+type Image struct {
+	Data     []byte
+	Format   imaging.Format
+	Filename string
+}
+
+// Sniff detects the image format from the magic bytes at the start of data.
+func Sniff(data []byte) (imaging.Format, error) {
+	for _, sig := range signatures {
+		for _, magic := range sig.magic {
+			if bytes.HasPrefix(data, magic) {
+				return sig.format, nil
+			}
+		}
+	}
+
+	return -1, ErrUnsupportedFormat
+}
+
+// Extension returns the file extension, including the dot, used when storing
+// images of the given format.
+func Extension(format imaging.Format) string {
+	return imgproc.Extension(format)
+}
+
+// SanitizeFilename strips any directory components and control or separator
+// characters from a client-supplied filename, and makes sure its extension
+// matches the sniffed format.
+func SanitizeFilename(name string, format imaging.Format) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = filepath.Base(name)
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '-', r == '_', r == ' ':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	name = strings.Trim(name, ". ")
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if base == "" {
+		base = "image"
+	}
+
+	ext := Extension(format)
+	for len(base)+len(ext) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+
+	return base + ext
+}
+
+// Validate checks that data is a complete image in one of the allowed formats
+// whose dimensions are within limits, and returns it along with a safe
+// filename to record for it.
+func Validate(data []byte, filename string, limits imgproc.Limits) (*Image, error) {
+	if len(data) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	format, err := validateImage(bytes.NewReader(data), limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:     data,
+		Format:   format,
+		Filename: SanitizeFilename(filename, format),
+	}, nil
+}
+
+// ValidateFile is Validate for an image stored at path. The file is read as
+// a stream rather than loaded whole, and the returned Image has no Data.
+func ValidateFile(path, filename string, limits imgproc.Limits) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	format, err := validateImage(file, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Format:   format,
+		Filename: SanitizeFilename(filename, format),
+	}, nil
+}
+
+// validateImage sniffs the format of the image in r and checks that it
+// decodes within limits, rewinding r between the steps.
+func validateImage(r io.ReadSeeker, limits imgproc.Limits) (imaging.Format, error) {
+	magic := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return -1, err
+	}
+	format, err := Sniff(magic[:n])
+	if err != nil {
+		return -1, err
+	}
+
+	// reject decompression bombs from the header alone, before the pixel
+	// buffer is allocated
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return -1, err
+	}
+	if err := limits.CheckHeader(r); err != nil {
+		if errors.Is(err, imgproc.ErrImageTooLarge) {
+			return -1, err
+		}
+		return -1, errors.Join(ErrInvalidImage, err)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return -1, err
+	}
+	if _, err := imaging.Decode(r); err != nil {
+		return -1, errors.Join(ErrInvalidImage, err)
+	}
+
+	return format, nil
+}
@@ -0,0 +1,225 @@
+// Package validation checks request models against rules declared in their
+// struct tags, for example:
+//
+//	Sigma request.Number `json:"sigma" validate:"gt=0,max=100"`
+//
+// Supported rules are required, min, max, gt, lt and oneof. Numeric rules
+// apply to the value of number fields and to the length of strings and
+// slices. oneof takes space-separated strings and allows the empty string,
+// leaving that to required. Nil pointers are only checked for required,
+// which makes optional parameters easy to express.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FieldError describes a single invalid field, named as it appears in JSON.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is returned by Struct when one or more fields are invalid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for idx, fieldErr := range e {
+		msgs[idx] = fieldErr.Field + " " + fieldErr.Message
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Struct validates v, which must be a struct or a pointer to one. It returns
+// Errors listing every invalid field, or nil. Embedded and nested structs
+// are validated too, with nested field names joined by dots.
+func Struct(v any) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+
+	var errs Errors
+	validateStruct(value, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateStruct(value reflect.Value, prefix string, errs *Errors) {
+	typ := value.Type()
+
+	for idx := 0; idx < typ.NumField(); idx++ {
+		field := typ.Field(idx)
+		fieldValue := value.Field(idx)
+
+		// fields of embedded structs are promoted, even when the embedded
+		// type itself is unexported
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			validateStruct(fieldValue, prefix, errs)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + fieldName(field)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			if msg := checkField(fieldValue, tag); msg != "" {
+				*errs = append(*errs, FieldError{Field: name, Message: msg})
+				continue
+			}
+		}
+
+		validateNested(fieldValue, name, errs)
+	}
+}
+
+func validateNested(value reflect.Value, name string, errs *Errors) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			validateNested(value.Elem(), name, errs)
+		}
+	case reflect.Struct:
+		validateStruct(value, name+".", errs)
+	case reflect.Slice, reflect.Array:
+		for idx := 0; idx < value.Len(); idx++ {
+			validateNested(value.Index(idx), fmt.Sprintf("%s[%d]", name, idx), errs)
+		}
+	}
+}
+
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
+
+// checkField applies the rules in tag to value and returns a message for the
+// first one that fails.
+func checkField(value reflect.Value, tag string) string {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			if hasRule(tag, "required") {
+				return "is required"
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+
+		if name == "required" {
+			if value.IsZero() {
+				return "is required"
+			}
+			continue
+		}
+
+		if name == "oneof" {
+			if value.Kind() != reflect.String {
+				panic(fmt.Sprintf("validation: rule %q does not apply to %s", rule, value.Type()))
+			}
+			options := strings.Fields(param)
+			if value.String() != "" && !slices.Contains(options, value.String()) {
+				return "must be one of " + strings.Join(options, ", ")
+			}
+			continue
+		}
+
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid rule %q", rule))
+		}
+
+		actual, isLength, ok := measure(value)
+		if !ok {
+			panic(fmt.Sprintf("validation: rule %q does not apply to %s", rule, value.Type()))
+		}
+
+		if msg := compare(name, actual, limit, isLength); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func hasRule(tag, name string) bool {
+	for _, rule := range strings.Split(tag, ",") {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// measure returns the number numeric rules compare against: the value of
+// numbers and the length of strings and slices.
+func measure(value reflect.Value) (float64, bool, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false, true
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true, true
+	default:
+		return 0, false, false
+	}
+}
+
+func compare(rule string, actual, limit float64, isLength bool) string {
+	subject := "must be"
+	if isLength {
+		subject = "length must be"
+	}
+	formatted := strconv.FormatFloat(limit, 'f', -1, 64)
+
+	switch rule {
+	case "min":
+		if actual < limit {
+			return fmt.Sprintf("%s at least %s", subject, formatted)
+		}
+	case "max":
+		if actual > limit {
+			return fmt.Sprintf("%s at most %s", subject, formatted)
+		}
+	case "gt":
+		if actual <= limit {
+			return fmt.Sprintf("%s greater than %s", subject, formatted)
+		}
+	case "lt":
+		if actual >= limit {
+			return fmt.Sprintf("%s less than %s", subject, formatted)
+		}
+	default:
+		panic(fmt.Sprintf("validation: unknown rule %q", rule))
+	}
+
+	return ""
+}
@@ -0,0 +1,72 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type embedded struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type item struct {
+	Name string `json:"name" validate:"required,max=4"`
+}
+
+type params struct {
+	embedded
+	Sigma float64 `json:"sigma" validate:"gt=0,max=100"`
+	Amount *float64 `json:"amount" validate:"min=0,max=5"`
+	Radius *int `json:"radius" validate:"required,min=1"`
+	Items []item `json:"items"`
+	Mode string `json:"mode" validate:"oneof=fast slow"`
+}
+
+func TestStruct(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+	radius := 3
+
+	testcases := []struct{
+		name string
+		input params
+		wantErrors Errors
+	}{
+		{
+			name: "Valid",
+			input: params{embedded: embedded{ID: "a"}, Sigma: 1.5, Radius: &radius, Items: []item{{Name: "ok"}}},
+		},
+		{
+			name: "Optional field out of range",
+			input: params{embedded: embedded{ID: "a"}, Sigma: 1, Amount: ptr(6), Radius: &radius},
+			wantErrors: Errors{{Field: "amount", Message: "must be at most 5"}},
+		},
+		{
+			name: "Reports every invalid field",
+			input: params{Sigma: 0, Items: []item{{Name: "ok"}, {Name: "too long"}}, Mode: "medium"},
+			wantErrors: Errors{
+				{Field: "id", Message: "is required"},
+				{Field: "sigma", Message: "must be greater than 0"},
+				{Field: "radius", Message: "is required"},
+				{Field: "items[1].name", Message: "length must be at most 4"},
+				{Field: "mode", Message: "must be one of fast, slow"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Struct(&tc.input)
+
+			if tc.wantErrors == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs Errors
+			require.ErrorAs(t, err, &errs)
+			assert.Equal(t, tc.wantErrors, errs)
+		})
+	}
+}
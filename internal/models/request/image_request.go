@@ -1,11 +1,310 @@
 package request
 
 type BlurImageRequest struct {
-	SessionID string `json:"sessionID"`
-	Sigma string `json:"sigma"`
+	SessionID string `json:"sessionID" validate:"required"`
+	Sigma Number `json:"sigma" validate:"gt=0,max=100"`
 }
 
 type SharpenImageRequest struct {
-	SessionID string `json:"sessionID"`
-	Sigma string `json:"sigma"`
+	SessionID string `json:"sessionID" validate:"required"`
+	Sigma Number `json:"sigma" validate:"gt=0,max=100"`
+}
+
+type UnsharpMaskRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Radius is the sigma of the blur the mask is built from.
+	Radius Number `json:"radius" validate:"gt=0,max=100"`
+	// Amount defaults to 1.
+	Amount *Number `json:"amount" validate:"gt=0,max=10"`
+	Threshold Number `json:"threshold" validate:"min=0,max=255"`
+	LuminanceOnly bool `json:"luminanceOnly"`
+}
+
+type ConvolveRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Kernel names a built-in kernel and Matrix gives a custom one, rows of
+	// equal odd length. Exactly one of them is required.
+	Kernel string `json:"kernel"`
+	Matrix [][]Number `json:"matrix" validate:"max=15"`
+	Normalize bool `json:"normalize"`
+	Bias Number `json:"bias" validate:"min=-255,max=255"`
+}
+
+type EdgeDetectRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Method is one of sobel, prewitt, laplacian or canny.
+	Method string `json:"method" validate:"required"`
+	Sigma Number `json:"sigma" validate:"min=0,max=20"`
+	// Threshold makes Sobel, Prewitt and Laplacian output binary.
+	Threshold *Number `json:"threshold" validate:"min=0,max=255"`
+	// Low and High are the Canny hysteresis thresholds.
+	Low *Number `json:"low" validate:"min=0,max=255"`
+	High *Number `json:"high" validate:"min=0,max=255"`
+}
+
+type DenoiseRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Method is one of median, bilateral or nlmeans. Parameters that do not
+	// apply to it are rejected, missing ones use the method's defaults.
+	Method string `json:"method" validate:"required"`
+	// Radius is the median window radius or the nlmeans search radius.
+	Radius *int `json:"radius" validate:"min=1,max=10"`
+	SpatialSigma *Number `json:"spatialSigma" validate:"gt=0,max=10"`
+	RangeSigma *Number `json:"rangeSigma" validate:"gt=0,max=255"`
+	Strength *Number `json:"strength" validate:"gt=0,max=100"`
+	PatchRadius *int `json:"patchRadius" validate:"min=1,max=3"`
+}
+
+type CubeLUTRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// LUT names a previously uploaded .cube file.
+	LUT string `json:"lut" validate:"required,max=64"`
+	Interpolation string `json:"interpolation" validate:"oneof=trilinear tetrahedral"`
+	// Intensity mixes the graded image with the original, defaulting to 1.
+	Intensity *Number `json:"intensity" validate:"gt=0,max=1"`
+}
+
+type OverlayRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Exactly one of OverlaySessionID and Watermark selects the image drawn
+	// on top: another session's image or an uploaded watermark.
+	OverlaySessionID string `json:"overlaySessionID"`
+	Watermark string `json:"watermark" validate:"max=64"`
+	Anchor string `json:"anchor" validate:"oneof=center top-left top top-right left right bottom-left bottom bottom-right"`
+	OffsetX *int `json:"offsetX" validate:"min=-10000,max=10000"`
+	OffsetY *int `json:"offsetY" validate:"min=-10000,max=10000"`
+	// Scale is the overlay width as a fraction of the base width.
+	Scale *Number `json:"scale" validate:"gt=0,max=1"`
+	Opacity *Number `json:"opacity" validate:"gt=0,max=1"`
+	Tile string `json:"tile" validate:"oneof=none repeat stagger"`
+	Spacing *int `json:"spacing" validate:"min=0,max=10000"`
+	Blend string `json:"blend" validate:"oneof=normal multiply screen overlay"`
+}
+
+// Rect is a rectangle in image pixels.
+type Rect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	Width int `json:"width" validate:"min=1"`
+	Height int `json:"height" validate:"min=1"`
+}
+
+type TextStroke struct {
+	Width int `json:"width" validate:"min=1,max=50"`
+	Color string `json:"color"`
+}
+
+type TextShadow struct {
+	OffsetX int `json:"offsetX" validate:"min=-1000,max=1000"`
+	OffsetY int `json:"offsetY" validate:"min=-1000,max=1000"`
+	Blur *Number `json:"blur" validate:"min=0,max=50"`
+	Color string `json:"color"`
+}
+
+type TextRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	Text string `json:"text" validate:"required"`
+	// Font names an uploaded font; the bundled one is used when empty.
+	Font string `json:"font" validate:"max=64"`
+	Size *Number `json:"size" validate:"gt=0,max=1000"`
+	// Colours are hex, e.g. "#ffffff" or "#00000080".
+	Color string `json:"color"`
+	Align string `json:"align" validate:"oneof=left center right"`
+	LineSpacing *Number `json:"lineSpacing" validate:"min=0.5,max=5"`
+	// Box is the area the text is wrapped to and placed in by Anchor,
+	// defaulting to the whole image.
+	Box *Rect `json:"box"`
+	Anchor string `json:"anchor" validate:"oneof=center top-left top top-right left right bottom-left bottom bottom-right"`
+	Rotation *Number `json:"rotation" validate:"min=-360,max=360"`
+	Stroke *TextStroke `json:"stroke"`
+	Shadow *TextShadow `json:"shadow"`
+}
+
+type Point struct {
+	X Number `json:"x"`
+	Y Number `json:"y"`
+}
+
+// Shape is a vector primitive. Rectangles and ellipses are given by two
+// opposite corners, lines and arrows by the points of their path and
+// polygons by their vertices.
+type Shape struct {
+	Type string `json:"type" validate:"required,oneof=rect ellipse line arrow polygon"`
+	Points []Point `json:"points" validate:"required,min=2,max=256"`
+	// StrokeWidth defaults to 2; 0 draws no outline.
+	StrokeWidth *Number `json:"strokeWidth" validate:"min=0,max=200"`
+	StrokeColor string `json:"strokeColor"`
+	// Fill applies to rect, ellipse and polygon, which are unfilled when it
+	// is empty.
+	Fill string `json:"fill"`
+	// ArrowSize is the length of an arrow's head, scaled to the stroke
+	// width when missing.
+	ArrowSize *Number `json:"arrowSize" validate:"gt=0,max=500"`
+	// AntiAlias defaults to true.
+	AntiAlias *bool `json:"antiAlias"`
+}
+
+type DrawRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Shapes are drawn in order, later ones on top.
+	Shapes []Shape `json:"shapes" validate:"required,min=1,max=100"`
+}
+
+// Region is a rectangle or, when Points are given instead, a polygon.
+type Region struct {
+	Rect *Rect `json:"rect"`
+	Points []Point `json:"points" validate:"max=256"`
+}
+
+type RedactRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	Regions []Region `json:"regions" validate:"required,min=1,max=100"`
+	Mode string `json:"mode" validate:"required,oneof=fill pixelate blur"`
+	// Color applies to fill and defaults to black.
+	Color string `json:"color"`
+	// BlockSize applies to pixelate and defaults to 16.
+	BlockSize *int `json:"blockSize" validate:"min=2,max=512"`
+	// Sigma applies to blur and defaults to 20.
+	Sigma *Number `json:"sigma" validate:"gt=0,max=100"`
+}
+
+// MaskParams describe where a masked operation applies. Which fields are
+// required depends on Type.
+type MaskParams struct {
+	Type string `json:"type" validate:"required,oneof=rect ellipse radial linear image"`
+	// Rect bounds rect and ellipse masks, whose edges are softened over
+	// Feather pixels.
+	Rect *Rect `json:"rect"`
+	Feather *Number `json:"feather" validate:"min=0,max=1000"`
+	// Center and Radius describe radial masks.
+	Center *Point `json:"center"`
+	Radius *Number `json:"radius" validate:"gt=0"`
+	// From and To are where linear masks start and reach full strength.
+	From *Point `json:"from"`
+	To *Point `json:"to"`
+	// Name is the uploaded mask image of image masks.
+	Name string `json:"name" validate:"max=64"`
+	Invert bool `json:"invert"`
+}
+
+type MaskRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Operations are applied within the mask, written as in transformation
+	// URLs, e.g. "blur_8/sharpen_1".
+	Operations string `json:"operations" validate:"required"`
+	Mask MaskParams `json:"mask"`
+}
+
+// CanvasFill is what fills the area added around an image.
+type CanvasFill struct {
+	// Fill is color, the default, blur or transparent. Transparency turns
+	// images in formats without it into PNG.
+	Fill string `json:"fill" validate:"oneof=color blur transparent"`
+	// Color applies to the color fill and defaults to white.
+	Color string `json:"color"`
+	// Sigma applies to the blur fill and defaults to 20.
+	Sigma *Number `json:"sigma" validate:"gt=0,max=100"`
+}
+
+type PadRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Width and Height are the canvas size, raised to the image size when
+	// smaller.
+	Width *int `json:"width" validate:"min=1,max=65535"`
+	Height *int `json:"height" validate:"min=1,max=65535"`
+	// AspectRatio is width:height, e.g. "1:1" or "16:9", and cannot be
+	// combined with Width and Height.
+	AspectRatio string `json:"aspectRatio" validate:"max=32"`
+	Anchor string `json:"anchor" validate:"oneof=center top-left top top-right left right bottom-left bottom bottom-right"`
+	CanvasFill
+}
+
+type BorderRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Width applies to every side not given separately.
+	Width *int `json:"width" validate:"min=0,max=10000"`
+	Top *int `json:"top" validate:"min=0,max=10000"`
+	Right *int `json:"right" validate:"min=0,max=10000"`
+	Bottom *int `json:"bottom" validate:"min=0,max=10000"`
+	Left *int `json:"left" validate:"min=0,max=10000"`
+	CanvasFill
+}
+
+type AutoToneRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	// Method is one of autolevels, equalize or clahe.
+	Method string `json:"method" validate:"required,oneof=autolevels equalize clahe"`
+	// ShadowClip, HighlightClip and PerChannel apply to autolevels.
+	ShadowClip *Number `json:"shadowClip" validate:"min=0,max=50"`
+	HighlightClip *Number `json:"highlightClip" validate:"min=0,max=50"`
+	PerChannel bool `json:"perChannel"`
+	// TileSize and ClipLimit apply to clahe.
+	TileSize *int `json:"tileSize" validate:"min=8,max=1024"`
+	ClipLimit *Number `json:"clipLimit" validate:"min=1,max=100"`
+}
+
+// LevelsParams are the levels for one channel. Missing values leave that
+// part of the range unchanged.
+type LevelsParams struct {
+	InputBlack Number `json:"inputBlack" validate:"min=0,max=255"`
+	InputWhite *Number `json:"inputWhite" validate:"min=0,max=255"`
+	Gamma *Number `json:"gamma" validate:"min=0.1,max=10"`
+	OutputBlack Number `json:"outputBlack" validate:"min=0,max=255"`
+	OutputWhite *Number `json:"outputWhite" validate:"min=0,max=255"`
+}
+
+// LevelsRequest adjusts any of the channels. The individual channels are
+// applied first and rgb last, over all of them.
+type LevelsRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	RGB *LevelsParams `json:"rgb"`
+	Red *LevelsParams `json:"red"`
+	Green *LevelsParams `json:"green"`
+	Blue *LevelsParams `json:"blue"`
+}
+
+type CurvePoint struct {
+	In Number `json:"in" validate:"min=0,max=255"`
+	Out Number `json:"out" validate:"min=0,max=255"`
+}
+
+// CurvesRequest takes control points per channel, in increasing input
+// order. The individual channels are applied first and rgb last.
+type CurvesRequest struct {
+	SessionID string `json:"sessionID" validate:"required"`
+	RGB []CurvePoint `json:"rgb" validate:"max=16"`
+	Red []CurvePoint `json:"red" validate:"max=16"`
+	Green []CurvePoint `json:"green" validate:"max=16"`
+	Blue []CurvePoint `json:"blue" validate:"max=16"`
+}
+
+// HistogramQuery holds the query parameters of the histogram endpoint.
+type HistogramQuery struct {
+	Bins int `json:"bins" validate:"min=1,max=256"`
+	// Format is json (the default) or png for a rendered chart.
+	Format string `json:"format" validate:"oneof=json png"`
+}
+
+type UploadImageData struct {
+	Filename string `json:"filename"`
+	// Data is either a data URI ("data:image/png;base64,...") or plain
+	// base64-encoded image bytes.
+	Data string `json:"data"`
+}
+
+type UploadImageRequest struct {
+	UploadImageData
+	Images []UploadImageData `json:"images"`
+}
+
+type ImportImageRequest struct {
+	URL string `json:"url"`
+}
+
+type SignedURLRequest struct {
+	// Ops is an optional transformation chain; without it the URL is for
+	// downloading the image as is.
+	Ops string `json:"ops"`
+	// ExpiresIn is the lifetime of the URL in seconds.
+	ExpiresIn int64 `json:"expiresIn"`
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Number is a numeric request parameter. It accepts JSON numbers as well as
+// numeric strings ("1.5"), which older clients send.
+type Number float64
+
+func (n *Number) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+
+	value, err := strconv.ParseFloat(string(bytes.TrimSpace(data)), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%q is not a number", data)
+	}
+
+	*n = Number(value)
+	return nil
+}
+
+func (n Number) Float64() float64 {
+	return float64(n)
+}